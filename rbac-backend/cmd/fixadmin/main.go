@@ -2,48 +2,34 @@ package main
 
 import (
 	"log"
+
 	"rbac-backend/internal/db"
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
 )
 
+// fixadmin restores ADMIN as a superuser role, e.g. after its flag was
+// cleared by mistake. Superuser roles need no permission config.
 func main() {
 	database := db.Connect()
 	defer database.Close()
 
-	log.Println("Updating ADMIN permissions...")
-
-	_, err := database.Exec(`
-DELETE FROM role_permissions WHERE role='ADMIN';
+	log.Println("Restoring ADMIN superuser role...")
 
-INSERT INTO role_permissions (role, permissions) VALUES (
-'ADMIN',
-'{
-  "projects": {
-    "view": true,
-    "edit": true,
-    "fields": {
-      "id": { "view": true, "edit": true },
-      "name": { "view": true, "edit": true },
-      "description": { "view": true, "edit": true },
-      "created_by": { "view": true, "edit": true }
-    }
-  },
-  "users": {
-    "view": true,
-    "edit": true,
-    "fields": {
-      "id": { "view": true, "edit": false },
-      "name": { "view": true, "edit": true },
-      "email": { "view": true, "edit": true },
-      "role": { "view": true, "edit": true },
-      "is_active": { "view": true, "edit": true }
-    }
-  }
-}'
-);
-`)
+	admin, err := db.GetRole(database, rbac.RoleAdmin)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if admin == nil {
+		admin = &models.Role{Name: rbac.RoleAdmin}
+	}
+	admin.Superuser = true
+	if err := db.SaveRole(database, *admin); err != nil {
+		log.Fatal(err)
+	}
+	if err := db.RecordAudit(database, models.DefaultOrgID, db.SystemAuthor, "role.settings", rbac.RoleAdmin, "superuser=true (fixadmin)"); err != nil {
+		log.Println("audit failed:", err)
+	}
 
-	log.Println("✅ ADMIN permissions fixed!")
+	log.Println("✅ ADMIN is a superuser role")
 }
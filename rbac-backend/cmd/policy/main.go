@@ -0,0 +1,179 @@
+// Command policy exports, imports and tests the RBAC policy bundle.
+//
+//	go run ./cmd/policy export [-org id] [-format yaml|json] [-o file]
+//	go run ./cmd/policy import [-org id] [-plan] [-author name] file
+//	go run ./cmd/policy test [-org id] [-bundle file] [-v] cases
+//
+// -org selects the organization whose role permissions are read or written
+// (default: the default organization).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/policy"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "export":
+		runExport(os.Args[2:])
+	case "import":
+		runImport(os.Args[2:])
+	case "test":
+		runTest(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: policy export [-org id] [-format yaml|json] [-o file]")
+	fmt.Fprintln(os.Stderr, "       policy import [-org id] [-plan] [-author name] file")
+	fmt.Fprintln(os.Stderr, "       policy test [-org id] [-bundle file] [-v] cases")
+	os.Exit(2)
+}
+
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "yaml", "output format: yaml or json")
+	out := fs.String("o", "", "write to file instead of stdout")
+	org := fs.String("org", models.DefaultOrgID, "organization to export permissions from")
+	fs.Parse(args)
+
+	database := db.Connect()
+	defer database.Close()
+
+	bundle, err := policy.Export(database, *org)
+	if err != nil {
+		log.Fatal("export failed: ", err)
+	}
+	data, err := policy.Encode(bundle, *format)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	planOnly := fs.Bool("plan", false, "show what would change without applying it")
+	author := fs.String("author", "policy-cli", "author recorded on new permission versions")
+	org := fs.String("org", models.DefaultOrgID, "organization to import permissions into")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	bundle, err := policy.Parse(data)
+	if err != nil {
+		log.Fatal("invalid bundle: ", err)
+	}
+	if diags := policy.Validate(bundle); len(diags) > 0 {
+		for _, d := range diags {
+			fmt.Fprintf(os.Stderr, "error: %s: %s\n", d.Path, d.Message)
+		}
+		os.Exit(1)
+	}
+
+	database := db.Connect()
+	defer database.Close()
+
+	plan, err := policy.MakePlan(database, *org, bundle)
+	if err != nil {
+		log.Fatal("plan failed: ", err)
+	}
+	policy.WritePlan(os.Stdout, plan)
+	if *planOnly || !plan.HasChanges() {
+		return
+	}
+
+	if err := policy.Apply(database, *org, bundle, plan, *author); err != nil {
+		log.Fatal("import failed: ", err)
+	}
+	fmt.Println("Policy applied.")
+}
+
+// runTest checks a cases file against a bundle, or against the live database
+// when no bundle is given. It exits with status 1 if any case fails.
+func runTest(args []string) {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	bundlePath := fs.String("bundle", "", "test this bundle instead of the live database")
+	verbose := fs.Bool("v", false, "print passing cases too")
+	org := fs.String("org", models.DefaultOrgID, "organization whose permissions are tested (live database only)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	cases, err := policy.LoadCases(data)
+	if err != nil {
+		log.Fatal("invalid cases: ", err)
+	}
+
+	var src policy.Source
+	if *bundlePath != "" {
+		raw, err := os.ReadFile(*bundlePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		bundle, err := policy.Parse(raw)
+		if err != nil {
+			log.Fatal("invalid bundle: ", err)
+		}
+		src = bundle
+	} else {
+		database := db.Connect()
+		defer database.Close()
+		src = policy.DBSource{DB: database, OrgID: *org}
+	}
+
+	failed := 0
+	for _, res := range policy.RunCases(cases, src) {
+		switch {
+		case res.Err != nil:
+			failed++
+			fmt.Printf("ERROR %s: %v\n", res.Case, res.Err)
+		case !res.Passed():
+			failed++
+			fmt.Printf("FAIL  %s: expected %s, got %s%s\n", res.Case, res.Case.Expect, res.Got, reasonSuffix(res.Reason))
+		case *verbose:
+			fmt.Printf("ok    %s: %s%s\n", res.Case, res.Got, reasonSuffix(res.Reason))
+		}
+	}
+	fmt.Printf("%d cases, %d failed\n", len(cases), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func reasonSuffix(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return " (" + reason + ")"
+}
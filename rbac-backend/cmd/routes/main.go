@@ -0,0 +1,49 @@
+// Command routes lints the route table and prints the route-permission
+// matrix: who may call each endpoint, or the OpenAPI spec.
+//
+//	go run ./cmd/routes [-format md|json|openapi]
+//
+// It exits with status 1 if the route table has problems.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"rbac-backend/internal/routes"
+)
+
+func main() {
+	format := flag.String("format", "md", "output format: md, json or openapi")
+	flag.Parse()
+
+	api := routes.API(nil)
+	if problems := routes.Lint(api); len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Fprintln(os.Stderr, "error:", p)
+		}
+		os.Exit(1)
+	}
+
+	switch *format {
+	case "md":
+		routes.WriteMatrix(os.Stdout, api)
+	case "json":
+		writeJSON(routes.Matrix(api))
+	case "openapi":
+		writeJSON(routes.OpenAPI(api))
+	default:
+		log.Fatalf("unknown format %q", *format)
+	}
+}
+
+func writeJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Fatal(err)
+	}
+}
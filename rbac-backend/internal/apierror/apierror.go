@@ -0,0 +1,79 @@
+// Package apierror writes the JSON error envelope every endpoint answers
+// errors with:
+//
+//	{"error": {"code": "not_found", "message": "task not found", "request_id": "..."}}
+//
+// code is machine-readable and stable, message is for people, details is
+// optional structured data (e.g. the constraint that refused a change) and
+// request_id repeats the X-Request-ID response header set by
+// middleware.RequestID, so a report can be matched with the server log.
+package apierror
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// RequestIDHeader carries the request id in requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+// Codes used in addition to the ones derived from the status by Code.
+const (
+	CodeConstraintViolation = "constraint_violation"
+	CodeInvalidPermissions  = "invalid_permissions"
+	CodeValidationFailed    = "validation_failed"
+	CodeForbiddenFields     = "forbidden_fields"
+)
+
+// Body is the content of the envelope.
+type Body struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// Envelope is the JSON document of an error response.
+type Envelope struct {
+	Error Body `json:"error"`
+}
+
+// Write sends status with the envelope. The request id is taken from the
+// response header, so middleware that set it earlier need not pass it along.
+func Write(w http.ResponseWriter, status int, code, message string, details interface{}) {
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Envelope{Error: Body{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: h.Get(RequestIDHeader),
+	}})
+}
+
+// Error replaces http.Error: it sends message with the code for status.
+func Error(w http.ResponseWriter, message string, status int) {
+	Write(w, status, Code(status), message, nil)
+}
+
+// Internal sends 500 with message and logs err, which is not shown to the
+// client, together with the request id.
+func Internal(w http.ResponseWriter, message string, err error) {
+	log.Printf("request %s: %s: %v", w.Header().Get(RequestIDHeader), message, err)
+	Error(w, message, http.StatusInternalServerError)
+}
+
+// Code is the error code for status: its status text in snake case, e.g.
+// "not_found" for 404.
+func Code(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(strings.ReplaceAll(text, "-", " ")), " ", "_")
+}
@@ -9,16 +9,19 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Claims identify the user, their active organization and their role in it.
 type Claims struct {
 	UserID string `json:"user_id"`
 	Role   string `json:"role"`
+	OrgID  string `json:"org_id"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(userID, role string) (string, error) {
+func GenerateJWT(userID, role, orgID string) (string, error) {
 	claims := &Claims{
 		UserID: userID,
 		Role:   role,
+		OrgID:  orgID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 		},
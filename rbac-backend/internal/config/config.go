@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,6 +12,17 @@ type Config struct {
 	JWTSecret string
 	Port      string
 	DBPath    string
+	// FieldMode is how writes treat fields the caller cannot edit: "lenient"
+	// ignores and reports them, "strict" rejects the write. Requests may
+	// choose with the X-Field-Mode header.
+	FieldMode string
+	// RedactionKey keys the HMAC of the hash redaction mode, so hashed
+	// values cannot be recovered by hashing guesses. It defaults to a key
+	// derived from JWTSecret.
+	RedactionKey string
+	// TrashRetention is how long deleted projects and tasks stay in the
+	// trash before they are purged; 0 keeps them until purged by hand.
+	TrashRetention time.Duration
 }
 
 var AppConfig *Config
@@ -23,7 +35,15 @@ func LoadConfig() {
 		JWTSecret: getEnv("JWT_SECRET", "super-secret-key"),
 		Port:      getEnv("PORT", "8080"),
 		DBPath:    getEnv("DB_PATH", "rbac.db"),
+		FieldMode: getEnv("FIELD_MODE", "lenient"),
 	}
+	AppConfig.RedactionKey = getEnv("REDACTION_KEY", "redaction:"+AppConfig.JWTSecret)
+
+	retention, err := time.ParseDuration(getEnv("TRASH_RETENTION", "720h"))
+	if err != nil || retention < 0 {
+		log.Fatalf("TRASH_RETENTION must be a duration such as 720h: %q", os.Getenv("TRASH_RETENTION"))
+	}
+	AppConfig.TrashRetention = retention
 
 	log.Println("Config loaded successfully")
 }
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"rbac-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// RecordAudit appends an entry to the organization's audit log.
+func RecordAudit(db *sql.DB, orgID, actorID, action, target, details string) error {
+	_, err := db.Exec(
+		"INSERT INTO audit_log (id, org_id, actor_id, action, target, details, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		uuid.New().String(), orgID, actorID, action, target, details, time.Now().UTC(),
+	)
+	return err
+}
+
+// ListAudit returns the organization's most recent audit entries, newest first.
+func ListAudit(db *sql.DB, orgID string, limit int) ([]models.AuditEntry, error) {
+	rows, err := db.Query(
+		"SELECT id, COALESCE(actor_id, ''), action, COALESCE(target, ''), COALESCE(details, ''), created_at FROM audit_log WHERE org_id = ? ORDER BY created_at DESC LIMIT ?",
+		orgID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.Target, &e.Details, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
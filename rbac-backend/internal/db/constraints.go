@@ -0,0 +1,36 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"rbac-backend/internal/models"
+)
+
+// GetConstraints returns the separation-of-duties config; a missing row means none.
+func GetConstraints(db *sql.DB) (models.Constraints, error) {
+	var c models.Constraints
+	var raw string
+	err := db.QueryRow("SELECT constraints FROM rbac_constraints WHERE id = 1").Scan(&raw)
+	if err == sql.ErrNoRows {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal([]byte(raw), &c)
+	return c, err
+}
+
+// UpdateConstraints replaces the separation-of-duties config.
+func UpdateConstraints(db *sql.DB, c models.Constraints) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(
+		"INSERT OR REPLACE INTO rbac_constraints (id, constraints) VALUES (1, ?)",
+		string(data),
+	)
+	return err
+}
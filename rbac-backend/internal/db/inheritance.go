@@ -0,0 +1,94 @@
+package db
+
+import (
+	"database/sql"
+
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+)
+
+// GetRoleParents returns the roles that role inherits from.
+func GetRoleParents(db *sql.DB, role string) ([]string, error) {
+	rows, err := db.Query("SELECT parent FROM role_inheritance WHERE role = ? ORDER BY parent", role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parents []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		parents = append(parents, p)
+	}
+	return parents, rows.Err()
+}
+
+// SetRoleParents replaces the roles that role inherits from.
+func SetRoleParents(db *sql.DB, role string, parents []string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM role_inheritance WHERE role = ?", role); err != nil {
+		return err
+	}
+	for _, p := range parents {
+		if _, err := tx.Exec("INSERT INTO role_inheritance (role, parent) VALUES (?, ?)", role, p); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetEffectivePermissions returns role's permissions in the organization
+// including everything it inherits; see rbac.ResolveInherited. It fails when
+// role itself has no config. Inheritance is the same in every organization.
+func GetEffectivePermissions(db *sql.DB, orgID, role string) (models.Permissions, error) {
+	return rbac.ResolveInherited(role, func(r string) (models.Permissions, error) {
+		perms, err := GetPermissionsByRole(db, orgID, r)
+		if err == sql.ErrNoRows && r != role {
+			return nil, nil
+		}
+		return perms, err
+	}, func(r string) ([]string, error) {
+		return GetRoleParents(db, r)
+	})
+}
+
+// GetRoleDescendants returns the roles that inherit from role, directly or
+// transitively, in breadth-first order.
+func GetRoleDescendants(db *sql.DB, role string) ([]string, error) {
+	var out []string
+	seen := map[string]bool{role: true}
+	queue := []string{role}
+	for len(queue) > 0 {
+		rows, err := db.Query("SELECT role FROM role_inheritance WHERE parent = ? ORDER BY role", queue[0])
+		if err != nil {
+			return nil, err
+		}
+		queue = queue[1:]
+		for rows.Next() {
+			var child string
+			if err := rows.Scan(&child); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if !seen[child] {
+				seen[child] = true
+				out = append(out, child)
+				queue = append(queue, child)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
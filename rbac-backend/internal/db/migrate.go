@@ -59,6 +59,10 @@ func RunMigrations(db *sql.DB) error {
 		log.Println("✓ Successfully applied:", filepath.Base(file))
 	}
 
+	if err := upgradeSchema(db); err != nil {
+		return err
+	}
+
 	log.Println("All migrations completed successfully!")
 	return nil
 }
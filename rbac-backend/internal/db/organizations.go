@@ -0,0 +1,103 @@
+package db
+
+import (
+	"database/sql"
+
+	"rbac-backend/internal/models"
+)
+
+// CreateOrganization adds org and makes creatorID a member with creatorRole.
+// The new organization starts with the default role permissions from
+// role_permissions.
+func CreateOrganization(db *sql.DB, org models.Organization, creatorID, creatorRole string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"INSERT INTO organizations (id, name, created_at) VALUES (?, ?, ?)",
+		org.ID, org.Name, org.CreatedAt,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO org_role_permissions (org_id, role, permissions)
+		 SELECT ?, role, permissions FROM role_permissions`,
+		org.ID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO organization_members (org_id, user_id, role) VALUES (?, ?, ?)",
+		org.ID, creatorID, creatorRole,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListOrganizations returns every organization, ordered by name.
+func ListOrganizations(db *sql.DB) ([]models.Organization, error) {
+	rows, err := db.Query("SELECT id, name, created_at FROM organizations ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []models.Organization
+	for rows.Next() {
+		var o models.Organization
+		var created sql.NullTime
+		if err := rows.Scan(&o.ID, &o.Name, &created); err != nil {
+			return nil, err
+		}
+		o.CreatedAt = created.Time
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+// ListMemberships returns the organizations userID belongs to, ordered by name.
+func ListMemberships(db *sql.DB, userID string) ([]models.Membership, error) {
+	rows, err := db.Query(
+		`SELECT m.org_id, o.name, m.user_id, m.role
+		 FROM organization_members m JOIN organizations o ON o.id = m.org_id
+		 WHERE m.user_id = ? ORDER BY o.name`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.Membership
+	for rows.Next() {
+		var m models.Membership
+		if err := rows.Scan(&m.OrgID, &m.OrgName, &m.UserID, &m.Role); err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// GetMembership returns userID's membership of orgID, or nil when the user
+// does not belong to it.
+func GetMembership(db *sql.DB, orgID, userID string) (*models.Membership, error) {
+	var m models.Membership
+	err := db.QueryRow(
+		`SELECT m.org_id, o.name, m.user_id, m.role
+		 FROM organization_members m JOIN organizations o ON o.id = m.org_id
+		 WHERE m.org_id = ? AND m.user_id = ?`,
+		orgID, userID,
+	).Scan(&m.OrgID, &m.OrgName, &m.UserID, &m.Role)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
@@ -3,15 +3,17 @@ package db
 import (
 	"database/sql"
 	"encoding/json"
+	"time"
 
 	"rbac-backend/internal/models"
 )
 
-func GetPermissionsByRole(db *sql.DB, role string) (models.Permissions, error) {
+// GetPermissionsByRole returns role's own permissions in the organization.
+func GetPermissionsByRole(db *sql.DB, orgID, role string) (models.Permissions, error) {
 	var raw string
 	err := db.QueryRow(
-		"SELECT permissions FROM role_permissions WHERE role=?",
-		role,
+		"SELECT permissions FROM org_role_permissions WHERE org_id=? AND role=?",
+		orgID, role,
 	).Scan(&raw)
 	if err != nil {
 		return nil, err
@@ -22,9 +24,10 @@ func GetPermissionsByRole(db *sql.DB, role string) (models.Permissions, error) {
 	return perms, err
 }
 
-// ListRoles returns all role names that have config in role_permissions (non-ADMIN roles).
-func ListRoles(db *sql.DB) ([]string, error) {
-	rows, err := db.Query("SELECT role FROM role_permissions ORDER BY role")
+// ListRoles returns all role names that have config in the organization.
+// ListRoleDefinitions lists the role catalogue itself.
+func ListRoles(db *sql.DB, orgID string) ([]string, error) {
+	rows, err := db.Query("SELECT role FROM org_role_permissions WHERE org_id = ? ORDER BY role", orgID)
 	if err != nil {
 		return nil, err
 	}
@@ -41,15 +44,39 @@ func ListRoles(db *sql.DB) ([]string, error) {
 	return roles, rows.Err()
 }
 
-// UpdateRolePermissions sets the JSON permissions for a role (MANAGER, EDITOR, VIEWER only).
-func UpdateRolePermissions(db *sql.DB, role string, perms models.Permissions) error {
+// UpdateRolePermissions sets the JSON permissions for a role in the organization
+// and records the change as a new version with author and comment. It returns the new
+// version number.
+func UpdateRolePermissions(db *sql.DB, orgID, role string, perms models.Permissions, author, comment string) (int, error) {
 	data, err := json.Marshal(perms)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	_, err = db.Exec(
-		"INSERT OR REPLACE INTO role_permissions (role, permissions) VALUES (?, ?)",
-		role, string(data),
-	)
-	return err
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	latest, err := snapshotCurrent(tx, orgID, role, now)
+	if err != nil {
+		return 0, err
+	}
+
+	version := latest + 1
+	if _, err := tx.Exec(
+		`INSERT INTO role_permission_versions (org_id, role, version, permissions, author, comment, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		orgID, role, version, string(data), author, comment, now,
+	); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(
+		"INSERT OR REPLACE INTO org_role_permissions (org_id, role, permissions) VALUES (?, ?, ?)",
+		orgID, role, string(data),
+	); err != nil {
+		return 0, err
+	}
+	return version, tx.Commit()
 }
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"rbac-backend/internal/models"
+)
+
+// SystemAuthor is recorded for versions not created through the API, e.g. the
+// baseline snapshot or a config changed by a migration.
+const SystemAuthor = "system"
+
+// ListRolePermissionVersions returns a role's versions in the organization, newest first.
+func ListRolePermissionVersions(db *sql.DB, orgID, role string) ([]models.RolePermissionVersion, error) {
+	rows, err := db.Query(
+		`SELECT role, version, permissions, COALESCE(author, ''), COALESCE(comment, ''), created_at
+		 FROM role_permission_versions WHERE org_id = ? AND role = ? ORDER BY version DESC`,
+		orgID, role,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []models.RolePermissionVersion
+	for rows.Next() {
+		v, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// GetRolePermissionVersion returns one version, or nil when it does not exist.
+func GetRolePermissionVersion(db *sql.DB, orgID, role string, version int) (*models.RolePermissionVersion, error) {
+	rows, err := db.Query(
+		`SELECT role, version, permissions, COALESCE(author, ''), COALESCE(comment, ''), created_at
+		 FROM role_permission_versions WHERE org_id = ? AND role = ? AND version = ?`,
+		orgID, role, version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	v, err := scanVersion(rows)
+	return &v, err
+}
+
+func scanVersion(rows *sql.Rows) (models.RolePermissionVersion, error) {
+	var v models.RolePermissionVersion
+	var raw string
+	if err := rows.Scan(&v.Role, &v.Version, &raw, &v.Author, &v.Comment, &v.CreatedAt); err != nil {
+		return v, err
+	}
+	err := json.Unmarshal([]byte(raw), &v.Permissions)
+	return v, err
+}
+
+// snapshotCurrent records the live config as a version when it is not already
+// the latest one (first change to a role, or a change made outside the API).
+func snapshotCurrent(tx *sql.Tx, orgID, role string, now time.Time) (int, error) {
+	var latest int
+	var latestRaw sql.NullString
+	err := tx.QueryRow(
+		`SELECT version, permissions FROM role_permission_versions WHERE org_id = ? AND role = ? ORDER BY version DESC LIMIT 1`,
+		orgID, role,
+	).Scan(&latest, &latestRaw)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	var current string
+	err = tx.QueryRow("SELECT permissions FROM org_role_permissions WHERE org_id = ? AND role = ?", orgID, role).Scan(&current)
+	if err == sql.ErrNoRows {
+		return latest, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if latestRaw.Valid && sameJSON(latestRaw.String, current) {
+		return latest, nil
+	}
+
+	comment := "baseline"
+	if latest > 0 {
+		comment = "changed outside the API"
+	}
+	latest++
+	_, err = tx.Exec(
+		`INSERT INTO role_permission_versions (org_id, role, version, permissions, author, comment, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		orgID, role, latest, current, SystemAuthor, comment, now,
+	)
+	return latest, err
+}
+
+func sameJSON(a, b string) bool {
+	var va, vb interface{}
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return a == b
+	}
+	ca, _ := json.Marshal(va)
+	cb, _ := json.Marshal(vb)
+	return string(ca) == string(cb)
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"database/sql"
+
+	"rbac-backend/internal/models"
+)
+
+// ListRoleDefinitions returns every role in the catalogue, ordered by name.
+func ListRoleDefinitions(db *sql.DB) ([]models.Role, error) {
+	rows, err := db.Query("SELECT name, superuser, description FROM roles ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		var r models.Role
+		if err := rows.Scan(&r.Name, &r.Superuser, &r.Description); err != nil {
+			return nil, err
+		}
+		roles = append(roles, r)
+	}
+	return roles, rows.Err()
+}
+
+// GetRole returns the role, or nil when it is not in the catalogue.
+func GetRole(db *sql.DB, name string) (*models.Role, error) {
+	var r models.Role
+	err := db.QueryRow("SELECT name, superuser, description FROM roles WHERE name = ?", name).
+		Scan(&r.Name, &r.Superuser, &r.Description)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// IsSuperuser reports whether role exists and carries the superuser flag.
+func IsSuperuser(db *sql.DB, role string) (bool, error) {
+	r, err := GetRole(db, role)
+	if err != nil || r == nil {
+		return false, err
+	}
+	return r.Superuser, nil
+}
+
+// SaveRole creates the role or updates its flag and description.
+func SaveRole(db *sql.DB, r models.Role) error {
+	_, err := db.Exec(
+		`INSERT INTO roles (name, superuser, description) VALUES (?, ?, ?)
+		 ON CONFLICT(name) DO UPDATE SET superuser = excluded.superuser, description = excluded.description`,
+		r.Name, r.Superuser, r.Description,
+	)
+	return err
+}
+
+// CountActiveSuperusers returns the number of active members of the default
+// organization whose role there is a superuser role other than excludeRole.
+// Settings shared by all organizations can only be managed from the default
+// one, so it must keep a superuser.
+func CountActiveSuperusers(db *sql.DB, excludeRole string) (int, error) {
+	var n int
+	err := db.QueryRow(
+		`SELECT COUNT(*) FROM organization_members m
+		 JOIN users ON users.id = m.user_id
+		 JOIN roles ON roles.name = m.role
+		 WHERE m.org_id = ? AND roles.superuser = 1 AND users.is_active = 1 AND roles.name <> ?`,
+		models.DefaultOrgID, excludeRole,
+	).Scan(&n)
+	return n, err
+}
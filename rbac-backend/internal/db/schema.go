@@ -0,0 +1,264 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+var errForeignKeyViolation = errors.New("foreign key check failed after table rebuild")
+
+// schemaUpgrades are schema changes that a migration file cannot express,
+// because every file is re-applied on each run and SQLite has no conditional
+// DDL (e.g. rebuilding a table to drop a CHECK). Each step inspects the
+// schema and does nothing when it has already been applied.
+var schemaUpgrades = []struct {
+	name string
+	run  func(*sql.DB) error
+}{
+	{"users.role references roles", upgradeUserRoleReference},
+	{"org_id on tenant tables", addOrgColumns},
+	{"role_permission_versions per organization", upgradeVersionsOrg},
+	{"version on projects and tasks", addVersionColumns},
+	{"trash on projects and tasks", addTrashColumns},
+}
+
+// upgradeSchema runs the schema upgrades after the migration files.
+func upgradeSchema(db *sql.DB) error {
+	for _, u := range schemaUpgrades {
+		if err := u.run(db); err != nil {
+			log.Printf("Error applying schema upgrade %q: %v", u.name, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// upgradeUserRoleReference replaces the fixed CHECK on users.role with a
+// reference to roles, so roles can be added at runtime.
+func upgradeUserRoleReference(db *sql.DB) error {
+	var ddl string
+	if err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'users'`).Scan(&ddl); err != nil {
+		return err
+	}
+	if !strings.Contains(ddl, "CHECK(role IN") {
+		return nil
+	}
+
+	return rebuildTable(db, "users", `
+		CREATE TABLE users_new (
+		    id TEXT PRIMARY KEY,
+		    name TEXT NOT NULL,
+		    email TEXT UNIQUE NOT NULL,
+		    password_hash TEXT NOT NULL,
+		    role TEXT NOT NULL REFERENCES roles(name),
+		    is_active BOOLEAN DEFAULT 1,
+		    last_login DATETIME,
+		    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT INTO users_new (id, name, email, password_hash, role, is_active, last_login, created_at, updated_at)
+		 SELECT id, name, email, password_hash, role, is_active, last_login, created_at, updated_at FROM users`,
+	)
+}
+
+// orgTables are the tables whose rows belong to one organization.
+var orgTables = []string{"projects", "tasks", "elevation_requests", "audit_log"}
+
+// addOrgColumns adds org_id to the tenant tables. Rows that predate
+// organizations belong to the default one.
+func addOrgColumns(db *sql.DB) error {
+	for _, table := range orgTables {
+		if err := addColumn(db, table, "org_id", "TEXT NOT NULL DEFAULT 'default'"); err != nil {
+			return err
+		}
+		if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_" + table + "_org ON " + table + "(org_id)"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upgradeVersionsOrg keys permission versions by organization as well as role.
+func upgradeVersionsOrg(db *sql.DB) error {
+	has, err := hasColumn(db, "role_permission_versions", "org_id")
+	if err != nil || has {
+		return err
+	}
+	return rebuildTable(db, "role_permission_versions", `
+		CREATE TABLE role_permission_versions_new (
+		    org_id TEXT NOT NULL DEFAULT 'default' REFERENCES organizations(id) ON DELETE CASCADE,
+		    role TEXT NOT NULL,
+		    version INTEGER NOT NULL,
+		    permissions TEXT NOT NULL,
+		    author TEXT,
+		    comment TEXT,
+		    created_at DATETIME NOT NULL,
+		    PRIMARY KEY (org_id, role, version)
+		)`,
+		`INSERT INTO role_permission_versions_new (role, version, permissions, author, comment, created_at)
+		 SELECT role, version, permissions, author, comment, created_at FROM role_permission_versions`,
+	)
+}
+
+// addColumn adds column to table unless it is already there. ALTER TABLE
+// cannot add a REFERENCES column with a non-NULL default, so def must not
+// declare one.
+func addColumn(db *sql.DB, table, column, def string) error {
+	has, err := hasColumn(db, table, column)
+	if err != nil || has {
+		return err
+	}
+	_, err = db.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + def)
+	return err
+}
+
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&n)
+	return n > 0, err
+}
+
+// rebuildTable replaces table with <table>_new, created by createNew and
+// filled by copy, following SQLite's recommended procedure for schema changes
+// ALTER TABLE cannot make. Foreign keys are switched off on the connection
+// for the swap; before committing, the foreign keys of table and of the
+// tables referencing it are checked. Only violations the rebuild added fail
+// it, so rows that were already dangling do not block the upgrade.
+func rebuildTable(db *sql.DB, table, createNew, copy string) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	checked, err := referencingTables(ctx, tx, table)
+	if err != nil {
+		return err
+	}
+	checked = append(checked, table)
+	before, err := foreignKeyViolations(ctx, tx, checked)
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range []string{
+		createNew,
+		copy,
+		"DROP TABLE " + table,
+		"ALTER TABLE " + table + "_new RENAME TO " + table,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	after, err := foreignKeyViolations(ctx, tx, checked)
+	if err != nil {
+		return err
+	}
+	for ref, n := range after {
+		if n > before[ref] {
+			return fmt.Errorf("%w: %d new in %s", errForeignKeyViolation, n-before[ref], ref)
+		}
+	}
+	return tx.Commit()
+}
+
+// referencingTables returns the other tables with a foreign key to table.
+func referencingTables(ctx context.Context, tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT DISTINCT m.name FROM sqlite_master m, pragma_foreign_key_list(m.name) f
+		WHERE m.type = 'table' AND f."table" = ? COLLATE NOCASE AND m.name <> ? COLLATE NOCASE`, table, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// foreignKeyViolations counts the foreign key violations of tables, keyed by
+// "<table> -> <parent>".
+func foreignKeyViolations(ctx context.Context, tx *sql.Tx, tables []string) (map[string]int, error) {
+	counts := map[string]int{}
+	for _, table := range tables {
+		rows, err := tx.QueryContext(ctx, `PRAGMA foreign_key_check("`+table+`")`)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var child, parent string
+			var rowid sql.NullInt64
+			var fkid int
+			if err := rows.Scan(&child, &rowid, &parent, &fkid); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			counts[child+" -> "+parent]++
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return counts, nil
+}
+
+// versionedTables are the tables whose updates are checked against the
+// version the client read; each update increments it.
+var versionedTables = []string{"projects", "tasks"}
+
+// addVersionColumns adds version to the versioned tables. Existing rows start
+// at version 1.
+func addVersionColumns(db *sql.DB) error {
+	for _, table := range versionedTables {
+		if err := addColumn(db, table, "version", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trashTables are the tables whose deletes move rows to the trash, from
+// which they are restored or purged.
+var trashTables = []string{"projects", "tasks"}
+
+// addTrashColumns adds deleted_at and deleted_by to the trash tables. A row
+// is in the trash when deleted_at is set.
+func addTrashColumns(db *sql.DB) error {
+	for _, table := range trashTables {
+		if err := addColumn(db, table, "deleted_at", "DATETIME"); err != nil {
+			return err
+		}
+		if err := addColumn(db, table, "deleted_by", "TEXT"); err != nil {
+			return err
+		}
+		if _, err := db.Exec("CREATE INDEX IF NOT EXISTS idx_" + table + "_deleted_at ON " + table + "(deleted_at)"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
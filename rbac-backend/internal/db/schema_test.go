@@ -0,0 +1,70 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// setupLegacyDB returns a database with users.role still under the fixed
+// CHECK, and a task whose assignee was already dangling before any upgrade.
+func setupLegacyDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "rbac.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	schema := `
+    CREATE TABLE roles (name TEXT PRIMARY KEY);
+    CREATE TABLE users (id TEXT PRIMARY KEY, name TEXT NOT NULL, email TEXT UNIQUE NOT NULL, password_hash TEXT NOT NULL,
+        role TEXT CHECK(role IN ('ADMIN','MANAGER','EDITOR','VIEWER')) NOT NULL, is_active BOOLEAN DEFAULT 1, last_login DATETIME,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+    CREATE TABLE tasks (id TEXT PRIMARY KEY, assignee TEXT, created_by TEXT NOT NULL,
+        FOREIGN KEY (assignee) REFERENCES users(id), FOREIGN KEY (created_by) REFERENCES users(id));
+    INSERT INTO roles (name) VALUES ('ADMIN'), ('EDITOR');
+    INSERT INTO users (id, name, email, password_hash, role) VALUES ('u1', 'A', 'a@example.com', 'x', 'ADMIN');
+    INSERT INTO tasks (id, assignee, created_by) VALUES ('t1', '["u1"]', 'u1');
+    `
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestRebuildIgnoresExistingViolations(t *testing.T) {
+	db := setupLegacyDB(t)
+	if err := upgradeUserRoleReference(db); err != nil {
+		t.Fatalf("upgrade with a violation that predates it: %v", err)
+	}
+	var ddl string
+	if err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE name = 'users'`).Scan(&ddl); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(ddl, "REFERENCES roles") {
+		t.Fatalf("users was not rebuilt: %s", ddl)
+	}
+	// Running it again finds nothing to do.
+	if err := upgradeUserRoleReference(db); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRebuildRejectsNewViolations(t *testing.T) {
+	db := setupLegacyDB(t)
+	// MANAGER passes the old CHECK but is not a row of roles.
+	if _, err := db.Exec(`INSERT INTO users (id, name, email, password_hash, role) VALUES ('u2', 'B', 'b@example.com', 'x', 'MANAGER')`); err != nil {
+		t.Fatal(err)
+	}
+	if err := upgradeUserRoleReference(db); !errors.Is(err, errForeignKeyViolation) {
+		t.Fatalf("upgrade leaving a user with an unknown role: %v, want errForeignKeyViolation", err)
+	}
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&n); err != nil || n != 2 {
+		t.Errorf("users after the failed upgrade: %d, %v", n, err)
+	}
+}
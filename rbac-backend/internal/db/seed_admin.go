@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"rbac-backend/internal/auth"
+	"rbac-backend/internal/models"
 
 	"github.com/google/uuid"
 )
@@ -36,6 +37,15 @@ func SeedAdmin(db *sql.DB) {
 		log.Fatal("Failed to seed admin:", err)
 	}
 
+	// Make sure the admin can sign in to the default organization
+	_, err = db.Exec(`
+	INSERT OR IGNORE INTO organization_members (org_id, user_id, role)
+	SELECT ?, id, ? FROM users WHERE email = ?
+	`, models.DefaultOrgID, role, email)
+	if err != nil {
+		log.Fatal("Failed to seed admin membership:", err)
+	}
+
 	fmt.Println("✅ Admin user seeded successfully")
 	fmt.Println("📧 Email:", email)
 	fmt.Println("🔑 Password:", password)
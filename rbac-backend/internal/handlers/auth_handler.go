@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"rbac-backend/internal/apierror"
 	"rbac-backend/internal/auth"
 	dbrepo "rbac-backend/internal/db"
 	"rbac-backend/internal/middleware"
 	"rbac-backend/internal/models"
 	"rbac-backend/internal/rbac"
+	repositories "rbac-backend/internal/repository"
 	"rbac-backend/internal/utils"
 
 	"github.com/google/uuid"
@@ -19,7 +21,7 @@ func Login(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
@@ -27,67 +29,100 @@ func Login(db *sql.DB) http.HandlerFunc {
 		var req struct {
 			Email    string
 			Password string
+			OrgID    string `json:"org_id"`
 		}
 
 		json.NewDecoder(r.Body).Decode(&req)
 
-		var userID, hash, role string
+		var userID, hash string
 		err := db.QueryRow(
-			"SELECT id, password_hash, role FROM users WHERE email=? AND is_active=1",
+			"SELECT id, password_hash FROM users WHERE email=? AND is_active=1",
 			req.Email,
-		).Scan(&userID, &hash, &role)
+		).Scan(&userID, &hash)
 
 		if err != nil || auth.CheckPassword(hash, req.Password) != nil {
-			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+			apierror.Error(w, "Invalid credentials", http.StatusUnauthorized)
 			return
 		}
 
-		token, _ := auth.GenerateJWT(userID, role)
-		json.NewEncoder(w).Encode(map[string]string{"token": token})
+		m, err := loginMembership(db, userID, req.OrgID)
+		if err != nil {
+			apierror.Internal(w, "Failed to load organizations", err)
+			return
+		}
+		if m == nil {
+			apierror.Error(w, "Not a member of this organization", http.StatusForbidden)
+			return
+		}
+
+		token, _ := auth.GenerateJWT(userID, m.Role, m.OrgID)
+		json.NewEncoder(w).Encode(map[string]string{"token": token, "org_id": m.OrgID})
 	}
 }
 
-// Signup registers a new user and returns a JWT token.
+// loginMembership picks the organization a login starts in: orgID when given,
+// otherwise the default organization, otherwise the first the user belongs
+// to. It returns nil when the user is not a member of any candidate.
+func loginMembership(database *sql.DB, userID, orgID string) (*models.Membership, error) {
+	if orgID != "" {
+		return dbrepo.GetMembership(database, orgID, userID)
+	}
+	list, err := dbrepo.ListMemberships(database, userID)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	for _, m := range list {
+		if m.OrgID == models.DefaultOrgID {
+			return &m, nil
+		}
+	}
+	return &list[0], nil
+}
+
+// Signup registers a new user in the default organization and returns a JWT token.
 func Signup(db *sql.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			apierror.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
 		var req models.SignupRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			apierror.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
 
 		if req.Name == "" || req.Email == "" || req.Password == "" {
-			http.Error(w, "Name, email and password are required", http.StatusBadRequest)
+			apierror.Error(w, "Name, email and password are required", http.StatusBadRequest)
 			return
 		}
 
 		hashedPassword, err := auth.HashPassword(req.Password)
 		if err != nil {
-			http.Error(w, "Failed to process password", http.StatusInternalServerError)
+			apierror.Internal(w, "Failed to process password", err)
 			return
 		}
 
 		userID := uuid.New().String()
 		role := "VIEWER"
 
-		_, err = db.Exec(
-			`INSERT INTO users (id, name, email, password_hash, role, is_active) 
-			 VALUES (?, ?, ?, ?, ?, 1)`,
-			userID, req.Name, req.Email, hashedPassword, role,
-		)
+		err = repositories.NewUserRepository(db).ForOrg(models.DefaultOrgID).CreateUser(models.User{
+			ID:           userID,
+			Name:         req.Name,
+			Email:        req.Email,
+			PasswordHash: hashedPassword,
+			Role:         role,
+			IsActive:     true,
+		})
 		if err != nil {
-			http.Error(w, "Could not create user", http.StatusBadRequest)
+			repoError(w, err, "Could not create user")
 			return
 		}
 
-		token, err := auth.GenerateJWT(userID, role)
+		token, err := auth.GenerateJWT(userID, role, models.DefaultOrgID)
 		if err != nil {
-			http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+			apierror.Internal(w, "Failed to generate token", err)
 			return
 		}
 
@@ -101,7 +136,7 @@ func ViewEmployees(database *sql.DB) http.Handler {
 
 		role := r.Context().Value(middleware.RoleKey).(string)
 
-		perms, _ := dbrepo.GetPermissionsByRole(database, role)
+		perms, _ := dbrepo.GetPermissionsByRole(database, orgFromRequest(r), role)
 		fieldPerms := perms["employees"].Fields
 
 		employee := map[string]interface{}{
@@ -120,7 +155,7 @@ func EditEmployees(database *sql.DB) http.Handler {
 
 		role := r.Context().Value(middleware.RoleKey).(string)
 
-		perms, _ := dbrepo.GetPermissionsByRole(database, role)
+		perms, _ := dbrepo.GetPermissionsByRole(database, orgFromRequest(r), role)
 		fieldPerms := perms["employees"].Fields
 
 		var input map[string]interface{}
@@ -136,7 +171,7 @@ func EditEmployees(database *sql.DB) http.Handler {
 
 func (h *AdminHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		apierror.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -148,30 +183,36 @@ func (h *AdminHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid body", http.StatusBadRequest)
+		apierror.Error(w, "invalid body", http.StatusBadRequest)
 		return
 	}
 
 	if req.Name == "" || req.Email == "" || req.Password == "" {
-		http.Error(w, "name, email, password required", http.StatusBadRequest)
+		apierror.Error(w, "name, email, password required", http.StatusBadRequest)
 		return
 	}
 
 	if req.Role == "" {
 		req.Role = rbac.RoleViewer
 	}
-	validRole := map[string]bool{
-		rbac.RoleAdmin: true, rbac.RoleManager: true,
-		rbac.RoleEditor: true, rbac.RoleViewer: true,
+	if !grantableRole(w, r, h.UserRepo.DB, req.Role) {
+		return
 	}
-	if !validRole[req.Role] {
-		http.Error(w, "invalid role", http.StatusBadRequest)
+
+	orgID := orgFromRequest(r)
+	violation, err := checkHeldRoles(h.UserRepo.DB, orgID, "", req.Role)
+	if err != nil {
+		apierror.Internal(w, "constraint check failed", err)
+		return
+	}
+	if violation != nil {
+		writeConstraintViolation(w, violation)
 		return
 	}
 
 	hashed, err := auth.HashPassword(req.Password)
 	if err != nil {
-		http.Error(w, "password error", http.StatusInternalServerError)
+		apierror.Internal(w, "password error", err)
 		return
 	}
 
@@ -183,8 +224,8 @@ func (h *AdminHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		Role:         req.Role,
 		IsActive:     true,
 	}
-	if err := h.UserRepo.CreateUser(user); err != nil {
-		http.Error(w, "create failed", http.StatusInternalServerError)
+	if err := h.UserRepo.ForOrg(orgID).CreateUser(user); err != nil {
+		repoError(w, err, "create failed")
 		return
 	}
 
@@ -192,12 +233,78 @@ func (h *AdminHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "user created"})
 }
 
+// UpdateUserRole changes a user's role after checking separation-of-duties
+// constraints. Only a superuser may grant a superuser role, and the last
+// active superuser of the default organization keeps theirs.
+func (h *AdminHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		apierror.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		UserID string `json:"user_id"`
+		Role   string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if req.UserID == "" || req.Role == "" {
+		apierror.Error(w, "user_id and role required", http.StatusBadRequest)
+		return
+	}
+	if !grantableRole(w, r, h.UserRepo.DB, req.Role) {
+		return
+	}
+
+	orgID := orgFromRequest(r)
+	repo := h.UserRepo.ForOrg(orgID)
+	user, err := repo.GetUserByID(req.UserID)
+	if err != nil {
+		apierror.Internal(w, "failed to fetch user", err)
+		return
+	}
+	if user == nil {
+		apierror.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	if !keepsSuperuser(w, h.UserRepo.DB, orgID, user.ID, req.Role) {
+		return
+	}
+
+	violation, err := checkHeldRoles(h.UserRepo.DB, orgID, user.ID, req.Role)
+	if err != nil {
+		apierror.Internal(w, "constraint check failed", err)
+		return
+	}
+	if violation != nil {
+		writeConstraintViolation(w, violation)
+		return
+	}
+
+	if err := repo.UpdateUserRole(user.ID, req.Role); err != nil {
+		repoError(w, err, "update failed")
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "role updated"})
+}
+
 func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	users, err := h.UserRepo.ListUsers()
+	tablePerm, _ := r.Context().Value(middleware.TablePermKey).(models.ResourcePermission)
+	q, err := listQuery(r, repositories.UserListing, tablePerm)
+	if err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	users, next, err := h.UserRepo.ForOrg(orgFromRequest(r)).ListUsersPage(q)
 	if err != nil {
-		http.Error(w, "failed to list users", http.StatusInternalServerError)
+		repoError(w, err, "failed to list users")
 		return
 	}
+	writeNextPage(w, r, next)
 	json.NewEncoder(w).Encode(map[string]interface{}{"users": users})
 }
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/models"
+	repositories "rbac-backend/internal/repository"
+)
+
+func TestOnlySuperusersGrantSuperuserRoles(t *testing.T) {
+	database, adminID := setupHandlerDB(t)
+	users := NewAdminHandler(repositories.NewUserRepository(database))
+	orgs := NewOrgHandler(database)
+	if err := db.SaveRole(database, models.Role{Name: "HR"}); err != nil {
+		t.Fatal(err)
+	}
+	veraID := addMember(t, database, "vera@example.com", models.DefaultOrgID, "HR")
+	hr := caller{userID: veraID, role: "HR", perm: models.ResourcePermission{View: true, Create: true, Edit: true}}
+	admin := caller{userID: adminID, role: "ADMIN"}
+
+	tests := []struct {
+		name string
+		h    http.HandlerFunc
+		r    *http.Request
+		want int
+	}{
+		{"promote", users.UpdateUserRole, hr.request("PUT", "/admin/update-user-role", map[string]string{"user_id": veraID, "role": "ADMIN"}), http.StatusForbidden},
+		{"create", users.CreateUser, hr.request("POST", "/admin/create-user", map[string]string{"name": "Mallory", "email": "mallory@example.com", "password": "pw", "role": "ADMIN"}), http.StatusForbidden},
+		{"invite", orgs.InviteMember, hr.request("POST", "/admin/org/members", map[string]string{"email": "admin@example.com", "role": "ADMIN"}), http.StatusForbidden},
+		{"create a viewer", users.CreateUser, hr.request("POST", "/admin/create-user", map[string]string{"name": "Ann", "email": "ann@example.com", "password": "pw", "role": "VIEWER"}), http.StatusCreated},
+		{"demote the last superuser", users.UpdateUserRole, admin.request("PUT", "/admin/update-user-role", map[string]string{"user_id": adminID, "role": "VIEWER"}), http.StatusConflict},
+		{"promote as a superuser", users.UpdateUserRole, admin.request("PUT", "/admin/update-user-role", map[string]string{"user_id": veraID, "role": "ADMIN"}), http.StatusOK},
+	}
+	for _, tt := range tests {
+		if status, body := serve(t, tt.h, tt.r); status != tt.want {
+			t.Errorf("%s: status %d, want %d: %v", tt.name, status, tt.want, body)
+		}
+	}
+}
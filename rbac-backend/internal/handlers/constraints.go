@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"rbac-backend/internal/apierror"
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+	repositories "rbac-backend/internal/repository"
+)
+
+// writeConstraintViolation sends the 403 for a separation-of-duties
+// violation, naming the constraint (and roles) in the details.
+func writeConstraintViolation(w http.ResponseWriter, v *models.ConstraintViolation) {
+	details := map[string]interface{}{"constraint": v.Constraint}
+	if len(v.Roles) > 0 {
+		details["roles"] = v.Roles
+	}
+	apierror.Write(w, http.StatusForbidden, apierror.CodeConstraintViolation, v.Message, details)
+}
+
+// actionAllowed checks the action constraints for table/action and writes
+// the 403 when one refuses it. record is the stored row, or the new one on
+// create, and change the fields being written.
+func actionAllowed(w http.ResponseWriter, r *http.Request, database *sql.DB, table, action string, record, change map[string]interface{}) bool {
+	c, err := db.GetConstraints(database)
+	if err != nil {
+		apierror.Internal(w, "constraint check failed", err)
+		return false
+	}
+	if v := rbac.CheckAction(c, table, action, subjectFromRequest(r), record, change); v != nil {
+		writeConstraintViolation(w, v)
+		return false
+	}
+	return true
+}
+
+// checkHeldRoles verifies the exclusive-roles constraints for a user who would
+// hold assignedRole plus extra in the organization, together with their active
+// role elevations there.
+func checkHeldRoles(database *sql.DB, orgID, userID, assignedRole string, extra ...string) (*models.ConstraintViolation, error) {
+	c, err := db.GetConstraints(database)
+	if err != nil {
+		return nil, err
+	}
+	roles := append([]string{assignedRole}, extra...)
+	if userID != "" {
+		grants, err := repositories.NewElevationRepository(database).ForOrg(orgID).ActiveForUser(userID, time.Now().UTC())
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range grants {
+			if g.Role != "" {
+				roles = append(roles, g.Role)
+			}
+		}
+	}
+	return rbac.CheckRoleAssignment(c, roles), nil
+}
+
+func (h *RolesHandler) GetConstraints(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	c, err := db.GetConstraints(h.DB)
+	if err != nil {
+		apierror.Internal(w, "failed to load constraints", err)
+		return
+	}
+	json.NewEncoder(w).Encode(c)
+}
+
+// UpdateConstraints replaces the constraints. They apply to every
+// organization, so the route only accepts callers in the default one.
+func (h *RolesHandler) UpdateConstraints(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var c models.Constraints
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		apierror.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if err := rbac.ValidateConstraints(c); err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := db.UpdateConstraints(h.DB, c); err != nil {
+		apierror.Internal(w, "update failed", err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+}
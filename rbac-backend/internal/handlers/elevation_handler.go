@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"rbac-backend/internal/apierror"
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/middleware"
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+	repositories "rbac-backend/internal/repository"
+	"rbac-backend/internal/validate"
+)
+
+// MaxElevationMinutes caps how long a single elevation may last.
+const MaxElevationMinutes = 8 * 60
+
+// ElevationHandler implements the just-in-time elevation workflow: users
+// request a role or table action for a limited time, a superuser approves or
+// denies it, and grants are revoked manually or when they expire.
+type ElevationHandler struct {
+	Repo *repositories.ElevationRepository
+	DB   *sql.DB
+}
+
+func NewElevationHandler(repo *repositories.ElevationRepository, database *sql.DB) *ElevationHandler {
+	return &ElevationHandler{Repo: repo, DB: database}
+}
+
+func (h *ElevationHandler) RequestElevation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		apierror.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Role            string `json:"role"`
+		Table           string `json:"table"`
+		Action          string `json:"action"`
+		Justification   string `json:"justification"`
+		DurationMinutes int    `json:"duration_minutes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Role != "" {
+		def, err := db.GetRole(h.DB, req.Role)
+		if err != nil {
+			apierror.Internal(w, "failed to fetch role", err)
+			return
+		}
+		if def == nil {
+			apierror.Error(w, "invalid role", http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch {
+	case req.Role != "" && (req.Table != "" || req.Action != ""):
+		apierror.Error(w, "request either a role or a table action, not both", http.StatusBadRequest)
+		return
+	case req.Role == "" && (req.Table == "" || req.Action == ""):
+		apierror.Error(w, "role, or table and action, required", http.StatusBadRequest)
+		return
+	case req.Justification == "":
+		apierror.Error(w, "justification required", http.StatusBadRequest)
+		return
+	case req.DurationMinutes <= 0 || req.DurationMinutes > MaxElevationMinutes:
+		apierror.Error(w, fmt.Sprintf("duration_minutes must be between 1 and %d", MaxElevationMinutes), http.StatusBadRequest)
+		return
+	}
+
+	if req.Role == "" {
+		if errs := elevationTargetErrors(req.Table, req.Action); len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+	}
+
+	e := models.Elevation{
+		ID:              uuid.New().String(),
+		UserID:          userID,
+		Role:            req.Role,
+		Table:           req.Table,
+		Action:          req.Action,
+		Justification:   req.Justification,
+		DurationMinutes: req.DurationMinutes,
+		Status:          models.ElevationPending,
+		RequestedAt:     time.Now().UTC(),
+	}
+	orgID := orgFromRequest(r)
+	if err := h.Repo.ForOrg(orgID).CreateElevation(e); err != nil {
+		repoError(w, err, "failed to create request")
+		return
+	}
+	e.OrgID = orgID
+	h.audit(orgID, userID, "elevation.request", e.ID, elevationSummary(e))
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(e)
+}
+
+// elevationTargetErrors checks that a table action elevation names a table
+// of rbac.Registry and one of rbac.TableActions.
+func elevationTargetErrors(table, action string) validate.Errors {
+	var errs validate.Errors
+	if _, ok := rbac.Registry[table]; !ok {
+		tables := make([]string, 0, len(rbac.Registry))
+		for t := range rbac.Registry {
+			tables = append(tables, t)
+		}
+		sort.Strings(tables)
+		errs = append(errs, validate.FieldError{Field: "table", Rule: "enum", Message: "must be one of " + strings.Join(tables, ", ")})
+	}
+	if !contains(rbac.TableActions, action) {
+		errs = append(errs, validate.FieldError{Field: "action", Rule: "enum", Message: "must be one of " + strings.Join(rbac.TableActions, ", ")})
+	}
+	return errs
+}
+
+// ListElevations returns every request in the caller's organization for
+// superusers and the caller's own otherwise.
+func (h *ElevationHandler) ListElevations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+
+	filter := userID
+	if h.isSuperuser(role) {
+		filter = r.URL.Query().Get("user_id")
+	}
+
+	list, err := h.Repo.ForOrg(orgFromRequest(r)).ListElevations(filter)
+	if err != nil {
+		apierror.Internal(w, "failed to list elevations", err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"elevations": list})
+}
+
+func (h *ElevationHandler) ApproveElevation(w http.ResponseWriter, r *http.Request) {
+	e, approverID, ok := h.loadForDecision(w, r)
+	if !ok {
+		return
+	}
+	if e.UserID == approverID {
+		apierror.Error(w, "cannot approve your own request", http.StatusForbidden)
+		return
+	}
+
+	if e.Role != "" {
+		requester, err := repositories.NewUserRepository(h.DB).ForOrg(e.OrgID).GetUserByID(e.UserID)
+		if err != nil {
+			apierror.Internal(w, "failed to fetch requester", err)
+			return
+		}
+		if requester == nil {
+			apierror.Error(w, "requester is no longer a member", http.StatusConflict)
+			return
+		}
+		violation, err := checkHeldRoles(h.DB, e.OrgID, e.UserID, requester.Role, e.Role)
+		if err != nil {
+			apierror.Internal(w, "constraint check failed", err)
+			return
+		}
+		if violation != nil {
+			writeConstraintViolation(w, violation)
+			return
+		}
+	}
+
+	approved, err := h.Repo.ForOrg(e.OrgID).Approve(e.ID, approverID, time.Now().UTC())
+	if err != nil {
+		apierror.Internal(w, "approve failed", err)
+		return
+	}
+	if !approved {
+		apierror.Error(w, "request is not pending", http.StatusConflict)
+		return
+	}
+	h.audit(e.OrgID, approverID, "elevation.approve", e.ID, elevationSummary(*e))
+
+	json.NewEncoder(w).Encode(map[string]string{"status": models.ElevationApproved})
+}
+
+func (h *ElevationHandler) DenyElevation(w http.ResponseWriter, r *http.Request) {
+	e, approverID, ok := h.loadForDecision(w, r)
+	if !ok {
+		return
+	}
+
+	closed, err := h.Repo.ForOrg(e.OrgID).Close(e.ID, models.ElevationDenied, time.Now().UTC(), models.ElevationPending)
+	if err != nil {
+		apierror.Internal(w, "deny failed", err)
+		return
+	}
+	if !closed {
+		apierror.Error(w, "request is not pending", http.StatusConflict)
+		return
+	}
+	h.audit(e.OrgID, approverID, "elevation.deny", e.ID, elevationSummary(*e))
+
+	json.NewEncoder(w).Encode(map[string]string{"status": models.ElevationDenied})
+}
+
+// RevokeElevation ends a pending or active grant early. Superusers can revoke
+// any request; other users can only give up their own.
+func (h *ElevationHandler) RevokeElevation(w http.ResponseWriter, r *http.Request) {
+	e, callerID, ok := h.loadForDecision(w, r)
+	if !ok {
+		return
+	}
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+	if !h.isSuperuser(role) && e.UserID != callerID {
+		apierror.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	closed, err := h.Repo.ForOrg(e.OrgID).Close(e.ID, models.ElevationRevoked, time.Now().UTC(), models.ElevationPending, models.ElevationApproved)
+	if err != nil {
+		apierror.Internal(w, "revoke failed", err)
+		return
+	}
+	if !closed {
+		apierror.Error(w, "request is no longer active", http.StatusConflict)
+		return
+	}
+	h.audit(e.OrgID, callerID, "elevation.revoke", e.ID, elevationSummary(*e))
+
+	json.NewEncoder(w).Encode(map[string]string{"status": models.ElevationRevoked})
+}
+
+// ListAudit returns the caller's organization's recent audit log entries (?limit=, default 100).
+func (h *ElevationHandler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit := 100
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 1000 {
+		limit = v
+	}
+	entries, err := db.ListAudit(h.DB, orgFromRequest(r), limit)
+	if err != nil {
+		apierror.Internal(w, "failed to list audit log", err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"entries": entries})
+}
+
+// RunExpiry revokes expired grants every interval until ctx is done.
+func (h *ElevationHandler) RunExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		h.expireDue()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *ElevationHandler) expireDue() {
+	expired, err := h.Repo.ExpireDue(time.Now().UTC())
+	if err != nil {
+		log.Println("elevation expiry failed:", err)
+	}
+	for _, e := range expired {
+		h.audit(e.OrgID, "", "elevation.expire", e.ID, elevationSummary(e))
+	}
+}
+
+func (h *ElevationHandler) loadForDecision(w http.ResponseWriter, r *http.Request) (*models.Elevation, string, bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		apierror.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil, "", false
+	}
+	callerID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		apierror.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, "", false
+	}
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		apierror.Error(w, "elevation id required", http.StatusBadRequest)
+		return nil, "", false
+	}
+	e, err := h.Repo.ForOrg(orgFromRequest(r)).GetElevationByID(id)
+	if err != nil {
+		apierror.Internal(w, "failed to fetch elevation", err)
+		return nil, "", false
+	}
+	if e == nil {
+		apierror.Error(w, "not found", http.StatusNotFound)
+		return nil, "", false
+	}
+	return e, callerID, true
+}
+
+func (h *ElevationHandler) isSuperuser(role string) bool {
+	ok, err := db.IsSuperuser(h.DB, role)
+	return err == nil && ok
+}
+
+func (h *ElevationHandler) audit(orgID, actorID, action, target, details string) {
+	if err := db.RecordAudit(h.DB, orgID, actorID, action, target, details); err != nil {
+		log.Printf("audit %s %s: %v", action, target, err)
+	}
+}
+
+func elevationSummary(e models.Elevation) string {
+	grant := "role " + e.Role
+	if e.Role == "" {
+		grant = e.Table + "." + e.Action
+	}
+	return fmt.Sprintf("user=%s grant=%s minutes=%d justification=%q", e.UserID, grant, e.DurationMinutes, e.Justification)
+}
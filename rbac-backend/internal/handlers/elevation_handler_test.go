@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	repositories "rbac-backend/internal/repository"
+)
+
+func TestRequestElevationChecksTableAndAction(t *testing.T) {
+	database, adminID := setupHandlerDB(t)
+	h := NewElevationHandler(repositories.NewElevationRepository(database), database)
+	viewer := caller{userID: adminID, role: "VIEWER"}
+
+	tests := []struct {
+		table, action string
+		want          int
+		fields        int
+	}{
+		{"tasks", "delete", http.StatusCreated, 0},
+		{"nope", "view", http.StatusUnprocessableEntity, 1},
+		{"tasks", "archive", http.StatusUnprocessableEntity, 1},
+		{"nope", "archive", http.StatusUnprocessableEntity, 2},
+		{"tasks", "", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		body := map[string]interface{}{"table": tt.table, "action": tt.action, "justification": "release", "duration_minutes": 30}
+		status, got := serve(t, h.RequestElevation, viewer.request("POST", "/elevations/request", body))
+		if status != tt.want {
+			t.Errorf("%s:%s: status %d, want %d: %v", tt.table, tt.action, status, tt.want, got)
+			continue
+		}
+		if tt.fields > 0 {
+			fields := got.(map[string]interface{})["error"].(map[string]interface{})["details"].(map[string]interface{})["fields"].([]interface{})
+			if len(fields) != tt.fields {
+				t.Errorf("%s:%s: %d field errors, want %d: %v", tt.table, tt.action, len(fields), tt.fields, fields)
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"rbac-backend/internal/apierror"
+	"rbac-backend/internal/rbac"
+	repositories "rbac-backend/internal/repository"
+	"rbac-backend/internal/validate"
+)
+
+// repoError answers a failed repository call: 400 for ErrBadCursor, 404 for
+// ErrNotFound, 409 for ErrConflict and ErrConstraint, 412 for ErrStale, all
+// with the repository's message, and a logged 500 with message for anything
+// else. Updates answer ErrStale with the current record instead (see
+// writeStale).
+func repoError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, repositories.ErrBadCursor):
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, repositories.ErrStale):
+		apierror.Error(w, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, repositories.ErrNotFound):
+		apierror.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repositories.ErrConflict), errors.Is(err, repositories.ErrConstraint):
+		apierror.Error(w, err.Error(), http.StatusConflict)
+	default:
+		apierror.Internal(w, message, err)
+	}
+}
+
+// writeInvalidPermissions sends 422 with the validation report as details.
+func writeInvalidPermissions(w http.ResponseWriter, report rbac.ValidationReport) {
+	apierror.Write(w, http.StatusUnprocessableEntity, apierror.CodeInvalidPermissions, "permission config is invalid", report)
+}
+
+// ValidationDetails are the details of a validation_failed error.
+type ValidationDetails struct {
+	Fields validate.Errors `json:"fields"`
+}
+
+// writeValidationErrors sends 422 listing every invalid field.
+func writeValidationErrors(w http.ResponseWriter, errs validate.Errors) {
+	apierror.Write(w, http.StatusUnprocessableEntity, apierror.CodeValidationFailed, "request is invalid: "+errs.Error(), ValidationDetails{Fields: errs})
+}
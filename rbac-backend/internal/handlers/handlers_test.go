@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"rbac-backend/internal/config"
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/middleware"
+	"rbac-backend/internal/models"
+
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite"
+)
+
+// setupHandlerDB returns a migrated database with the seeded admin and the
+// admin's id.
+func setupHandlerDB(t *testing.T) (*sql.DB, string) {
+	config.AppConfig = &config.Config{JWTSecret: "test-secret", FieldMode: "lenient"}
+	t.Chdir(filepath.Join("..", "..")) // RunMigrations looks for ./migrations
+
+	database, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "rbac.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := db.RunMigrations(database); err != nil {
+		t.Fatal(err)
+	}
+	db.SeedAdmin(database)
+
+	var adminID string
+	if err := database.QueryRow(`SELECT id FROM users WHERE email = 'admin@example.com'`).Scan(&adminID); err != nil {
+		t.Fatal(err)
+	}
+	return database, adminID
+}
+
+// caller is the context AuthMiddleware and RBACMiddleware would give a
+// request.
+type caller struct {
+	userID string
+	role   string
+	orgID  string
+	perm   models.ResourcePermission
+}
+
+// request builds a request made by c with body encoded as JSON.
+func (c caller) request(method, target string, body interface{}) *http.Request {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	r := httptest.NewRequest(method, target, &buf)
+	orgID := c.orgID
+	if orgID == "" {
+		orgID = models.DefaultOrgID
+	}
+	ctx := context.WithValue(r.Context(), middleware.UserIDKey, c.userID)
+	ctx = context.WithValue(ctx, middleware.RoleKey, c.role)
+	ctx = context.WithValue(ctx, middleware.OrgIDKey, orgID)
+	ctx = context.WithValue(ctx, middleware.TablePermKey, c.perm)
+	return r.WithContext(ctx)
+}
+
+// serve runs h on r and returns the status and the decoded JSON body.
+func serve(t *testing.T, h http.HandlerFunc, r *http.Request) (int, interface{}) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h(rec, r)
+	var body interface{}
+	if rec.Body.Len() > 0 {
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s %s: invalid JSON: %s", r.Method, r.URL, rec.Body)
+		}
+	}
+	return rec.Code, body
+}
+
+// errorCode is the code of the error envelope in body, if it is one.
+func errorCode(body interface{}) string {
+	m, _ := body.(map[string]interface{})
+	e, _ := m["error"].(map[string]interface{})
+	code, _ := e["code"].(string)
+	return code
+}
+
+// addMember creates an account with email and makes it a member of orgID
+// with role. It returns the account's id.
+func addMember(t *testing.T, database *sql.DB, email, orgID, role string) string {
+	t.Helper()
+	id := uuid.New().String()
+	if _, err := database.Exec(
+		`INSERT INTO users (id, name, email, password_hash, role) VALUES (?, ?, ?, 'x', ?)`, id, email, email, role,
+	); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec(`INSERT INTO organization_members (org_id, user_id, role) VALUES (?, ?, ?)`, orgID, id, role); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+	repositories "rbac-backend/internal/repository"
+)
+
+// Page sizes of list endpoints.
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// NextCursorHeader carries the cursor of the next page of a list.
+const NextCursorHeader = "X-Next-Cursor"
+
+// listQuery reads the paging, sort and filter parameters of a list request:
+//
+//	limit, cursor          page size and position
+//	sort=-created_at,title sort fields, "-" for descending
+//	q=text                 text search over the searchable fields
+//	<field>=value          equality filter, e.g. status=DONE
+//	<field>_from, _to      date range, e.g. created_at_from=2024-01-01
+//
+// Listing.Params renames some filters, e.g. assignee for assignees. A field
+// the caller cannot view, or sees redacted, cannot be sorted or filtered on,
+// since the order or the matches would reveal its values; text search skips
+// it. When the default sort uses such a field, the list is sorted by id.
+func listQuery(r *http.Request, l repositories.Listing, perm models.ResourcePermission) (repositories.ListQuery, error) {
+	params := r.URL.Query()
+	q := repositories.ListQuery{
+		Equal:  map[string]string{},
+		From:   map[string]string{},
+		To:     map[string]string{},
+		Limit:  defaultPageSize,
+		Cursor: params.Get("cursor"),
+		Text:   strings.TrimSpace(params.Get("q")),
+	}
+
+	if s := params.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxPageSize {
+			return q, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		q.Limit = n
+	}
+
+	if s := params.Get("sort"); s != "" {
+		for _, part := range strings.Split(s, ",") {
+			field, desc := strings.CutPrefix(part, "-")
+			if !contains(l.Sortable, field) || !visible(perm, field) {
+				return q, fmt.Errorf("cannot sort by %q", field)
+			}
+			q.Sort = append(q.Sort, repositories.SortKey{Field: field, Desc: desc})
+		}
+	}
+
+	if len(q.Sort) == 0 {
+		for _, k := range l.DefaultSort {
+			if !visible(perm, k.Field) {
+				// Nor may the default sort order by a hidden field.
+				q.Sort = []repositories.SortKey{{Field: "id"}}
+				break
+			}
+		}
+	}
+
+	for _, field := range l.Searchable {
+		if visible(perm, field) {
+			q.TextFields = append(q.TextFields, field)
+		}
+	}
+
+	for name := range params {
+		field := name
+		if f, ok := l.Params[name]; ok {
+			field = f
+		}
+		switch {
+		case contains(l.Filters, field):
+			if !visible(perm, field) {
+				return q, fmt.Errorf("cannot filter by %q", name)
+			}
+			q.Equal[field] = params.Get(name)
+		case strings.HasSuffix(name, "_from") || strings.HasSuffix(name, "_to"):
+			field, bound := strings.TrimSuffix(strings.TrimSuffix(name, "_from"), "_to"), "to"
+			if strings.HasSuffix(name, "_from") {
+				bound = "from"
+			}
+			if !contains(l.Dates, field) || !visible(perm, field) {
+				return q, fmt.Errorf("cannot filter by %q", name)
+			}
+			t, err := parseBound(params.Get(name), bound == "to")
+			if err != nil {
+				return q, fmt.Errorf("%s: %v", name, err)
+			}
+			if bound == "from" {
+				q.From[field] = t
+			} else {
+				q.To[field] = t
+			}
+		}
+	}
+	return q, nil
+}
+
+// visible reports whether the caller sees field unredacted.
+func visible(perm models.ResourcePermission, field string) bool {
+	return rbac.Evaluate(perm, rbac.ActionView, field).Allowed() && rbac.FieldRedaction(perm.Fields, field) == ""
+}
+
+// parseBound reads a date or RFC 3339 time as the UTC "2006-01-02 15:04:05"
+// the repositories compare on. A date as an upper bound covers the whole day.
+func parseBound(s string, upper bool) (string, error) {
+	const layout = "2006-01-02 15:04:05"
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC().Format(layout), nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return "", errors.New("want a date (2006-01-02) or an RFC 3339 time")
+	}
+	if upper {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return t.Format(layout), nil
+}
+
+// writeNextPage points the client at the next page, if there is one, with a
+// Link header and NextCursorHeader.
+func writeNextPage(w http.ResponseWriter, r *http.Request, next string) {
+	if next == "" {
+		return
+	}
+	u := *r.URL
+	params := u.Query()
+	params.Set("cursor", next)
+	u.RawQuery = params.Encode()
+	w.Header().Set("Link", "<"+u.RequestURI()+`>; rel="next"`)
+	w.Header().Set(NextCursorHeader, next)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"rbac-backend/internal/apierror"
+	"rbac-backend/internal/middleware"
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+	repositories "rbac-backend/internal/repository"
+	"rbac-backend/internal/utils"
+)
+
+// MeHandler serves the authenticated caller's own profile and permissions.
+type MeHandler struct {
+	DB *sql.DB
+}
+
+func NewMeHandler(database *sql.DB) *MeHandler {
+	return &MeHandler{DB: database}
+}
+
+// tableAccess is the caller's resolved access to one table. Conditions holds
+// the row-level condition of actions that only apply to some records.
+type tableAccess struct {
+	Actions    map[string]bool                   `json:"actions"`
+	Conditions map[string]string                 `json:"conditions,omitempty"`
+	Fields     map[string]models.FieldPermission `json:"fields"`
+}
+
+// GetMe returns the caller's profile and active elevations in their active organization.
+func (h *MeHandler) GetMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	orgID := orgFromRequest(r)
+	user, err := repositories.NewUserRepository(h.DB).ForOrg(orgID).GetUserByID(userID)
+	if err != nil {
+		apierror.Internal(w, "failed to fetch user", err)
+		return
+	}
+	if user == nil {
+		apierror.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	_, grants, err := middleware.EffectivePermissions(h.DB, orgID, user.Role, userID)
+	if err != nil {
+		apierror.Internal(w, "permission lookup failed", err)
+		return
+	}
+	if grants == nil {
+		grants = []models.Elevation{}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user":       user,
+		"org_id":     orgID,
+		"elevations": grants,
+	})
+}
+
+// GetMyPermissions returns what the caller can do on each table and field,
+// resolved exactly as RBACMiddleware and the field filters would: superuser
+// access, inheritance and active elevations included. Tables the caller
+// cannot access are omitted. Field create/edit follow
+// utils.FilterEditableFields, which the create and update handlers use.
+func (h *MeHandler) GetMyPermissions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+
+	orgID := orgFromRequest(r)
+	perms, grants, err := middleware.EffectivePermissions(h.DB, orgID, role, userID)
+	if err != nil {
+		apierror.Internal(w, "permission lookup failed", err)
+		return
+	}
+
+	tables := map[string]tableAccess{}
+	for table, perm := range perms {
+		tables[table] = resolveTableAccess(table, perm)
+	}
+	elevationIDs := []string{}
+	for _, g := range grants {
+		elevationIDs = append(elevationIDs, g.ID)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_id":    userID,
+		"org_id":     orgID,
+		"role":       role,
+		"elevations": elevationIDs,
+		"tables":     tables,
+	})
+}
+
+func resolveTableAccess(table string, perm models.ResourcePermission) tableAccess {
+	access := tableAccess{
+		Actions: map[string]bool{},
+		Fields:  map[string]models.FieldPermission{},
+	}
+	for _, action := range rbac.TableActions {
+		allowed := rbac.Evaluate(perm, action, "").Allowed()
+		access.Actions[action] = allowed
+		if cond := perm.Conditions[action]; allowed && cond != "" {
+			if access.Conditions == nil {
+				access.Conditions = map[string]string{}
+			}
+			access.Conditions[action] = cond
+		}
+	}
+
+	row := map[string]interface{}{}
+	for _, field := range rbac.Registry[table] {
+		row[field] = nil
+	}
+	viewable := utils.FilterFields(row, perm.Fields)
+	editable := utils.FilterEditableFields(row, perm.Fields)
+	for field := range row {
+		_, canView := viewable[field]
+		_, canEdit := editable[field]
+		fp := models.FieldPermission{
+			View:   access.Actions[rbac.ActionView] && canView,
+			Create: access.Actions[rbac.ActionCreate] && canEdit,
+			Edit:   access.Actions[rbac.ActionEdit] && canEdit,
+		}
+		if fp.View {
+			fp.Redact = rbac.FieldRedaction(perm.Fields, field)
+		}
+		access.Fields[field] = fp
+	}
+	return access
+}
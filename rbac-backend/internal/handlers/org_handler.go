@@ -0,0 +1,387 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"rbac-backend/internal/apierror"
+	"rbac-backend/internal/auth"
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/middleware"
+	"rbac-backend/internal/models"
+	repositories "rbac-backend/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// OrgHandler serves organizations and their memberships.
+type OrgHandler struct {
+	DB *sql.DB
+}
+
+func NewOrgHandler(database *sql.DB) *OrgHandler {
+	return &OrgHandler{DB: database}
+}
+
+// ListMyOrgs returns the organizations the caller belongs to and the role
+// they hold in each.
+func (h *OrgHandler) ListMyOrgs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		apierror.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	list, err := db.ListMemberships(h.DB, userID)
+	if err != nil {
+		apierror.Internal(w, "failed to list organizations", err)
+		return
+	}
+	if list == nil {
+		list = []models.Membership{}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"active":        orgFromRequest(r),
+		"organizations": list,
+	})
+}
+
+// SwitchOrg issues a new token for another organization the caller belongs
+// to, carrying their role in that organization.
+func (h *OrgHandler) SwitchOrg(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		apierror.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		OrgID string `json:"org_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.OrgID == "" {
+		apierror.Error(w, "org_id required", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	m, err := db.GetMembership(h.DB, req.OrgID, userID)
+	if err != nil {
+		apierror.Internal(w, "failed to load membership", err)
+		return
+	}
+	if m == nil {
+		apierror.Error(w, "not a member of this organization", http.StatusForbidden)
+		return
+	}
+
+	token, err := auth.GenerateJWT(userID, m.Role, m.OrgID)
+	if err != nil {
+		apierror.Internal(w, "failed to issue token", err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"token": token, "org_id": m.OrgID, "role": m.Role})
+}
+
+func (h *OrgHandler) ListOrgs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	orgs, err := db.ListOrganizations(h.DB)
+	if err != nil {
+		apierror.Internal(w, "failed to list organizations", err)
+		return
+	}
+	if orgs == nil {
+		orgs = []models.Organization{}
+	}
+	json.NewEncoder(w).Encode(orgs)
+}
+
+// CreateOrg adds an organization seeded with the default role permissions.
+// The caller becomes its first member with the role they hold now.
+func (h *OrgHandler) CreateOrg(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		apierror.Error(w, "name required", http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+	org := models.Organization{ID: uuid.New().String(), Name: req.Name, CreatedAt: time.Now().UTC()}
+	if err := db.CreateOrganization(h.DB, org, userID, role); err != nil {
+		apierror.Internal(w, "create failed", err)
+		return
+	}
+	h.audit(models.DefaultOrgID, userID, "org.create", org.ID, fmt.Sprintf("name=%q", org.Name))
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(org)
+}
+
+// InviteMember invites the account with an email to join the active
+// organization with a role, after checking separation-of-duties constraints.
+// The account joins only when it accepts (see AcceptInvitation). The answer
+// is the same whether or not an account with that email exists, so it cannot
+// be used to find out about accounts in other organizations.
+func (h *OrgHandler) InviteMember(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Email) == "" || req.Role == "" {
+		apierror.Error(w, "email and role required", http.StatusBadRequest)
+		return
+	}
+	if !grantableRole(w, r, h.DB, req.Role) {
+		return
+	}
+
+	orgID := orgFromRequest(r)
+	violation, err := checkHeldRoles(h.DB, orgID, "", req.Role)
+	if err != nil {
+		apierror.Internal(w, "constraint check failed", err)
+		return
+	}
+	if violation != nil {
+		writeConstraintViolation(w, violation)
+		return
+	}
+
+	actorID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	id := uuid.New().String()
+	if err := repositories.NewInvitationRepository(h.DB).ForOrg(orgID).Invite(id, req.Email, req.Role, actorID); err != nil {
+		repoError(w, err, "failed to invite member")
+		return
+	}
+	h.audit(orgID, actorID, "member.invite", id, "email="+strings.TrimSpace(req.Email)+" role="+req.Role)
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "invitation recorded"})
+}
+
+// ListInvitations returns the invitations made for the caller's email.
+func (h *OrgHandler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	email, ok := h.callerEmail(w, r)
+	if !ok {
+		return
+	}
+	list, err := repositories.NewInvitationRepository(h.DB).ForEmail(email)
+	if err != nil {
+		apierror.Internal(w, "failed to list invitations", err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"invitations": list})
+}
+
+// AcceptInvitation makes the caller a member of the organization that invited
+// them, with the role of the invitation, after checking separation-of-duties
+// constraints there. Use POST /orgs/switch to work in it.
+func (h *OrgHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	email, ok := h.callerEmail(w, r)
+	if !ok {
+		return
+	}
+	repo := repositories.NewInvitationRepository(h.DB)
+	inv, err := repo.Get(r.PathValue("id"), email)
+	if err != nil {
+		apierror.Internal(w, "failed to load invitation", err)
+		return
+	}
+	if inv == nil {
+		apierror.Error(w, "invitation not found", http.StatusNotFound)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	violation, err := checkHeldRoles(h.DB, inv.OrgID, userID, inv.Role)
+	if err != nil {
+		apierror.Internal(w, "constraint check failed", err)
+		return
+	}
+	if violation != nil {
+		writeConstraintViolation(w, violation)
+		return
+	}
+	if err := repo.Accept(*inv, userID); err != nil {
+		repoError(w, err, "failed to accept invitation")
+		return
+	}
+	h.audit(inv.OrgID, userID, "member.add", userID, "role="+inv.Role+" invitation="+inv.ID)
+
+	m, err := db.GetMembership(h.DB, inv.OrgID, userID)
+	if err != nil || m == nil {
+		apierror.Internal(w, "failed to load membership", err)
+		return
+	}
+	json.NewEncoder(w).Encode(m)
+}
+
+// DeclineInvitation deletes an invitation made for the caller's email.
+func (h *OrgHandler) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	email, ok := h.callerEmail(w, r)
+	if !ok {
+		return
+	}
+	declined, err := repositories.NewInvitationRepository(h.DB).Decline(r.PathValue("id"), email)
+	if err != nil {
+		apierror.Internal(w, "failed to decline invitation", err)
+		return
+	}
+	if !declined {
+		apierror.Error(w, "invitation not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "invitation declined"})
+}
+
+// callerEmail returns the email of the authenticated caller's account.
+func (h *OrgHandler) callerEmail(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	user, err := repositories.NewUserRepository(h.DB).ForOrg(orgFromRequest(r)).GetUserByID(userID)
+	if err != nil {
+		apierror.Internal(w, "failed to fetch user", err)
+		return "", false
+	}
+	if user == nil {
+		apierror.Error(w, "user not found", http.StatusNotFound)
+		return "", false
+	}
+	return user.Email, true
+}
+
+// RemoveMember takes ?user_id= out of the active organization. The account
+// and its other memberships are kept.
+func (h *OrgHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		apierror.Error(w, "user_id required", http.StatusBadRequest)
+		return
+	}
+
+	orgID := orgFromRequest(r)
+	if !keepsSuperuser(w, h.DB, orgID, userID, "") {
+		return
+	}
+	removed, err := repositories.NewUserRepository(h.DB).ForOrg(orgID).RemoveMember(userID)
+	if err != nil {
+		repoError(w, err, "failed to remove member")
+		return
+	}
+	if !removed {
+		apierror.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	actorID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	h.audit(orgID, actorID, "member.remove", userID, "")
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "member removed"})
+}
+
+// grantableRole checks that role exists and that the caller may give it to
+// someone. Only a superuser may grant a superuser role; otherwise users edit
+// would be enough to make anyone, the caller included, a superuser.
+func grantableRole(w http.ResponseWriter, r *http.Request, database *sql.DB, role string) bool {
+	def, err := db.GetRole(database, role)
+	if err != nil {
+		apierror.Internal(w, "failed to fetch role", err)
+		return false
+	}
+	if def == nil {
+		apierror.Error(w, "invalid role", http.StatusBadRequest)
+		return false
+	}
+	if !def.Superuser {
+		return true
+	}
+	callerRole, _ := r.Context().Value(middleware.RoleKey).(string)
+	super, err := db.IsSuperuser(database, callerRole)
+	if err != nil {
+		apierror.Internal(w, "failed to check superusers", err)
+		return false
+	}
+	if !super {
+		apierror.Error(w, "only a superuser can grant a superuser role", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// keepsSuperuser refuses to take the last active superuser of the default
+// organization out of it (newRole "") or give them a role that is not a
+// superuser role, which would leave shared settings unmanageable.
+func keepsSuperuser(w http.ResponseWriter, database *sql.DB, orgID, userID, newRole string) bool {
+	if orgID != models.DefaultOrgID {
+		return true
+	}
+	user, err := repositories.NewUserRepository(database).ForOrg(orgID).GetUserByID(userID)
+	if err != nil {
+		apierror.Internal(w, "failed to fetch user", err)
+		return false
+	}
+	if user == nil || !user.IsActive {
+		return true
+	}
+	super, err := db.IsSuperuser(database, user.Role)
+	if err != nil {
+		apierror.Internal(w, "failed to check superusers", err)
+		return false
+	}
+	if !super {
+		return true
+	}
+	if newRole != "" {
+		stays, err := db.IsSuperuser(database, newRole)
+		if err != nil {
+			apierror.Internal(w, "failed to check superusers", err)
+			return false
+		}
+		if stays {
+			return true
+		}
+	}
+	n, err := db.CountActiveSuperusers(database, "")
+	if err != nil {
+		apierror.Internal(w, "failed to check superusers", err)
+		return false
+	}
+	if n <= 1 {
+		apierror.Error(w, "change would leave no active superuser", http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+func (h *OrgHandler) audit(orgID, actorID, action, target, details string) {
+	if err := db.RecordAudit(h.DB, orgID, actorID, action, target, details); err != nil {
+		log.Println("audit failed:", err)
+	}
+}
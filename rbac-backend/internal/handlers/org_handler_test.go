@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/models"
+)
+
+func TestInviteMemberDoesNotRevealOtherTenants(t *testing.T) {
+	database, adminID := setupHandlerDB(t)
+	h := NewOrgHandler(database)
+	acme := models.Organization{ID: "acme", Name: "Acme", CreatedAt: time.Now()}
+	if err := db.CreateOrganization(database, acme, adminID, "ADMIN"); err != nil {
+		t.Fatal(err)
+	}
+	veraID := addMember(t, database, "vera@example.com", models.DefaultOrgID, "VIEWER")
+	hr := caller{userID: addMember(t, database, "hr@example.com", "acme", "EDITOR"), role: "EDITOR", orgID: "acme"}
+
+	invite := func(email string) (int, string) {
+		t.Helper()
+		status, body := serve(t, h.InviteMember, hr.request("POST", "/admin/org/members", map[string]string{"email": email, "role": "VIEWER"}))
+		return status, fmt.Sprint(body)
+	}
+	existing, existingBody := invite("vera@example.com")
+	unknown, unknownBody := invite("nobody@example.com")
+	if existing != http.StatusAccepted || existing != unknown || existingBody != unknownBody {
+		t.Errorf("an existing account got %d %s, an unknown email %d %s; want the same 202", existing, existingBody, unknown, unknownBody)
+	}
+	if status, _ := invite("HR@example.com"); status != http.StatusConflict {
+		t.Errorf("inviting a member: status %d, want 409", status)
+	}
+	if m, err := db.GetMembership(database, "acme", veraID); err != nil || m != nil {
+		t.Fatalf("vera is a member before accepting: %v, %v", m, err)
+	}
+
+	vera := caller{userID: veraID, role: "VIEWER"}
+	_, body := serve(t, h.ListInvitations, vera.request("GET", "/orgs/invitations", nil))
+	list := body.(map[string]interface{})["invitations"].([]interface{})
+	if len(list) != 1 || list[0].(map[string]interface{})["org_id"] != "acme" {
+		t.Fatalf("vera's invitations: %v, want the one from acme", list)
+	}
+	id := list[0].(map[string]interface{})["id"].(string)
+
+	accept := func(c caller) int {
+		r := c.request("POST", "/orgs/invitations/"+id+"/accept", nil)
+		r.SetPathValue("id", id)
+		status, _ := serve(t, h.AcceptInvitation, r)
+		return status
+	}
+	if status := accept(caller{userID: adminID, role: "ADMIN"}); status != http.StatusNotFound {
+		t.Errorf("accepting someone else's invitation: status %d, want 404", status)
+	}
+	if status := accept(vera); status != http.StatusOK {
+		t.Fatalf("accept: status %d", status)
+	}
+	if m, err := db.GetMembership(database, "acme", veraID); err != nil || m == nil || m.Role != "VIEWER" {
+		t.Errorf("vera after accepting: %v, %v; want a VIEWER of acme", m, err)
+	}
+	if status := accept(vera); status != http.StatusNotFound {
+		t.Errorf("accepting twice: status %d, want 404", status)
+	}
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"rbac-backend/internal/apierror"
 	"rbac-backend/internal/middleware"
 	"rbac-backend/internal/models"
 	"rbac-backend/internal/rbac"
@@ -25,7 +27,7 @@ func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 
 	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		apierror.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -33,22 +35,48 @@ func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 
 	var incoming map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&incoming); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+		apierror.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
 
-	safe := utils.FilterEditableFields(incoming, tablePerm.Fields)
+	// The repository writes safe as columns; binding makes sure it only holds
+	// ProjectRequest fields with values of the right type.
+	delete(incoming, "id")
+	safe, ignored, ok := editableFields(w, r, incoming, tablePerm)
+	if !ok {
+		return
+	}
+	var req ProjectRequest
+	if !bindRequest(w, r, h.Repo.DB, incoming, safe, &req, false) {
+		return
+	}
 
 	safe["id"] = uuid.New().String()
 	safe["created_by"] = userID
+	row := projectRow(models.Project{
+		ID:                safe["id"].(string),
+		Name:              req.Name,
+		Description:       req.Description,
+		CreatedBy:         userID,
+		AssignedEmployees: req.AssignedEmployees,
+	})
+	if !recordAllowed(r, tablePerm, rbac.ActionCreate, row) {
+		apierror.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if !actionAllowed(w, r, h.Repo.DB, rbac.TableProjects, rbac.ActionCreate, row, row) {
+		return
+	}
 
-	err := h.Repo.CreateProjectDynamic(safe)
+	err := h.Repo.ForOrg(orgFromRequest(r)).CreateProjectDynamic(safe)
 	if err != nil {
-		http.Error(w, "failed to create project", http.StatusInternalServerError)
+		repoError(w, err, "failed to create project")
 		return
 	}
 
-	json.NewEncoder(w).Encode(safe)
+	safe["version"] = 1
+	setETag(w, 1)
+	json.NewEncoder(w).Encode(withIgnored(safe, ignored))
 }
 
 func (h *ProjectHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
@@ -57,47 +85,59 @@ func (h *ProjectHandler) GetProjects(w http.ResponseWriter, r *http.Request) {
 
 	tablePerm := r.Context().Value(middleware.TablePermKey).(models.ResourcePermission)
 
-	projects, err := h.Repo.GetProjects()
+	q, err := listQuery(r, repositories.ProjectListing, tablePerm)
 	if err != nil {
-		http.Error(w, "failed to fetch projects", http.StatusInternalServerError)
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	var response []map[string]interface{}
+	filter, err := rowFilter(r, tablePerm, rbac.ActionView, repositories.ProjectSQL)
+	if err != nil {
+		apierror.Internal(w, "invalid row policy", err)
+		return
+	}
 
-	role, _ := r.Context().Value(middleware.RoleKey).(string)
-	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	projects, next, err := h.Repo.ForOrg(orgFromRequest(r)).ListProjects(filter, q)
+	if err != nil {
+		repoError(w, err, "failed to fetch projects")
+		return
+	}
+	writeNextPage(w, r, next)
+
+	var response []map[string]interface{}
 
 	for _, p := range projects {
-		if role != rbac.RoleAdmin {
-			allowed := false
-			for _, uid := range p.AssignedEmployees {
-				if uid == userID {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				continue
-			}
-		}
-		row := map[string]interface{}{
-			"id":                 p.ID,
-			"name":               p.Name,
-			"description":        p.Description,
-			"created_by":         p.CreatedBy,
-			"assigned_employees": p.AssignedEmployees,
-		}
+		response = append(response, projectResponse(p, tablePerm))
+	}
+
+	json.NewEncoder(w).Encode(response)
+}
 
-		filtered := utils.FilterFields(row, tablePerm.Fields)
+// GetProject returns a single project with the caller's field rules applied.
+func (h *ProjectHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 
-		if len(p.AssignedEmployees) > 0 && tablePerm.View {
-			filtered["assigned_employees"] = p.AssignedEmployees
-		}
-		response = append(response, filtered)
+	w.Header().Set("Content-Type", "application/json")
+
+	tablePerm := r.Context().Value(middleware.TablePermKey).(models.ResourcePermission)
+
+	p, err := h.Repo.ForOrg(orgFromRequest(r)).GetProjectByID(r.PathValue("id"))
+	if err != nil {
+		apierror.Internal(w, "failed to fetch project", err)
+		return
+	}
+	if p == nil {
+		apierror.Error(w, "project not found", http.StatusNotFound)
+		return
 	}
 
-	json.NewEncoder(w).Encode(response)
+	row := projectRow(*p)
+	if !recordAllowed(r, tablePerm, rbac.ActionView, row) {
+		apierror.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	setETag(w, p.Version)
+	json.NewEncoder(w).Encode(projectResponse(*p, tablePerm))
 }
 
 func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
@@ -108,55 +148,141 @@ func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 
 	var incoming map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&incoming); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+		apierror.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
 
-	id, ok := incoming["id"].(string)
-	if !ok || id == "" {
-		http.Error(w, "project id required", http.StatusBadRequest)
+	id := r.PathValue("id")
+	if id == "" {
+		id, _ = incoming["id"].(string)
+	}
+	if id == "" {
+		apierror.Error(w, "project id required", http.StatusBadRequest)
 		return
 	}
 
 	delete(incoming, "id")
 
-	safeData := utils.FilterEditableFields(incoming, tablePerm.Fields)
+	repo := h.Repo.ForOrg(orgFromRequest(r))
+	p, ok := projectAllowed(w, r, repo, tablePerm, rbac.ActionEdit, id)
+	if !ok {
+		return
+	}
+	if !ifMatch(w, r, p.Version, viewableProject(r, tablePerm, *p)) {
+		return
+	}
+
+	safeData, ignored, ok := editableFields(w, r, incoming, tablePerm)
+	if !ok {
+		return
+	}
+	if !bindRequest(w, r, h.Repo.DB, incoming, safeData, &ProjectRequest{}, true) {
+		return
+	}
 
 	if len(safeData) == 0 {
-		http.Error(w, "no editable fields", http.StatusForbidden)
+		apierror.Write(w, http.StatusForbidden, apierror.CodeForbiddenFields, "no editable fields", ForbiddenFieldsDetails{Fields: ignored})
+		return
+	}
+
+	if !actionAllowed(w, r, h.Repo.DB, rbac.TableProjects, rbac.ActionEdit, projectRow(*p), safeData) {
 		return
 	}
 
 	safeData["id"] = id
 
-	err := h.Repo.UpdateProjectDynamic(safeData)
+	err := repo.UpdateProjectDynamic(safeData, p.Version)
+	if errors.Is(err, repositories.ErrStale) {
+		current, err := repo.GetProjectByID(id)
+		if err != nil || current == nil {
+			repoError(w, err, "update failed")
+			return
+		}
+		writeStale(w, current.Version, viewableProject(r, tablePerm, *current))
+		return
+	}
 	if err != nil {
-		http.Error(w, "update failed", http.StatusInternalServerError)
+		repoError(w, err, "update failed")
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+	setETag(w, p.Version+1)
+	json.NewEncoder(w).Encode(withIgnored(map[string]interface{}{"status": "updated"}, ignored))
 	return
 
 }
 
+// DeleteProject moves a project to the trash. A project that still has
+// tasks outside the trash gets 409.
 func (h *ProjectHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	id := r.URL.Query().Get("id")
+	id := pathOrQuery(r, "id")
 	if id == "" {
-		http.Error(w, "project id required", http.StatusBadRequest)
+		apierror.Error(w, "project id required", http.StatusBadRequest)
 		return
 	}
 
-	err := h.Repo.DeleteProject(id)
-	if err != nil {
-		http.Error(w, "delete failed", http.StatusInternalServerError)
+	tablePerm := r.Context().Value(middleware.TablePermKey).(models.ResourcePermission)
+	repo := h.Repo.ForOrg(orgFromRequest(r))
+	if _, ok := projectAllowed(w, r, repo, tablePerm, rbac.ActionDelete, id); !ok {
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	if err := repo.DeleteProject(id, userID); err != nil {
+		repoError(w, err, "delete failed")
 		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]string{
-		"message": "project deleted",
+		"message": "project moved to the trash",
 	})
 }
+
+// projectAllowed loads the project and applies the row-level condition for
+// action, writing the error response when access is refused.
+func projectAllowed(w http.ResponseWriter, r *http.Request, repo *repositories.ProjectRepository, tablePerm models.ResourcePermission, action, id string) (*models.Project, bool) {
+	p, err := repo.GetProjectByID(id)
+	if err != nil {
+		apierror.Internal(w, "failed to fetch project", err)
+		return nil, false
+	}
+	if p == nil {
+		apierror.Error(w, "project not found", http.StatusNotFound)
+		return nil, false
+	}
+	if !recordAllowed(r, tablePerm, action, projectRow(*p)) {
+		apierror.Error(w, "forbidden", http.StatusForbidden)
+		return nil, false
+	}
+	return p, true
+}
+
+// projectResponse is p as the caller may view it. assigned_employees follows
+// the field rules like any other field.
+func projectResponse(p models.Project, tablePerm models.ResourcePermission) map[string]interface{} {
+	return withVersion(utils.FilterFields(projectRow(p), tablePerm.Fields), p.Version)
+}
+
+// viewableProject is projectResponse, or nil when the row-level rules do not
+// let the caller view p.
+func viewableProject(r *http.Request, tablePerm models.ResourcePermission, p models.Project) map[string]interface{} {
+	if !recordAllowed(r, tablePerm, rbac.ActionView, projectRow(p)) {
+		return nil
+	}
+	return projectResponse(p, tablePerm)
+}
+
+// projectRow is the full field map of a project, used for row-level
+// conditions and field filtering.
+func projectRow(p models.Project) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                 p.ID,
+		"name":               p.Name,
+		"description":        p.Description,
+		"created_by":         p.CreatedBy,
+		"assigned_employees": p.AssignedEmployees,
+	}
+}
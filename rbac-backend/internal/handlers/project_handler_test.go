@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"rbac-backend/internal/apierror"
+	"rbac-backend/internal/models"
+	repositories "rbac-backend/internal/repository"
+	"rbac-backend/internal/utils"
+)
+
+func TestProjectResponsesApplyAssignedEmployeesRules(t *testing.T) {
+	database, adminID := setupHandlerDB(t)
+	h := NewProjectHandler(repositories.NewProjectRepository(database))
+	if err := h.Repo.ForOrg(models.DefaultOrgID).CreateProjectDynamic(map[string]interface{}{
+		"id": "p1", "name": "Apollo", "created_by": adminID, "assigned_employees": []string{adminID},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		rule map[string]models.FieldPermission
+		want interface{} // nil when the field must be left out
+	}{
+		{"masked", map[string]models.FieldPermission{"assigned_employees": {View: true, Redact: "mask"}}, []interface{}{utils.Redact(adminID, "mask")}},
+		{"denied", map[string]models.FieldPermission{"assigned_employees": {View: true, Deny: []string{"view"}}}, nil},
+		{"nested", map[string]models.FieldPermission{"assigned_employees[*]": {View: true, Deny: []string{"view"}}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			perm := models.ResourcePermission{View: true, Fields: map[string]models.FieldPermission{
+				"id":   {View: true},
+				"name": {View: true},
+			}}
+			for k, v := range tt.rule {
+				perm.Fields[k] = v
+			}
+			c := caller{userID: adminID, role: "MANAGER", perm: perm}
+
+			status, list := serve(t, h.GetProjects, c.request("GET", "/projects", nil))
+			if status != http.StatusOK {
+				t.Fatalf("list: status %d: %v", status, list)
+			}
+			r := c.request("GET", "/projects/p1", nil)
+			r.SetPathValue("id", "p1")
+			status, got := serve(t, h.GetProject, r)
+			if status != http.StatusOK {
+				t.Fatalf("get: status %d: %v", status, got)
+			}
+
+			for name, p := range map[string]interface{}{"list": list.([]interface{})[0], "get": got} {
+				value, present := p.(map[string]interface{})["assigned_employees"]
+				switch {
+				case tt.want == nil && present:
+					t.Errorf("%s: assigned_employees = %v, want it left out", name, value)
+				case tt.want != nil && fmt.Sprint(value) != fmt.Sprint(tt.want):
+					t.Errorf("%s: assigned_employees = %v, want %v", name, value, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateProjectReplacesAssignedEmployees(t *testing.T) {
+	database, adminID := setupHandlerDB(t)
+	h := NewProjectHandler(repositories.NewProjectRepository(database))
+	if err := h.Repo.ForOrg(models.DefaultOrgID).CreateProjectDynamic(map[string]interface{}{
+		"id": "p1", "name": "Apollo", "created_by": adminID,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	admin := caller{userID: adminID, role: "ADMIN", perm: models.ResourcePermission{View: true, Edit: true}}
+
+	patch := func(body map[string]interface{}) (int, interface{}) {
+		t.Helper()
+		r := admin.request("PATCH", "/projects/p1", body)
+		r.SetPathValue("id", "p1")
+		r.Header.Set("If-Match", "*")
+		return serve(t, h.UpdateProject, r)
+	}
+	assigned := func() []string {
+		t.Helper()
+		p, err := h.Repo.ForOrg(models.DefaultOrgID).GetProjectByID("p1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p.AssignedEmployees
+	}
+
+	if status, got := patch(map[string]interface{}{"assigned_employees": []string{adminID}}); status != http.StatusOK {
+		t.Fatalf("assign: status %d: %v", status, got)
+	}
+	if got := assigned(); fmt.Sprint(got) != fmt.Sprint([]string{adminID}) {
+		t.Errorf("after assigning: %v, want [%s]", got, adminID)
+	}
+	if status, got := patch(map[string]interface{}{"name": "Apollo 2", "assigned_employees": []string{}}); status != http.StatusOK {
+		t.Fatalf("unassign: status %d: %v", status, got)
+	}
+	if got := assigned(); len(got) != 0 {
+		t.Errorf("after unassigning: %v, want none", got)
+	}
+	if status, _ := patch(map[string]interface{}{"assigned_employees": []string{"4a7c1c6e-0000-4000-8000-000000000000"}}); status != http.StatusUnprocessableEntity {
+		t.Errorf("assigning a non-member: status %d, want 422", status)
+	}
+}
+
+func TestUpdateProjectReportsFieldsAndConflicts(t *testing.T) {
+	database, adminID := setupHandlerDB(t)
+	h := NewProjectHandler(repositories.NewProjectRepository(database))
+	if err := h.Repo.ForOrg(models.DefaultOrgID).CreateProjectDynamic(map[string]interface{}{
+		"id": "p1", "name": "Apollo", "created_by": adminID,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	editor := caller{userID: adminID, role: "EDITOR", perm: models.ResourcePermission{View: true, Edit: true, Fields: map[string]models.FieldPermission{
+		"name":       {View: true, Edit: true},
+		"created_by": {View: true},
+	}}}
+	patch := func(ifMatch, mode string, body map[string]interface{}) (int, interface{}) {
+		t.Helper()
+		r := editor.request("PATCH", "/projects/p1", body)
+		r.SetPathValue("id", "p1")
+		r.Header.Set("If-Match", ifMatch)
+		r.Header.Set(FieldModeHeader, mode)
+		return serve(t, h.UpdateProject, r)
+	}
+
+	change := map[string]interface{}{"name": "Apollo 2", "created_by": "someone"}
+	if status, body := patch("*", "strict", change); status != http.StatusForbidden || errorCode(body) != apierror.CodeForbiddenFields {
+		t.Errorf("strict: %d %v, want 403 forbidden_fields", status, body)
+	}
+	status, body := patch(`"1"`, "lenient", change)
+	if status != http.StatusOK || fmt.Sprint(body.(map[string]interface{})["ignored_fields"]) != "[created_by]" {
+		t.Errorf("lenient: %d %v, want 200 with created_by ignored", status, body)
+	}
+
+	status, body = patch(`"1"`, "lenient", map[string]interface{}{"name": "Apollo 3"})
+	if status != http.StatusPreconditionFailed {
+		t.Fatalf("stale If-Match: status %d, want 412", status)
+	}
+	current := body.(map[string]interface{})["error"].(map[string]interface{})["details"].(map[string]interface{})["current"].(map[string]interface{})
+	if current["name"] != "Apollo 2" || current["version"] != float64(2) {
+		t.Errorf("412 current = %v, want Apollo 2 at version 2", current)
+	}
+}
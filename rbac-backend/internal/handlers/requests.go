@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"rbac-backend/internal/apierror"
+	"rbac-backend/internal/config"
+	"rbac-backend/internal/models"
+	repositories "rbac-backend/internal/repository"
+	"rbac-backend/internal/utils"
+	"rbac-backend/internal/validate"
+)
+
+// FieldModeHeader lets a request choose config.FieldMode for itself.
+const FieldModeHeader = "X-Field-Mode"
+
+// ForbiddenFieldsDetails are the details of a forbidden_fields error.
+type ForbiddenFieldsDetails struct {
+	Fields []string `json:"fields"`
+}
+
+// editableFields filters incoming to the fields the caller may edit. In
+// strict mode a body with other fields is refused with 403 and ok is false;
+// in lenient mode their paths are returned as ignored, for the response to
+// report.
+func editableFields(w http.ResponseWriter, r *http.Request, incoming map[string]interface{}, perm models.ResourcePermission) (safe map[string]interface{}, ignored []string, ok bool) {
+	mode := r.Header.Get(FieldModeHeader)
+	if mode == "" && config.AppConfig != nil {
+		mode = config.AppConfig.FieldMode
+	}
+	if mode != "" && mode != "strict" && mode != "lenient" {
+		apierror.Error(w, FieldModeHeader+" must be strict or lenient", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	safe, ignored = utils.EditableFields(incoming, perm.Fields)
+	if mode == "strict" && len(ignored) > 0 {
+		apierror.Write(w, http.StatusForbidden, apierror.CodeForbiddenFields,
+			"fields not editable: "+strings.Join(ignored, ", "), ForbiddenFieldsDetails{Fields: ignored})
+		return nil, nil, false
+	}
+	return safe, ignored, true
+}
+
+// withIgnored adds the ignored fields, if any, to a response body.
+func withIgnored(body map[string]interface{}, ignored []string) map[string]interface{} {
+	if len(ignored) > 0 {
+		body["ignored_fields"] = ignored
+	}
+	return body
+}
+
+// ProjectRequest is the body of a project create or update.
+type ProjectRequest struct {
+	ID                string   `json:"id,omitempty"`
+	Name              string   `json:"name,omitempty" validate:"required,max=200"`
+	Description       string   `json:"description,omitempty" validate:"max=5000"`
+	AssignedEmployees []string `json:"assigned_employees,omitempty" validate:"max=100,uuid,exists=member"`
+}
+
+// TaskRequest is the body of a task create or update. Status takes the values
+// the tasks table allows. Assignee sets a single assignee; Assignees wins when
+// both are given.
+type TaskRequest struct {
+	ID          string   `json:"id,omitempty"`
+	ProjectID   string   `json:"project_id,omitempty" validate:"required,uuid,exists=project"`
+	Title       string   `json:"title,omitempty" validate:"required,max=200"`
+	Description string   `json:"description,omitempty" validate:"max=5000"`
+	Status      string   `json:"status,omitempty" validate:"enum=TODO|IN_PROGRESS|REVIEW|DONE|ARCHIVED"`
+	Assignee    string   `json:"assignee,omitempty" validate:"uuid,exists=member"`
+	Assignees   []string `json:"assignees,omitempty" validate:"max=50,uuid,exists=member"`
+}
+
+// AssignRequest is the body of a task assignment.
+type AssignRequest struct {
+	ID        string   `json:"id,omitempty"`
+	Assignee  string   `json:"assignee,omitempty" validate:"uuid,exists=member"`
+	Assignees []string `json:"assignees,omitempty" validate:"max=50,uuid,exists=member"`
+}
+
+// bindRequest decodes data, the body left after field filtering, into req and
+// validates it: every field when creating, only the fields in data when
+// updating. Fields in incoming that filtering removed are not the caller's
+// mistake to fix here, so they are not reported as invalid. It answers 422
+// with every field error and returns false when the request is invalid.
+func bindRequest(w http.ResponseWriter, r *http.Request, db *sql.DB, incoming, data map[string]interface{}, req interface{}, update bool) bool {
+	errs := validate.Decode(data, req)
+
+	v := validate.Validator{Exists: repositories.Exists(db, orgFromRequest(r))}
+	var ruleErrs validate.Errors
+	var err error
+	if update {
+		ruleErrs, err = v.Fields(req, keys(data))
+	} else {
+		ruleErrs, err = v.Struct(req)
+	}
+	if err != nil {
+		apierror.Internal(w, "validation failed", err)
+		return false
+	}
+
+	// A value of the wrong type is only reported once.
+	for _, fe := range errs {
+		ruleErrs = ruleErrs.Without(fe.Field)
+	}
+	errs = append(errs, ruleErrs...).Without(removedFields(incoming, data)...)
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return false
+	}
+	return true
+}
+
+// removedFields are the keys of incoming that are not in filtered.
+func removedFields(incoming, filtered map[string]interface{}) []string {
+	var out []string
+	for k := range incoming {
+		if _, ok := filtered[k]; !ok {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func keys(m map[string]interface{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
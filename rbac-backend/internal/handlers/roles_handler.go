@@ -3,15 +3,22 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
-	"strings"
+	"strconv"
 
+	"rbac-backend/internal/apierror"
 	"rbac-backend/internal/db"
+	"rbac-backend/internal/middleware"
 	"rbac-backend/internal/models"
 	"rbac-backend/internal/rbac"
 )
 
 // RolesHandler handles admin-only role config (get/update permissions from DB).
+// Permissions are read and written in the caller's active organization; the
+// role catalogue itself is shared and only changed from the default one.
 type RolesHandler struct {
 	DB *sql.DB
 }
@@ -20,89 +27,329 @@ func NewRolesHandler(database *sql.DB) *RolesHandler {
 	return &RolesHandler{DB: database}
 }
 
-var validConfigRoles = map[string]bool{
-	rbac.RoleManager: true,
-	rbac.RoleEditor:  true,
-	rbac.RoleViewer:  true,
+// knownRole reports whether role is in the catalogue, writing 400 when it is not.
+func (h *RolesHandler) knownRole(w http.ResponseWriter, role string) bool {
+	def, err := db.GetRole(h.DB, role)
+	if err != nil {
+		apierror.Internal(w, "failed to fetch role", err)
+		return false
+	}
+	if def == nil {
+		apierror.Error(w, "invalid role", http.StatusBadRequest)
+		return false
+	}
+	return true
 }
 
-func roleFromPath(path string) string {
-	const prefix = "/admin/roles/"
-	if !strings.HasPrefix(path, prefix) {
-		return ""
+// GetRoles lists role names under "roles" and the full catalogue entries,
+// including the superuser flag, under "definitions".
+func (h *RolesHandler) GetRoles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	defs, err := db.ListRoleDefinitions(h.DB)
+	if err != nil {
+		apierror.Internal(w, "failed to list roles", err)
+		return
 	}
-	return strings.TrimSpace(strings.TrimPrefix(path, prefix))
+	roles := make([]string, 0, len(defs))
+	for _, d := range defs {
+		roles = append(roles, d.Name)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"roles": roles, "definitions": defs})
 }
 
-func (h *RolesHandler) GetRoles(w http.ResponseWriter, r *http.Request) {
+// CreateRole adds a role to the catalogue with an empty permission config in
+// the default organization (recorded as version 1).
+func (h *RolesHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req models.Role
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if !rbac.ValidRoleName(req.Name) {
+		apierror.Error(w, "invalid role name", http.StatusBadRequest)
+		return
+	}
+	existing, err := db.GetRole(h.DB, req.Name)
+	if err != nil {
+		apierror.Internal(w, "failed to fetch role", err)
+		return
+	}
+	if existing != nil {
+		apierror.Error(w, "role already exists", http.StatusConflict)
+		return
+	}
+
+	author, _ := r.Context().Value(middleware.UserIDKey).(string)
+	if err := db.SaveRole(h.DB, req); err != nil {
+		apierror.Internal(w, "create failed", err)
+		return
+	}
+	if _, err := db.UpdateRolePermissions(h.DB, models.DefaultOrgID, req.Name, models.Permissions{}, author, "role created"); err != nil {
+		apierror.Internal(w, "create failed", err)
+		return
+	}
+	h.auditRole(author, "role.create", req)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(req)
+}
+
+// UpdateRoleSettings changes a role's superuser flag and description. A
+// change that would leave no active user with a superuser role is refused.
+func (h *RolesHandler) UpdateRoleSettings(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	roles, err := db.ListRoles(h.DB)
+	role := r.PathValue("role")
+	current, err := db.GetRole(h.DB, role)
 	if err != nil {
-		http.Error(w, "failed to list roles", http.StatusInternalServerError)
+		apierror.Internal(w, "failed to fetch role", err)
 		return
 	}
-	json.NewEncoder(w).Encode(map[string]interface{}{"roles": roles})
+	if current == nil {
+		apierror.Error(w, "invalid role", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Superuser   *bool   `json:"superuser"`
+		Description *string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	updated := *current
+	if req.Superuser != nil {
+		updated.Superuser = *req.Superuser
+	}
+	if req.Description != nil {
+		updated.Description = *req.Description
+	}
+
+	if current.Superuser && !updated.Superuser {
+		remaining, err := db.CountActiveSuperusers(h.DB, role)
+		if err != nil {
+			apierror.Internal(w, "failed to check superusers", err)
+			return
+		}
+		if remaining == 0 {
+			apierror.Error(w, "change would leave no active superuser", http.StatusConflict)
+			return
+		}
+	}
+
+	if err := db.SaveRole(h.DB, updated); err != nil {
+		apierror.Internal(w, "update failed", err)
+		return
+	}
+	author, _ := r.Context().Value(middleware.UserIDKey).(string)
+	h.auditRole(author, "role.settings", updated)
+
+	json.NewEncoder(w).Encode(updated)
+}
+
+// auditRole records a catalogue change in the default organization's audit
+// log, the only place catalogue changes can be made from.
+func (h *RolesHandler) auditRole(actorID, action string, role models.Role) {
+	details := fmt.Sprintf("superuser=%t description=%q", role.Superuser, role.Description)
+	if err := db.RecordAudit(h.DB, models.DefaultOrgID, actorID, action, role.Name, details); err != nil {
+		log.Printf("audit %s %s: %v", action, role.Name, err)
+	}
 }
 
 func (h *RolesHandler) GetRole(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	role := roleFromPath(r.URL.Path)
+	role := r.PathValue("role")
 	if role == "" {
-		http.Error(w, "role required", http.StatusBadRequest)
+		apierror.Error(w, "role required", http.StatusBadRequest)
 		return
 	}
-	if !validConfigRoles[role] {
-		http.Error(w, "invalid role", http.StatusBadRequest)
+	if !h.knownRole(w, role) {
 		return
 	}
-	perms, err := db.GetPermissionsByRole(h.DB, role)
-	if err != nil {
-		http.Error(w, "role not found", http.StatusNotFound)
+	perms, err := db.GetPermissionsByRole(h.DB, orgFromRequest(r), role)
+	if err == sql.ErrNoRows {
+		perms = models.Permissions{}
+	} else if err != nil {
+		apierror.Internal(w, "failed to fetch role", err)
 		return
 	}
 	json.NewEncoder(w).Encode(perms)
 }
 
+// UpdateRole replaces a role's permissions after validating them against the
+// table/field registry. With ?dry_run=true it only returns the diagnostics;
+// an invalid config is rejected with 422 and the same report. Each saved change
+// becomes a new version; ?comment= is stored with it.
 func (h *RolesHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPut && r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	w.Header().Set("Content-Type", "application/json")
+	role := r.PathValue("role")
+	if role == "" {
+		apierror.Error(w, "role required", http.StatusBadRequest)
 		return
 	}
+	if !h.knownRole(w, role) {
+		return
+	}
+	raw, err := io.ReadAll(r.Body)
+	if err != nil {
+		apierror.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	perms, report, err := rbac.ValidatePermissionsJSON(raw)
+	if err != nil {
+		apierror.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if r.URL.Query().Get("dry_run") == "true" {
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+	if !report.Valid {
+		writeInvalidPermissions(w, report)
+		return
+	}
+	author, _ := r.Context().Value(middleware.UserIDKey).(string)
+	version, err := db.UpdateRolePermissions(h.DB, orgFromRequest(r), role, perms, author, r.URL.Query().Get("comment"))
+	if err != nil {
+		apierror.Internal(w, "update failed", err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "updated", "version": version, "warnings": report.Warnings})
+}
+
+func (h *RolesHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	role := roleFromPath(r.URL.Path)
-	if role == "" {
-		http.Error(w, "role required", http.StatusBadRequest)
+	role := r.PathValue("role")
+	if !h.knownRole(w, role) {
 		return
 	}
-	if !validConfigRoles[role] {
-		http.Error(w, "invalid role", http.StatusBadRequest)
+	versions, err := db.ListRolePermissionVersions(h.DB, orgFromRequest(r), role)
+	if err != nil {
+		apierror.Internal(w, "failed to list versions", err)
 		return
 	}
-	var perms models.Permissions
-	if err := json.NewDecoder(r.Body).Decode(&perms); err != nil {
-		http.Error(w, "invalid body", http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{"versions": versions})
+}
+
+func (h *RolesHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	role := r.PathValue("role")
+	if !h.knownRole(w, role) {
+		return
+	}
+	n, err := strconv.Atoi(r.PathValue("version"))
+	if err != nil {
+		apierror.Error(w, "invalid version", http.StatusBadRequest)
+		return
+	}
+	v, err := db.GetRolePermissionVersion(h.DB, orgFromRequest(r), role, n)
+	if err != nil {
+		apierror.Internal(w, "failed to fetch version", err)
 		return
 	}
-	if err := db.UpdateRolePermissions(h.DB, role, perms); err != nil {
-		http.Error(w, "update failed", http.StatusInternalServerError)
+	if v == nil {
+		apierror.Error(w, "version not found", http.StatusNotFound)
 		return
 	}
-	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+	json.NewEncoder(w).Encode(v)
 }
 
-// ServeRoleDetail handles GET (get one) and PUT/POST (update) for /admin/roles/{role}.
-func (h *RolesHandler) ServeRoleDetail(w http.ResponseWriter, r *http.Request) {
-	role := roleFromPath(r.URL.Path)
-	if role == "" {
-		http.Error(w, "role required", http.StatusBadRequest)
+// DiffVersions reports the rule changes from version ?from= to version ?to=,
+// or to the live config when to is omitted.
+func (h *RolesHandler) DiffVersions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	role := r.PathValue("role")
+	if !h.knownRole(w, role) {
+		return
+	}
+
+	orgID := orgFromRequest(r)
+	from, ok := h.loadVersion(w, orgID, role, r.URL.Query().Get("from"))
+	if !ok {
 		return
 	}
-	switch r.Method {
-	case http.MethodGet:
-		h.GetRole(w, r)
-	case http.MethodPut, http.MethodPost:
-		h.UpdateRole(w, r)
-	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	var to models.Permissions
+	var toLabel interface{} = "current"
+	if r.URL.Query().Get("to") == "" {
+		current, err := db.GetPermissionsByRole(h.DB, orgID, role)
+		if err != nil {
+			apierror.Error(w, "role not found", http.StatusNotFound)
+			return
+		}
+		to = current
+	} else {
+		v, ok := h.loadVersion(w, orgID, role, r.URL.Query().Get("to"))
+		if !ok {
+			return
+		}
+		to = v.Permissions
+		toLabel = v.Version
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"role":    role,
+		"from":    from.Version,
+		"to":      toLabel,
+		"changes": rbac.DiffPermissions(from.Permissions, to),
+	})
+}
+
+// Rollback restores the permissions of an earlier version as a new version.
+func (h *RolesHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	role := r.PathValue("role")
+	if !h.knownRole(w, role) {
+		return
+	}
+
+	var req struct {
+		Version int    `json:"version"`
+		Comment string `json:"comment"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Version <= 0 {
+		apierror.Error(w, "version required", http.StatusBadRequest)
+		return
+	}
+	orgID := orgFromRequest(r)
+	target, ok := h.loadVersion(w, orgID, role, strconv.Itoa(req.Version))
+	if !ok {
+		return
+	}
+
+	report := rbac.ValidatePermissions(target.Permissions)
+	if !report.Valid {
+		writeInvalidPermissions(w, report)
+		return
+	}
+
+	comment := fmt.Sprintf("rollback to version %d", target.Version)
+	if req.Comment != "" {
+		comment += ": " + req.Comment
+	}
+	author, _ := r.Context().Value(middleware.UserIDKey).(string)
+	version, err := db.UpdateRolePermissions(h.DB, orgID, role, target.Permissions, author, comment)
+	if err != nil {
+		apierror.Internal(w, "rollback failed", err)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "rolled back", "version": version})
+}
+
+func (h *RolesHandler) loadVersion(w http.ResponseWriter, orgID, role, param string) (*models.RolePermissionVersion, bool) {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		apierror.Error(w, "invalid version", http.StatusBadRequest)
+		return nil, false
+	}
+	v, err := db.GetRolePermissionVersion(h.DB, orgID, role, n)
+	if err != nil {
+		apierror.Internal(w, "failed to fetch version", err)
+		return nil, false
+	}
+	if v == nil {
+		apierror.Error(w, "version not found", http.StatusNotFound)
+		return nil, false
 	}
+	return v, true
 }
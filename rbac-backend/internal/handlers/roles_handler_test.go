@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestUpdateRoleRejectsConditionsListsCannotUse(t *testing.T) {
+	database, adminID := setupHandlerDB(t)
+	h := NewRolesHandler(database)
+	admin := caller{userID: adminID, role: "ADMIN"}
+
+	put := func(target, cond string) (int, map[string]interface{}) {
+		t.Helper()
+		r := admin.request("PUT", target, map[string]interface{}{
+			"tasks": map[string]interface{}{"view": map[string]interface{}{"if": cond}},
+		})
+		r.SetPathValue("role", "VIEWER")
+		status, got := serve(t, h.UpdateRole, r)
+		m, _ := got.(map[string]interface{})
+		return status, m
+	}
+
+	for _, cond := range []string{"record.assignees == user.id", "user.id in record.asignees"} {
+		if status, got := put("/admin/roles/VIEWER", cond); status != http.StatusUnprocessableEntity {
+			t.Errorf("%s: status %d, want 422: %v", cond, status, got)
+		}
+		if status, got := put("/admin/roles/VIEWER?dry_run=true", cond); status != http.StatusOK || got["valid"] != false {
+			t.Errorf("%s: dry run: %d %v, want an invalid report", cond, status, got)
+		}
+	}
+	if status, got := put("/admin/roles/VIEWER", "user.id in record.assignees"); status != http.StatusOK {
+		t.Errorf("valid condition: status %d: %v", status, got)
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"rbac-backend/internal/apierror"
+	"rbac-backend/internal/middleware"
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+	repositories "rbac-backend/internal/repository"
+	"rbac-backend/internal/utils"
+)
+
+// Page sizes of GET /search.
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchHandler serves full-text search over projects and tasks.
+type SearchHandler struct {
+	DB *sql.DB
+	// Searcher defaults to repositories.NewSearcher(DB), chosen on the first
+	// search.
+	Searcher repositories.Searcher
+	once     sync.Once
+}
+
+func NewSearchHandler(database *sql.DB) *SearchHandler {
+	return &SearchHandler{DB: database}
+}
+
+// SearchResult is one record found by a search: the record with the caller's
+// field rules applied and, for each field that matched, an HTML snippet with
+// the matches in <mark>.
+type SearchResult struct {
+	Kind     string                 `json:"kind"`
+	ID       string                 `json:"id"`
+	Record   map[string]interface{} `json:"record"`
+	Snippets map[string]string      `json:"snippets"`
+}
+
+// Search finds the projects and tasks matching ?q=. Each kind is searched
+// only if the caller may view its table, only in the fields they can view
+// unredacted (see visible) and only among the records the row-level rules let
+// them view. ?kind= limits the search to one kind.
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	params := r.URL.Query()
+	q := repositories.SearchQuery{Text: strings.TrimSpace(params.Get("q")), Limit: defaultSearchLimit, Scopes: map[string]repositories.SearchScope{}}
+	if q.Text == "" {
+		apierror.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	if s := params.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			apierror.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit), http.StatusBadRequest)
+			return
+		}
+		q.Limit = n
+	}
+	kinds := make([]string, 0, len(repositories.SearchKinds))
+	for kind := range repositories.SearchKinds {
+		kinds = append(kinds, kind)
+	}
+	if kind := params.Get("kind"); kind != "" {
+		if _, ok := repositories.SearchKinds[kind]; !ok {
+			apierror.Error(w, "unknown kind "+strconv.Quote(kind), http.StatusBadRequest)
+			return
+		}
+		kinds = []string{kind}
+	}
+
+	role, _ := r.Context().Value(middleware.RoleKey).(string)
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	orgID := orgFromRequest(r)
+	perms, _, err := middleware.EffectivePermissions(h.DB, orgID, role, userID)
+	if err != nil {
+		apierror.Internal(w, "permission lookup failed", err)
+		return
+	}
+	for _, kind := range kinds {
+		k := repositories.SearchKinds[kind]
+		perm, ok := perms[k.Table]
+		if !ok || !rbac.Evaluate(perm, rbac.ActionView, "").Allowed() {
+			continue
+		}
+		filter, err := rowFilter(r, perm, rbac.ActionView, k.SQL)
+		if err != nil {
+			apierror.Internal(w, "invalid row policy", err)
+			return
+		}
+		scope := repositories.SearchScope{Filter: filter}
+		for field := range k.Fields {
+			if visible(perm, field) {
+				scope.Fields = append(scope.Fields, field)
+			}
+		}
+		sort.Strings(scope.Fields)
+		q.Scopes[kind] = scope
+	}
+
+	h.once.Do(func() {
+		if h.Searcher == nil {
+			h.Searcher = repositories.NewSearcher(h.DB)
+		}
+	})
+	hits, err := h.Searcher.Search(orgID, q)
+	if err != nil {
+		apierror.Internal(w, "search failed", err)
+		return
+	}
+
+	records, err := h.records(orgID, hits, perms)
+	if err != nil {
+		apierror.Internal(w, "failed to load search results", err)
+		return
+	}
+	results := []SearchResult{}
+	for _, hit := range hits {
+		record, ok := records[hit.Kind+"/"+hit.ID]
+		if !ok {
+			continue // deleted since it was found
+		}
+		snippets := map[string]string{}
+		for field, s := range hit.Snippets {
+			snippets[field] = repositories.Markup(s)
+		}
+		results = append(results, SearchResult{Kind: hit.Kind, ID: hit.ID, Record: record, Snippets: snippets})
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+}
+
+// records loads the records of hits, keyed by kind and id, as the caller may
+// view them.
+func (h *SearchHandler) records(orgID string, hits []repositories.SearchHit, perms models.Permissions) (map[string]map[string]interface{}, error) {
+	ids := map[string][]string{}
+	for _, hit := range hits {
+		ids[hit.Kind] = append(ids[hit.Kind], hit.ID)
+	}
+	out := map[string]map[string]interface{}{}
+	projects, err := repositories.NewProjectRepository(h.DB).ForOrg(orgID).GetProjectsByIDs(ids["project"])
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range projects {
+		out["project/"+p.ID] = withVersion(utils.FilterFields(projectRow(p), perms[rbac.TableProjects].Fields), p.Version)
+	}
+	tasks, err := repositories.NewTaskRepository(h.DB).ForOrg(orgID).GetTasksByIDs(ids["task"])
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tasks {
+		out["task/"+t.ID] = taskResponse(t, perms[rbac.TableTasks])
+	}
+	return out, nil
+}
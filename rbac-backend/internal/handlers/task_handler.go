@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 
+	"rbac-backend/internal/apierror"
 	"rbac-backend/internal/middleware"
 	"rbac-backend/internal/models"
 	"rbac-backend/internal/rbac"
@@ -27,7 +30,7 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 
 	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
 	if !ok {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		apierror.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -35,117 +38,103 @@ func (h *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 
 	var incoming map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&incoming); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+		apierror.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
 
-	pid, ok := incoming["project_id"].(string)
-	if !ok || pid == "" {
-		http.Error(w, "project_id required", http.StatusBadRequest)
-		return
+	// project_id names the parent rather than setting a field, so the field
+	// rules do not apply to it.
+	pid, hasPID := incoming["project_id"]
+	if p := r.PathValue("project_id"); p != "" {
+		pid, hasPID = p, true
 	}
-	title, ok := incoming["title"].(string)
-	if !ok || title == "" {
-		http.Error(w, "title required", http.StatusBadRequest)
+	delete(incoming, "project_id")
+	safe, ignored, ok := editableFields(w, r, incoming, tablePerm)
+	if !ok {
 		return
 	}
+	if hasPID {
+		safe["project_id"] = pid
+	}
 
-	safe := utils.FilterEditableFields(incoming, tablePerm.Fields)
+	var req TaskRequest
+	if !bindRequest(w, r, h.Repo.DB, incoming, safe, &req, false) {
+		return
+	}
 
 	t := models.Task{
-		ID:        uuid.New().String(),
-		ProjectID: pid,
-		Title:     title,
-		CreatedBy: userID,
-		Status:    "TODO",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-
-	if desc, ok := safe["description"].(string); ok {
-		t.Description = desc
-	}
-	if s, ok := safe["status"].(string); ok && s != "" {
-		t.Status = s
-	}
-	if arr, ok := safe["assignees"].([]interface{}); ok {
-		for _, it := range arr {
-			if sid, ok := it.(string); ok {
-				t.Assignees = append(t.Assignees, sid)
-			}
-		}
-	} else if a, ok := safe["assignee"].(string); ok && a != "" {
-		t.Assignees = append(t.Assignees, a)
+		ID:          uuid.New().String(),
+		ProjectID:   req.ProjectID,
+		Title:       req.Title,
+		Description: req.Description,
+		CreatedBy:   userID,
+		Status:      req.Status,
+		Assignees:   req.assignees(),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if t.Status == "" {
+		t.Status = "TODO"
+	}
+	row := taskRow(t)
+	if !recordAllowed(r, tablePerm, rbac.ActionCreate, row) {
+		apierror.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if !actionAllowed(w, r, h.Repo.DB, rbac.TableTasks, rbac.ActionCreate, row, row) {
+		return
 	}
 
-	if err := h.Repo.CreateTask(t); err != nil {
-		http.Error(w, "failed to create task", http.StatusInternalServerError)
+	if err := h.Repo.ForOrg(orgFromRequest(r)).CreateTask(t); err != nil {
+		repoError(w, err, "failed to create task")
 		return
 	}
 
-	json.NewEncoder(w).Encode(t)
+	t.Version = 1
+	setETag(w, t.Version)
+	json.NewEncoder(w).Encode(TaskResult{Task: t, IgnoredFields: ignored})
+}
+
+// TaskResult is a created task with the fields the request could not set.
+type TaskResult struct {
+	models.Task
+	IgnoredFields []string `json:"ignored_fields,omitempty"`
 }
 
 func (h *TaskHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	tablePerm := r.Context().Value(middleware.TablePermKey).(models.ResourcePermission)
-	role, _ := r.Context().Value(middleware.RoleKey).(string)
-	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
 
-	projectID := r.URL.Query().Get("project_id")
-	assignee := r.URL.Query().Get("assignee")
-
-	var tasks []models.Task
-	var err error
+	q, err := listQuery(r, repositories.TaskListing, tablePerm)
+	if err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if pid := r.PathValue("project_id"); pid != "" {
+		if !visible(tablePerm, "project_id") {
+			apierror.Error(w, `cannot filter by "project_id"`, http.StatusBadRequest)
+			return
+		}
+		q.Equal["project_id"] = pid
+	}
 
-	if projectID != "" {
-		tasks, err = h.Repo.ListTasksByProject(projectID)
-	} else if assignee != "" {
-		tasks, err = h.Repo.ListTasksByAssignee(assignee)
-	} else {
-		http.Error(w, "project_id or assignee query required", http.StatusBadRequest)
+	filter, err := rowFilter(r, tablePerm, rbac.ActionView, repositories.TaskSQL)
+	if err != nil {
+		apierror.Internal(w, "invalid row policy", err)
 		return
 	}
 
+	tasks, next, err := h.Repo.ForOrg(orgFromRequest(r)).ListTasks(filter, q)
 	if err != nil {
-		http.Error(w, "failed to fetch tasks", http.StatusInternalServerError)
+		repoError(w, err, "failed to fetch tasks")
 		return
 	}
+	writeNextPage(w, r, next)
 
 	var out []map[string]interface{}
 	for _, t := range tasks {
-		if role == rbac.RoleViewer {
-			allowed := t.CreatedBy == userID
-			if !allowed {
-				for _, a := range t.Assignees {
-					if a == userID {
-						allowed = true
-						break
-					}
-				}
-			}
-			if !allowed {
-				continue
-			}
-		}
-
-		row := map[string]interface{}{
-			"id":           t.ID,
-			"project_id":   t.ProjectID,
-			"title":        t.Title,
-			"description":  t.Description,
-			"status":       t.Status,
-			"assignees":    t.Assignees,
-			"created_by":   t.CreatedBy,
-			"started_at":   t.StartedAt,
-			"completed_at": t.CompletedAt,
-			"created_at":   t.CreatedAt,
-			"updated_at":   t.UpdatedAt,
-		}
-
-		filtered := utils.FilterFields(row, tablePerm.Fields)
-		out = append(out, filtered)
+		out = append(out, taskResponse(t, tablePerm))
 	}
 
 	json.NewEncoder(w).Encode(out)
@@ -154,57 +143,32 @@ func (h *TaskHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
 func (h *TaskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	id := r.URL.Query().Get("id")
+	id := pathOrQuery(r, "id")
 	if id == "" {
-		http.Error(w, "task id required", http.StatusBadRequest)
+		apierror.Error(w, "task id required", http.StatusBadRequest)
 		return
 	}
 
-	t, err := h.Repo.GetTaskByID(id)
+	t, err := h.Repo.ForOrg(orgFromRequest(r)).GetTaskByID(id)
 	if err != nil {
-		http.Error(w, "failed to fetch task", http.StatusInternalServerError)
+		apierror.Internal(w, "failed to fetch task", err)
 		return
 	}
 	if t == nil {
-		http.Error(w, "not found", http.StatusNotFound)
+		apierror.Error(w, "task not found", http.StatusNotFound)
 		return
 	}
 
 	tablePerm := r.Context().Value(middleware.TablePermKey).(models.ResourcePermission)
-	role, _ := r.Context().Value(middleware.RoleKey).(string)
-	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
 
-	if role != rbac.RoleAdmin && role != rbac.RoleManager && role != rbac.RoleEditor {
-		allowed := t.CreatedBy == userID
-		if !allowed {
-			for _, a := range t.Assignees {
-				if a == userID {
-					allowed = true
-					break
-				}
-			}
-		}
-		if !allowed {
-			http.Error(w, "forbidden", http.StatusForbidden)
-			return
-		}
-	}
-
-	row := map[string]interface{}{
-		"id":           t.ID,
-		"project_id":   t.ProjectID,
-		"title":        t.Title,
-		"description":  t.Description,
-		"status":       t.Status,
-		"assignees":    t.Assignees,
-		"created_by":   t.CreatedBy,
-		"started_at":   t.StartedAt,
-		"completed_at": t.CompletedAt,
-		"created_at":   t.CreatedAt,
-		"updated_at":   t.UpdatedAt,
+	row := taskRow(*t)
+	if !recordAllowed(r, tablePerm, rbac.ActionView, row) {
+		apierror.Error(w, "forbidden", http.StatusForbidden)
+		return
 	}
 
-	json.NewEncoder(w).Encode(utils.FilterFields(row, tablePerm.Fields))
+	setETag(w, t.Version)
+	json.NewEncoder(w).Encode(taskResponse(*t, tablePerm))
 }
 
 func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
@@ -214,31 +178,54 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 
 	var incoming map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&incoming); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+		apierror.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
 
-	idVal, ok := incoming["id"].(string)
-	if !ok || idVal == "" {
-		http.Error(w, "task id required", http.StatusBadRequest)
+	idVal := r.PathValue("id")
+	if idVal == "" {
+		idVal, _ = incoming["id"].(string)
+	}
+	if idVal == "" {
+		apierror.Error(w, "task id required", http.StatusBadRequest)
 		return
 	}
+	delete(incoming, "id")
 
-	existing, err := h.Repo.GetTaskByID(idVal)
+	repo := h.Repo.ForOrg(orgFromRequest(r))
+	existing, err := repo.GetTaskByID(idVal)
 	if err != nil || existing == nil {
-		http.Error(w, "task not found", http.StatusNotFound)
+		apierror.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+	if !recordAllowed(r, tablePerm, rbac.ActionEdit, taskRow(*existing)) {
+		apierror.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if !ifMatch(w, r, existing.Version, viewableTask(r, tablePerm, *existing)) {
+		return
+	}
+
+	safe, ignored, ok := editableFields(w, r, incoming, tablePerm)
+	if !ok {
+		return
+	}
+	var req TaskRequest
+	if !bindRequest(w, r, h.Repo.DB, incoming, safe, &req, true) {
 		return
 	}
 
-	safe := utils.FilterEditableFields(incoming, tablePerm.Fields)
+	if !actionAllowed(w, r, h.Repo.DB, rbac.TableTasks, rbac.ActionEdit, taskRow(*existing), safe) {
+		return
+	}
 
-	if title, ok := safe["title"].(string); ok {
-		existing.Title = title
+	if _, ok := safe["title"]; ok {
+		existing.Title = req.Title
 	}
-	if desc, ok := safe["description"].(string); ok {
-		existing.Description = desc
+	if _, ok := safe["description"]; ok {
+		existing.Description = req.Description
 	}
-	if status, ok := safe["status"].(string); ok {
+	if status := req.Status; status != "" {
 		existing.Status = status
 		if status == "IN_PROGRESS" && existing.StartedAt == nil {
 			now := time.Now()
@@ -249,85 +236,222 @@ func (h *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 			existing.CompletedAt = &now
 		}
 	}
-	if arr, ok := safe["assignees"].([]interface{}); ok {
-		existing.Assignees = nil
-		for _, it := range arr {
-			if sid, ok := it.(string); ok {
-				existing.Assignees = append(existing.Assignees, sid)
-			}
-		}
-	} else if a, ok := safe["assignee"].(string); ok && a != "" {
-		existing.Assignees = []string{a}
+	if _, ok := safe["assignees"]; ok {
+		existing.Assignees = req.Assignees
+	} else if req.Assignee != "" {
+		existing.Assignees = []string{req.Assignee}
 	}
 
 	existing.UpdatedAt = time.Now()
 
-	if err := h.Repo.UpdateTask(*existing); err != nil {
-		http.Error(w, "update failed", http.StatusInternalServerError)
+	if err := repo.UpdateTask(*existing); err != nil {
+		h.updateFailed(w, r, err, tablePerm, idVal)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
+	setETag(w, existing.Version+1)
+	json.NewEncoder(w).Encode(withIgnored(map[string]interface{}{"status": "updated"}, ignored))
 }
 
 func (h *TaskHandler) AssignTask(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	var payload struct {
-		ID        string   `json:"id"`
-		Assignee  string   `json:"assignee"`
-		Assignees []string `json:"assignees"`
+	tablePerm := r.Context().Value(middleware.TablePermKey).(models.ResourcePermission)
+
+	var incoming map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&incoming); err != nil {
+		apierror.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	safe, ignored, ok := editableAssignment(w, r, incoming, tablePerm)
+	if !ok {
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		http.Error(w, "invalid request", http.StatusBadRequest)
+	var payload AssignRequest
+	if !bindRequest(w, r, h.Repo.DB, incoming, safe, &payload, false) {
 		return
 	}
+	if id := r.PathValue("id"); id != "" {
+		payload.ID = id
+	}
 	if payload.ID == "" {
-		http.Error(w, "id required", http.StatusBadRequest)
+		apierror.Error(w, "id required", http.StatusBadRequest)
 		return
 	}
 
-	if len(payload.Assignees) > 0 {
-		t, err := h.Repo.GetTaskByID(payload.ID)
-		if err != nil || t == nil {
-			http.Error(w, "task not found", http.StatusNotFound)
-			return
-		}
-		t.Assignees = payload.Assignees
-		if err := h.Repo.UpdateTask(*t); err != nil {
-			http.Error(w, "assign failed", http.StatusInternalServerError)
-			return
-		}
-		json.NewEncoder(w).Encode(map[string]string{"status": "assigned"})
+	repo := h.Repo.ForOrg(orgFromRequest(r))
+	t, err := repo.GetTaskByID(payload.ID)
+	if err != nil || t == nil {
+		apierror.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+	if !recordAllowed(r, tablePerm, rbac.ActionEdit, taskRow(*t)) {
+		apierror.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if !ifMatch(w, r, t.Version, viewableTask(r, tablePerm, *t)) {
 		return
 	}
 
-	if payload.Assignee == "" {
-		http.Error(w, "assignee required", http.StatusBadRequest)
+	var assignees []string
+	switch {
+	case len(payload.Assignees) > 0:
+		assignees = payload.Assignees
+	case payload.Assignee != "":
+		assignees = t.Assignees
+		if !slices.Contains(assignees, payload.Assignee) {
+			assignees = append(slices.Clone(assignees), payload.Assignee)
+		}
+	case len(ignored) > 0:
+		apierror.Write(w, http.StatusForbidden, apierror.CodeForbiddenFields, "no editable fields", ForbiddenFieldsDetails{Fields: ignored})
+		return
+	default:
+		apierror.Error(w, "assignee required", http.StatusBadRequest)
+		return
+	}
+	if !actionAllowed(w, r, h.Repo.DB, rbac.TableTasks, rbac.ActionEdit, taskRow(*t), map[string]interface{}{"assignees": assignees}) {
 		return
 	}
 
-	if err := h.Repo.AssignTask(payload.ID, payload.Assignee); err != nil {
-		http.Error(w, "assign failed", http.StatusInternalServerError)
+	if len(payload.Assignees) > 0 {
+		t.Assignees = payload.Assignees
+		err = repo.UpdateTask(*t)
+	} else {
+		err = repo.AssignTask(payload.ID, payload.Assignee, t.Version)
+	}
+	if err != nil {
+		h.updateFailed(w, r, err, tablePerm, payload.ID)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{"status": "assigned"})
+	setETag(w, t.Version+1)
+	json.NewEncoder(w).Encode(withIgnored(map[string]interface{}{"status": "assigned"}, ignored))
+}
+
+// editableAssignment is editableFields for an assignment body. id names the
+// task rather than setting a field, and assignee adds to the assignees
+// field, so the field rules of assignees decide it.
+func editableAssignment(w http.ResponseWriter, r *http.Request, incoming map[string]interface{}, perm models.ResourcePermission) (map[string]interface{}, []string, bool) {
+	fields := map[string]interface{}{}
+	for k, v := range incoming {
+		if k != "id" {
+			fields[k] = v
+		}
+	}
+	_, single := fields["assignee"]
+	_, list := fields["assignees"]
+	single = single && !list
+	if single {
+		fields["assignees"] = []interface{}{fields["assignee"]}
+		delete(fields, "assignee")
+	}
+
+	safe, ignored, ok := editableFields(w, r, fields, perm)
+	if !ok {
+		return nil, nil, false
+	}
+	if kept, _ := safe["assignees"].([]interface{}); single {
+		delete(safe, "assignees")
+		if len(kept) > 0 {
+			safe["assignee"] = kept[0]
+		}
+	}
+	if id, ok := incoming["id"]; ok {
+		safe["id"] = id
+	}
+	return safe, ignored, true
+}
+
+// updateFailed answers a failed task update: 412 with the current task when
+// another update came first (see ifMatch), otherwise as repoError does.
+func (h *TaskHandler) updateFailed(w http.ResponseWriter, r *http.Request, err error, tablePerm models.ResourcePermission, id string) {
+	if !errors.Is(err, repositories.ErrStale) {
+		repoError(w, err, "update failed")
+		return
+	}
+	t, err := h.Repo.ForOrg(orgFromRequest(r)).GetTaskByID(id)
+	if err != nil {
+		apierror.Internal(w, "failed to fetch task", err)
+		return
+	}
+	if t == nil {
+		apierror.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+	writeStale(w, t.Version, viewableTask(r, tablePerm, *t))
 }
 
+// DeleteTask moves a task to the trash.
 func (h *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	id := r.URL.Query().Get("id")
+	id := pathOrQuery(r, "id")
 	if id == "" {
-		http.Error(w, "task id required", http.StatusBadRequest)
+		apierror.Error(w, "task id required", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.Repo.DeleteTask(id); err != nil {
-		http.Error(w, "delete failed", http.StatusInternalServerError)
+	tablePerm := r.Context().Value(middleware.TablePermKey).(models.ResourcePermission)
+
+	repo := h.Repo.ForOrg(orgFromRequest(r))
+	t, err := repo.GetTaskByID(id)
+	if err != nil || t == nil {
+		apierror.Error(w, "task not found", http.StatusNotFound)
 		return
 	}
+	if !recordAllowed(r, tablePerm, rbac.ActionDelete, taskRow(*t)) {
+		apierror.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	if err := repo.DeleteTask(id, userID); err != nil {
+		repoError(w, err, "delete failed")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "task moved to the trash"})
+}
+
+// assignees are the assignees a create sets.
+func (req TaskRequest) assignees() []string {
+	if len(req.Assignees) > 0 {
+		return req.Assignees
+	}
+	if req.Assignee != "" {
+		return []string{req.Assignee}
+	}
+	return nil
+}
+
+// taskResponse is t as the caller may view it.
+func taskResponse(t models.Task, tablePerm models.ResourcePermission) map[string]interface{} {
+	return withVersion(utils.FilterFields(taskRow(t), tablePerm.Fields), t.Version)
+}
+
+// viewableTask is taskResponse, or nil when the row-level rules do not let
+// the caller view t.
+func viewableTask(r *http.Request, tablePerm models.ResourcePermission, t models.Task) map[string]interface{} {
+	if !recordAllowed(r, tablePerm, rbac.ActionView, taskRow(t)) {
+		return nil
+	}
+	return taskResponse(t, tablePerm)
+}
 
-	json.NewEncoder(w).Encode(map[string]string{"message": "task deleted"})
+// taskRow is the full field map of a task, used for row-level conditions and
+// field filtering.
+func taskRow(t models.Task) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           t.ID,
+		"project_id":   t.ProjectID,
+		"title":        t.Title,
+		"description":  t.Description,
+		"status":       t.Status,
+		"assignees":    t.Assignees,
+		"created_by":   t.CreatedBy,
+		"started_at":   t.StartedAt,
+		"completed_at": t.CompletedAt,
+		"created_at":   t.CreatedAt,
+		"updated_at":   t.UpdatedAt,
+	}
 }
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"rbac-backend/internal/apierror"
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/models"
+	repositories "rbac-backend/internal/repository"
+)
+
+// projectID is the project setupTaskHandler creates.
+const projectID = "6f1f8a52-3c1e-4d8e-9b1a-2f4c5d6e7f80"
+
+// setupTaskHandler returns a task handler over a database with a project and
+// task t1 in it, and the admin's id.
+func setupTaskHandler(t *testing.T) (*TaskHandler, string) {
+	database, adminID := setupHandlerDB(t)
+	if err := repositories.NewProjectRepository(database).ForOrg(models.DefaultOrgID).CreateProjectDynamic(map[string]interface{}{
+		"id": projectID, "name": "Apollo", "created_by": adminID,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	h := NewTaskHandler(repositories.NewTaskRepository(database))
+	if err := h.Repo.ForOrg(models.DefaultOrgID).CreateTask(models.Task{ID: "t1", ProjectID: projectID, Title: "Triage", CreatedBy: adminID, Status: "TODO"}); err != nil {
+		t.Fatal(err)
+	}
+	return h, adminID
+}
+
+func assign(t *testing.T, h *TaskHandler, c caller, mode string, body map[string]interface{}) int {
+	t.Helper()
+	r := c.request("POST", "/tasks/t1/assignees", body)
+	r.SetPathValue("id", "t1")
+	r.Header.Set("If-Match", "*")
+	if mode != "" {
+		r.Header.Set(FieldModeHeader, mode)
+	}
+	status, _ := serve(t, h.AssignTask, r)
+	return status
+}
+
+func TestAssignTaskFollowsAssigneesFieldRules(t *testing.T) {
+	h, adminID := setupTaskHandler(t)
+	triager := caller{userID: adminID, role: "TRIAGER", perm: models.ResourcePermission{View: true, Edit: true, Fields: map[string]models.FieldPermission{
+		"*":         {View: true, Edit: true},
+		"assignees": {View: true},
+	}}}
+
+	for _, tt := range []struct {
+		mode string
+		body map[string]interface{}
+	}{
+		{"lenient", map[string]interface{}{"assignee": adminID}},
+		{"lenient", map[string]interface{}{"assignees": []string{adminID}}},
+		{"strict", map[string]interface{}{"assignee": adminID}},
+	} {
+		if status := assign(t, h, triager, tt.mode, tt.body); status != http.StatusForbidden {
+			t.Errorf("%s %v: status %d, want 403", tt.mode, tt.body, status)
+		}
+	}
+	if task, _ := h.Repo.ForOrg(models.DefaultOrgID).GetTaskByID("t1"); len(task.Assignees) != 0 {
+		t.Errorf("assignees = %v, want none", task.Assignees)
+	}
+
+	triager.perm.Fields["assignees"] = models.FieldPermission{View: true, Edit: true}
+	if status := assign(t, h, triager, "", map[string]interface{}{"assignee": adminID}); status != http.StatusOK {
+		t.Errorf("with edit on assignees: status %d, want 200", status)
+	}
+}
+
+func TestCreateTaskChecksCreateCondition(t *testing.T) {
+	h, adminID := setupTaskHandler(t)
+	c := caller{userID: adminID, role: "TRIAGER", perm: models.ResourcePermission{
+		View: true, Create: true,
+		Conditions: map[string]string{"create": `record.status != "DONE"`},
+	}}
+	create := func(status string) int {
+		t.Helper()
+		code, _ := serve(t, h.CreateTask, c.request("POST", "/tasks", map[string]interface{}{"project_id": projectID, "title": "New", "status": status}))
+		return code
+	}
+	if status := create("DONE"); status != http.StatusForbidden {
+		t.Errorf("a task the condition refuses: status %d, want 403", status)
+	}
+	if status := create("TODO"); status != http.StatusOK {
+		t.Errorf("a task the condition allows: status %d, want 200", status)
+	}
+}
+
+func TestTaskConstraintsApplyToCreateAndAssign(t *testing.T) {
+	h, adminID := setupTaskHandler(t)
+	if err := db.UpdateConstraints(h.Repo.DB, models.Constraints{Actions: []models.ActionConstraint{
+		{ID: "no-done-create", Table: "tasks", Action: "create", DenyIf: `change.status == "DONE"`},
+		{ID: "admin-unassigned", Table: "tasks", Action: "edit", DenyIf: `"` + adminID + `" in change.assignees`},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	admin := caller{userID: adminID, role: "ADMIN", perm: models.ResourcePermission{View: true, Create: true, Edit: true}}
+
+	status, body := serve(t, h.CreateTask, admin.request("POST", "/tasks", map[string]interface{}{"project_id": projectID, "title": "Done already", "status": "DONE"}))
+	if status != http.StatusForbidden || errorCode(body) != apierror.CodeConstraintViolation {
+		t.Errorf("create: %d %v, want a constraint violation", status, body)
+	}
+	for _, body := range []map[string]interface{}{{"assignee": adminID}, {"assignees": []string{adminID}}} {
+		if status := assign(t, h, admin, "", body); status != http.StatusForbidden {
+			t.Errorf("assign %v: status %d, want 403", body, status)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"rbac-backend/internal/apierror"
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/middleware"
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+	repositories "rbac-backend/internal/repository"
+)
+
+// Deleted projects and tasks go to their table's trash. GET /<table>/trash
+// lists it and POST /<table>/trash/{id}/restore takes a record back out,
+// both under the restore action; DELETE /<table>/trash/{id} purges a record
+// for good under the purge action. TrashPurger purges what has been in the
+// trash for longer than the retention period.
+
+// ListTrash lists the projects in the trash that the caller may restore,
+// with the parameters of GET /projects.
+func (h *ProjectHandler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	tablePerm := r.Context().Value(middleware.TablePermKey).(models.ResourcePermission)
+	q, err := listQuery(r, repositories.ProjectListing, tablePerm)
+	if err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Trash = true
+	filter, err := rowFilter(r, tablePerm, rbac.ActionRestore, repositories.ProjectSQL)
+	if err != nil {
+		apierror.Internal(w, "invalid row policy", err)
+		return
+	}
+
+	projects, next, err := h.Repo.ForOrg(orgFromRequest(r)).ListProjects(filter, q)
+	if err != nil {
+		repoError(w, err, "failed to fetch the trash")
+		return
+	}
+	writeNextPage(w, r, next)
+
+	out := []map[string]interface{}{}
+	for _, p := range projects {
+		out = append(out, withTrash(projectResponse(p, tablePerm), p.DeletedAt, p.DeletedBy))
+	}
+	json.NewEncoder(w).Encode(out)
+}
+
+// RestoreProject takes a project out of the trash and returns it.
+func (h *ProjectHandler) RestoreProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	tablePerm := r.Context().Value(middleware.TablePermKey).(models.ResourcePermission)
+	repo := h.Repo.ForOrg(orgFromRequest(r))
+	p, ok := trashedRecord(w, r, tablePerm, rbac.ActionRestore, "project", repo.GetTrashedProject, projectRow)
+	if !ok {
+		return
+	}
+	if err := repo.RestoreProject(p.ID); err != nil {
+		repoError(w, err, "restore failed")
+		return
+	}
+
+	p.Version++
+	setETag(w, p.Version)
+	json.NewEncoder(w).Encode(projectResponse(*p, tablePerm))
+}
+
+// PurgeProject deletes a project from the trash for good. A project whose
+// tasks are still in the trash gets 409.
+func (h *ProjectHandler) PurgeProject(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	tablePerm := r.Context().Value(middleware.TablePermKey).(models.ResourcePermission)
+	repo := h.Repo.ForOrg(orgFromRequest(r))
+	p, ok := trashedRecord(w, r, tablePerm, rbac.ActionPurge, "project", repo.GetTrashedProject, projectRow)
+	if !ok {
+		return
+	}
+	if err := repo.PurgeProject(p.ID); err != nil {
+		repoError(w, err, "purge failed")
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	auditPurge(h.Repo.DB, orgFromRequest(r), userID, "project", p.ID, fmt.Sprintf("name=%q", p.Name))
+	json.NewEncoder(w).Encode(map[string]string{"message": "project purged"})
+}
+
+// ListTrash lists the tasks in the trash that the caller may restore, with
+// the parameters of GET /tasks.
+func (h *TaskHandler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	tablePerm := r.Context().Value(middleware.TablePermKey).(models.ResourcePermission)
+	q, err := listQuery(r, repositories.TaskListing, tablePerm)
+	if err != nil {
+		apierror.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Trash = true
+	filter, err := rowFilter(r, tablePerm, rbac.ActionRestore, repositories.TaskSQL)
+	if err != nil {
+		apierror.Internal(w, "invalid row policy", err)
+		return
+	}
+
+	tasks, next, err := h.Repo.ForOrg(orgFromRequest(r)).ListTasks(filter, q)
+	if err != nil {
+		repoError(w, err, "failed to fetch the trash")
+		return
+	}
+	writeNextPage(w, r, next)
+
+	out := []map[string]interface{}{}
+	for _, t := range tasks {
+		out = append(out, withTrash(taskResponse(t, tablePerm), t.DeletedAt, t.DeletedBy))
+	}
+	json.NewEncoder(w).Encode(out)
+}
+
+// RestoreTask takes a task out of the trash and returns it. A task whose
+// project is in the trash gets 409 until the project is restored.
+func (h *TaskHandler) RestoreTask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	tablePerm := r.Context().Value(middleware.TablePermKey).(models.ResourcePermission)
+	repo := h.Repo.ForOrg(orgFromRequest(r))
+	t, ok := trashedRecord(w, r, tablePerm, rbac.ActionRestore, "task", repo.GetTrashedTask, taskRow)
+	if !ok {
+		return
+	}
+	if err := repo.RestoreTask(t.ID); err != nil {
+		repoError(w, err, "restore failed")
+		return
+	}
+
+	t.Version++
+	setETag(w, t.Version)
+	json.NewEncoder(w).Encode(taskResponse(*t, tablePerm))
+}
+
+// PurgeTask deletes a task from the trash for good.
+func (h *TaskHandler) PurgeTask(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	tablePerm := r.Context().Value(middleware.TablePermKey).(models.ResourcePermission)
+	repo := h.Repo.ForOrg(orgFromRequest(r))
+	t, ok := trashedRecord(w, r, tablePerm, rbac.ActionPurge, "task", repo.GetTrashedTask, taskRow)
+	if !ok {
+		return
+	}
+	if err := repo.PurgeTask(t.ID); err != nil {
+		repoError(w, err, "purge failed")
+		return
+	}
+
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+	auditPurge(h.Repo.DB, orgFromRequest(r), userID, "task", t.ID, fmt.Sprintf("title=%q", t.Title))
+	json.NewEncoder(w).Encode(map[string]string{"message": "task purged"})
+}
+
+// trashedRecord loads the record {id} from the trash with get and applies the
+// row-level condition for action, writing the error response when it is not
+// in the trash or access is refused.
+func trashedRecord[T any](w http.ResponseWriter, r *http.Request, tablePerm models.ResourcePermission, action, kind string,
+	get func(id string) (*T, error), row func(T) map[string]interface{}) (*T, bool) {
+	record, err := get(r.PathValue("id"))
+	if err != nil {
+		apierror.Internal(w, "failed to fetch "+kind, err)
+		return nil, false
+	}
+	if record == nil {
+		apierror.Error(w, kind+" is not in the trash", http.StatusNotFound)
+		return nil, false
+	}
+	if !recordAllowed(r, tablePerm, action, row(*record)) {
+		apierror.Error(w, "forbidden", http.StatusForbidden)
+		return nil, false
+	}
+	return record, true
+}
+
+// withTrash adds when and by whom a record was deleted to a record filtered
+// for the caller. Like the version, they are not fields of the record.
+func withTrash(record map[string]interface{}, deletedAt *time.Time, deletedBy string) map[string]interface{} {
+	record["deleted_at"] = deletedAt
+	record["deleted_by"] = deletedBy
+	return record
+}
+
+// auditPurge records that actorID purged a record; actorID is empty for
+// TrashPurger.
+func auditPurge(database *sql.DB, orgID, actorID, kind, id, details string) {
+	if err := db.RecordAudit(database, orgID, actorID, kind+".purge", id, details); err != nil {
+		log.Printf("audit %s.purge %s: %v", kind, id, err)
+	}
+}
+
+// TrashPurger purges the projects and tasks that have been in the trash for
+// longer than Retention.
+type TrashPurger struct {
+	DB        *sql.DB
+	Retention time.Duration
+}
+
+func NewTrashPurger(database *sql.DB, retention time.Duration) *TrashPurger {
+	return &TrashPurger{DB: database, Retention: retention}
+}
+
+// Run purges the trash every interval until ctx is done.
+func (p *TrashPurger) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.purgeDue()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *TrashPurger) purgeDue() {
+	purged, err := repositories.PurgeTrash(p.DB, time.Now().Add(-p.Retention))
+	if err != nil {
+		log.Println("trash purge failed:", err)
+	}
+	for _, rec := range purged {
+		auditPurge(p.DB, rec.OrgID, "", rec.Kind, rec.ID, "retention="+p.Retention.String())
+	}
+}
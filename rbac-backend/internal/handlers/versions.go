@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"rbac-backend/internal/apierror"
+)
+
+// StaleDetails are the details of a 412 answer to an update: the record as
+// it is now, as far as the caller may view it.
+type StaleDetails struct {
+	Current map[string]interface{} `json:"current,omitempty"`
+}
+
+// etag is the ETag of a project or task at version. The same version gives
+// different bodies to callers with different field rules, but a caller
+// always gets the same body for it.
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", etag(version))
+}
+
+// ifMatch lets an update of a record at version proceed when If-Match holds
+// its ETag, or "*". It answers 428 when the header is missing and, through
+// writeStale, 412 with current when it names other versions.
+func ifMatch(w http.ResponseWriter, r *http.Request, version int, current map[string]interface{}) bool {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		apierror.Error(w, "If-Match is required: send the ETag of the version you read", http.StatusPreconditionRequired)
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimSpace(tag) == etag(version) {
+			return true
+		}
+	}
+	writeStale(w, version, current)
+	return false
+}
+
+// writeStale answers 412 for an update that expected another version than
+// version, the current one. current is nil when the caller may not view the
+// record.
+func writeStale(w http.ResponseWriter, version int, current map[string]interface{}) {
+	setETag(w, version)
+	apierror.Write(w, http.StatusPreconditionFailed, apierror.Code(http.StatusPreconditionFailed),
+		"the record was changed since you read it; current version is "+etag(version), StaleDetails{Current: current})
+}
+
+// withVersion adds version to a record filtered for the caller. The version
+// is not a field of the record, so field rules do not hide it.
+func withVersion(record map[string]interface{}, version int) map[string]interface{} {
+	record["version"] = version
+	return record
+}
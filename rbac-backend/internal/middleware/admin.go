@@ -1,22 +1,57 @@
 package middleware
 
 import (
+	"database/sql"
 	"net/http"
 
-	"rbac-backend/internal/rbac"
+	"rbac-backend/internal/apierror"
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/models"
 )
 
-// RequireAdmin restricts the route to ADMIN only (manage users, create/update roles).
-func RequireAdmin(next http.Handler) http.Handler {
+// RequireSuperuser restricts the route to superuser roles (manage users,
+// create/update roles). An active elevation to a superuser role also passes
+// and is recorded in the audit log.
+func RequireSuperuser(database *sql.DB, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		roleVal := r.Context().Value(RoleKey)
 		if roleVal == nil {
-			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			apierror.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
 		role := roleVal.(string)
-		if role != rbac.RoleAdmin {
-			http.Error(w, "admin only", http.StatusForbidden)
+		superuser, err := db.IsSuperuser(database, role)
+		if err != nil {
+			apierror.Internal(w, "permission lookup failed", err)
+			return
+		}
+		if !superuser {
+			userID, _ := r.Context().Value(UserIDKey).(string)
+			orgID, _ := r.Context().Value(OrgIDKey).(string)
+			grants, err := activeElevations(database, orgID, userID)
+			if err != nil {
+				apierror.Internal(w, "permission lookup failed", err)
+				return
+			}
+			grant, ok := elevatedToSuperuser(database, grants)
+			if !ok {
+				apierror.Error(w, "admin only", http.StatusForbidden)
+				return
+			}
+			auditElevationUse(database, orgID, userID, grant.ID, "admin", r.Method+" "+r.URL.Path)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireDefaultOrg restricts the route to callers whose active organization
+// is the default one. It guards settings shared by every organization, so an
+// organization's superusers cannot change them for the others.
+func RequireDefaultOrg(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		orgID, _ := r.Context().Value(OrgIDKey).(string)
+		if orgID != models.DefaultOrgID {
+			apierror.Error(w, "only available in the default organization", http.StatusForbidden)
 			return
 		}
 		next.ServeHTTP(w, r)
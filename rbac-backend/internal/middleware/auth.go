@@ -2,10 +2,13 @@ package middleware
 
 import (
 	"context"
+	"database/sql"
 	"net/http"
 	"strings"
 
+	"rbac-backend/internal/apierror"
 	"rbac-backend/internal/auth"
+	"rbac-backend/internal/db"
 )
 
 type ContextKey string
@@ -13,19 +16,24 @@ type ContextKey string
 const (
 	UserIDKey ContextKey = "userID"
 	RoleKey   ContextKey = "role"
+	OrgIDKey  ContextKey = "orgID"
 )
 
-func AuthMiddleware(next http.Handler) http.Handler {
+// AuthMiddleware authenticates the bearer token and puts the user, their
+// organization and their role there into the context. Membership is looked
+// up on every request rather than trusted from the token, so a member who is
+// removed loses access at once and a role change applies to the next request.
+func AuthMiddleware(database *sql.DB, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "missing authorization header", http.StatusUnauthorized)
+			apierror.Error(w, "missing authorization header", http.StatusUnauthorized)
 			return
 		}
 
 		if !strings.HasPrefix(authHeader, "Bearer ") {
-			http.Error(w, "invalid authorization format", http.StatusUnauthorized)
+			apierror.Error(w, "invalid authorization format", http.StatusUnauthorized)
 			return
 		}
 
@@ -33,12 +41,28 @@ func AuthMiddleware(next http.Handler) http.Handler {
 
 		claims, err := auth.ValidateJWT(tokenStr)
 		if err != nil {
-			http.Error(w, "invalid or expired token", http.StatusUnauthorized)
+			apierror.Error(w, "invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+
+		if claims.OrgID == "" {
+			apierror.Error(w, "token has no organization, log in again", http.StatusUnauthorized)
+			return
+		}
+
+		m, err := db.GetMembership(database, claims.OrgID, claims.UserID)
+		if err != nil {
+			apierror.Internal(w, "membership lookup failed", err)
+			return
+		}
+		if m == nil {
+			apierror.Error(w, "not a member of the token's organization, log in again", http.StatusUnauthorized)
 			return
 		}
 
 		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
-		ctx = context.WithValue(ctx, RoleKey, claims.Role)
+		ctx = context.WithValue(ctx, RoleKey, m.Role)
+		ctx = context.WithValue(ctx, OrgIDKey, claims.OrgID)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"rbac-backend/internal/auth"
+	"rbac-backend/internal/models"
+)
+
+func TestAuthMiddlewareLooksUpMembership(t *testing.T) {
+	database := setupMiddlewareDB(t)
+	userID := addMember(t, database, "EDITOR")
+
+	var role string
+	h := AuthMiddleware(database, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role = r.Context().Value(RoleKey).(string)
+	}))
+	call := func(orgID string) int {
+		t.Helper()
+		token, err := auth.GenerateJWT(userID, "EDITOR", orgID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	if status := call(models.DefaultOrgID); status != http.StatusOK || role != "EDITOR" {
+		t.Fatalf("member: status %d, role %q", status, role)
+	}
+	if status := call(""); status != http.StatusUnauthorized {
+		t.Errorf("token without an organization: status %d, want 401", status)
+	}
+
+	// The stored role is in force, not the one in the token.
+	if _, err := database.Exec(`UPDATE organization_members SET role = 'VIEWER' WHERE user_id = ?`, userID); err != nil {
+		t.Fatal(err)
+	}
+	if status := call(models.DefaultOrgID); status != http.StatusOK || role != "VIEWER" {
+		t.Errorf("after a role change: status %d, role %q, want VIEWER", status, role)
+	}
+
+	if _, err := database.Exec(`DELETE FROM organization_members WHERE user_id = ?`, userID); err != nil {
+		t.Fatal(err)
+	}
+	if status := call(models.DefaultOrgID); status != http.StatusUnauthorized {
+		t.Errorf("removed member: status %d, want 401", status)
+	}
+}
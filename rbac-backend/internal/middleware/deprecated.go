@@ -0,0 +1,13 @@
+package middleware
+
+import "net/http"
+
+// Deprecated marks responses of a legacy route with a Deprecation header and
+// a Link to the route that replaces it. The request is served unchanged.
+func Deprecated(successor string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		next.ServeHTTP(w, r)
+	})
+}
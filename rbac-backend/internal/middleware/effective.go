@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"database/sql"
+	"errors"
+
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+)
+
+// EffectivePermissions resolves the permission RBACMiddleware would apply to
+// the caller in organization orgID on every registered table: full access for
+// a superuser role or an elevation to one, otherwise the role's inherited
+// permissions with active elevations merged in. Tables the caller cannot
+// reach at all are omitted. The active elevations are returned alongside.
+func EffectivePermissions(database *sql.DB, orgID, role, userID string) (models.Permissions, []models.Elevation, error) {
+	superuser, err := db.IsSuperuser(database, role)
+	if err != nil {
+		return nil, nil, err
+	}
+	grants, err := activeElevations(database, orgID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, elevatedSuperuser := elevatedToSuperuser(database, grants)
+
+	var rolePerms models.Permissions
+	lookup := func(role string) (models.Permissions, error) {
+		if rolePerms != nil {
+			return rolePerms, nil
+		}
+		perms, err := db.GetEffectivePermissions(database, orgID, role)
+		rolePerms = perms
+		return perms, err
+	}
+
+	out := models.Permissions{}
+	for table := range rbac.Registry {
+		if elevatedSuperuser {
+			out[table] = fullAccessPerm()
+			continue
+		}
+		base, err := ResolveTablePermission(role, superuser, table, lookup)
+		hasBase := err == nil
+		if err != nil && !errors.Is(err, ErrNoTableAccess) && len(grants) == 0 {
+			return nil, nil, err
+		}
+		perm, applied := applyElevations(database, orgID, base, hasBase, table, grants)
+		if hasBase || len(applied) > 0 {
+			out[table] = perm
+		}
+	}
+	return out, grants, nil
+}
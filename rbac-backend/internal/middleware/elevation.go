@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"database/sql"
+	"time"
+
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+	repositories "rbac-backend/internal/repository"
+)
+
+// activeElevations returns the caller's approved, unexpired grants in the organization.
+func activeElevations(database *sql.DB, orgID, userID string) ([]models.Elevation, error) {
+	if userID == "" {
+		return nil, nil
+	}
+	return repositories.NewElevationRepository(database).ForOrg(orgID).ActiveForUser(userID, time.Now().UTC())
+}
+
+// elevatedToSuperuser reports whether one of the grants elevates the caller
+// to a superuser role (break-glass access).
+func elevatedToSuperuser(database *sql.DB, grants []models.Elevation) (models.Elevation, bool) {
+	for _, g := range grants {
+		if g.Role == "" {
+			continue
+		}
+		if ok, err := db.IsSuperuser(database, g.Role); err == nil && ok {
+			return g, true
+		}
+	}
+	return models.Elevation{}, false
+}
+
+// noFields is the field set of a table the role has no rules for: a rule for
+// "*" that grants nothing, so every field is hidden. Without it the elevated
+// entry would have no field rules, which allows every field.
+func noFields() map[string]models.FieldPermission {
+	return map[string]models.FieldPermission{"*": {}}
+}
+
+// applyElevations merges the grants that concern table into base and returns
+// the ids of the grants that contributed to the result. hasBase is false when
+// the role has no rules for table; the grants then start from noFields, so a
+// table action grant opens the action without opening any field and a role
+// grant opens only the fields of that role.
+func applyElevations(database *sql.DB, orgID string, base models.ResourcePermission, hasBase bool, table string, grants []models.Elevation) (models.ResourcePermission, []string) {
+	if !hasBase {
+		base = models.ResourcePermission{Fields: noFields()}
+	}
+	perm := base
+	var applied []string
+	for _, g := range grants {
+		switch {
+		case g.Role != "":
+			perms, err := db.GetEffectivePermissions(database, orgID, g.Role)
+			if err != nil {
+				continue
+			}
+			rolePerm, ok := perms[table]
+			if !ok {
+				continue
+			}
+			perm = rbac.Merge(perm, rolePerm)
+			applied = append(applied, g.ID)
+		case g.Table == table:
+			grant := models.ResourcePermission{Fields: base.Fields}
+			switch g.Action {
+			case rbac.ActionView:
+				grant.View = true
+			case rbac.ActionCreate:
+				grant.Create = true
+			case rbac.ActionEdit:
+				grant.Edit = true
+			case rbac.ActionDelete:
+				grant.Delete = true
+			case rbac.ActionRestore:
+				grant.Restore = true
+			case rbac.ActionPurge:
+				grant.Purge = true
+			}
+			perm = rbac.Merge(perm, grant)
+			applied = append(applied, g.ID)
+		}
+	}
+	return perm, applied
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+	repositories "rbac-backend/internal/repository"
+	"rbac-backend/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+func TestElevationOnTableWithoutRulesOpensNoFields(t *testing.T) {
+	database := setupMiddlewareDB(t)
+	viewer := addMember(t, database, "VIEWER")
+	approver := addMember(t, database, "ADMIN")
+	repo := repositories.NewElevationRepository(database).ForOrg(models.DefaultOrgID)
+	grant := func(e models.Elevation) {
+		t.Helper()
+		e.ID, e.UserID, e.Justification, e.DurationMinutes = uuid.New().String(), viewer, "audit", 30
+		e.Status, e.RequestedAt = models.ElevationPending, time.Now().UTC()
+		if err := repo.CreateElevation(e); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := repo.Approve(e.ID, approver, time.Now().UTC()); !ok || err != nil {
+			t.Fatalf("approve: %v, %v", ok, err)
+		}
+	}
+	user := map[string]interface{}{"id": "u1", "name": "Ann", "email": "ann@example.com", "role": "ADMIN"}
+
+	if status, _ := authorize(database, viewer, "VIEWER", rbac.TableUsers, rbac.ActionView); status != http.StatusForbidden {
+		t.Fatalf("VIEWER without a grant: status %d, want 403", status)
+	}
+
+	grant(models.Elevation{Table: rbac.TableUsers, Action: rbac.ActionView})
+	status, perm := authorize(database, viewer, "VIEWER", rbac.TableUsers, rbac.ActionView)
+	if status != http.StatusOK || perm == nil {
+		t.Fatalf("VIEWER with users:view: status %d, want 200", status)
+	}
+	if got := utils.FilterFields(user, perm.Fields); len(got) != 0 {
+		t.Errorf("users:view on a table VIEWER has no rules for shows %v, want no fields", got)
+	}
+
+	// A role grant opens the fields of that role, and only those.
+	if err := db.SaveRole(database, models.Role{Name: "HR"}); err != nil {
+		t.Fatal(err)
+	}
+	hr := models.Permissions{rbac.TableUsers: {View: true, Fields: map[string]models.FieldPermission{"name": {View: true}}}}
+	if _, err := db.UpdateRolePermissions(database, models.DefaultOrgID, "HR", hr, approver, ""); err != nil {
+		t.Fatal(err)
+	}
+	grant(models.Elevation{Role: "HR"})
+	status, perm = authorize(database, viewer, "VIEWER", rbac.TableUsers, rbac.ActionView)
+	if status != http.StatusOK || perm == nil {
+		t.Fatalf("VIEWER with users:view and HR: status %d, want 200", status)
+	}
+	if got := utils.FilterFields(user, perm.Fields); len(got) != 1 || got["name"] != "Ann" {
+		t.Errorf("users with an HR grant: %v, want only HR's name field", got)
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"rbac-backend/internal/config"
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/models"
+
+	"github.com/google/uuid"
+	_ "modernc.org/sqlite"
+)
+
+// setupMiddlewareDB returns a migrated database.
+func setupMiddlewareDB(t *testing.T) *sql.DB {
+	config.AppConfig = &config.Config{JWTSecret: "test-secret"}
+	t.Chdir(filepath.Join("..", "..")) // RunMigrations looks for ./migrations
+
+	database, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "rbac.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := db.RunMigrations(database); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+// addMember creates an account that is a member of the default organization
+// with role and returns its id.
+func addMember(t *testing.T, database *sql.DB, role string) string {
+	t.Helper()
+	id := uuid.New().String()
+	if _, err := database.Exec(
+		`INSERT INTO users (id, name, email, password_hash, role) VALUES (?, 'Test', ?, 'x', ?)`, id, id+"@example.com", role,
+	); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec(`INSERT INTO organization_members (org_id, user_id, role) VALUES (?, ?, ?)`, models.DefaultOrgID, id, role); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// authorize runs RBACMiddleware for table and action on behalf of userID
+// holding role. It returns the status and the permission handed to the next
+// handler, if it was called.
+func authorize(database *sql.DB, userID, role, table, action string) (int, *models.ResourcePermission) {
+	var got *models.ResourcePermission
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		perm := r.Context().Value(TablePermKey).(models.ResourcePermission)
+		got = &perm
+	})
+	r := httptest.NewRequest("GET", "/", nil)
+	ctx := context.WithValue(r.Context(), UserIDKey, userID)
+	ctx = context.WithValue(ctx, RoleKey, role)
+	ctx = context.WithValue(ctx, OrgIDKey, models.DefaultOrgID)
+	rec := httptest.NewRecorder()
+	RBACMiddleware(database, table, action, next).ServeHTTP(rec, r.WithContext(ctx))
+	return rec.Code, got
+}
@@ -3,8 +3,12 @@ package middleware
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 
+	"rbac-backend/internal/apierror"
 	"rbac-backend/internal/db"
 	"rbac-backend/internal/models"
 	"rbac-backend/internal/rbac"
@@ -18,78 +22,118 @@ const (
 	TablePermKey contextKey = "tablePerm"
 )
 
-// fullAccessPerm returns a ResourcePermission that allows all table and field access (for ADMIN).
+// fullAccessPerm returns a ResourcePermission that allows all table and field access (for superuser roles).
 func fullAccessPerm() models.ResourcePermission {
 	return models.ResourcePermission{
-		View:   true,
-		Create: true,
-		Edit:   true,
-		Delete: true,
-		Fields: nil,
+		View:    true,
+		Create:  true,
+		Edit:    true,
+		Delete:  true,
+		Restore: true,
+		Purge:   true,
+		Fields:  nil,
 	}
 }
 
-// RBACMiddleware enforces config-driven RBAC: ADMIN has full access; other roles use DB config only.
+// ErrNoTableAccess is returned when the role has no rules for the table.
+var ErrNoTableAccess = errors.New("no table access")
+
+// ResolveTablePermission returns role's permission on table before any
+// elevation is applied: full access for a superuser role, otherwise the
+// table's entry in the role's effective permissions as returned by lookup.
+// RBACMiddleware and the policy test harness both resolve permissions
+// through it.
+func ResolveTablePermission(role string, superuser bool, table string, lookup func(role string) (models.Permissions, error)) (models.ResourcePermission, error) {
+	if superuser {
+		return fullAccessPerm(), nil
+	}
+	perms, err := lookup(role)
+	if err != nil {
+		return models.ResourcePermission{}, err
+	}
+	perm, ok := perms[table]
+	if !ok {
+		return models.ResourcePermission{}, ErrNoTableAccess
+	}
+	return perm, nil
+}
+
+// RBACMiddleware enforces config-driven RBAC: superuser roles (see the roles table) have full
+// access; other roles use the DB config of the caller's active organization only, including
+// for the users table.
+// Table-level decisions go through rbac.Evaluate so explicit denies take precedence over allows.
+// Approved, unexpired elevations are merged into the role's permissions; a request that is
+// only allowed because of an elevation is recorded in the audit log.
 func RBACMiddleware(database *sql.DB, table, action string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		roleVal := r.Context().Value(RoleKey)
 		if roleVal == nil {
-			http.Error(w, "role missing in context", http.StatusUnauthorized)
+			apierror.Error(w, "role missing in context", http.StatusUnauthorized)
 			return
 		}
 		role := roleVal.(string)
 
-		if table == "users" && role != rbac.RoleAdmin {
-			http.Error(w, "users table restricted to ADMIN", http.StatusForbidden)
+		userID, _ := r.Context().Value(UserIDKey).(string)
+		orgID, _ := r.Context().Value(OrgIDKey).(string)
+
+		superuser, err := db.IsSuperuser(database, role)
+		if err != nil {
+			apierror.Internal(w, "permission lookup failed", err)
 			return
 		}
+		grants, err := activeElevations(database, orgID, userID)
+		if err != nil {
+			apierror.Internal(w, "permission lookup failed", err)
+			return
+		}
+		superGrant, elevatedSuperuser := elevatedToSuperuser(database, grants)
 
 		var tablePerm models.ResourcePermission
 
-		if role == rbac.RoleAdmin {
+		if elevatedSuperuser && !superuser {
 			tablePerm = fullAccessPerm()
+			auditElevationUse(database, orgID, userID, superGrant.ID, table, action)
 		} else {
-			perms, err := db.GetPermissionsByRole(database, role)
-			if err != nil {
-				http.Error(w, "permission lookup failed", http.StatusForbidden)
+			basePerm, err := ResolveTablePermission(role, superuser, table, func(role string) (models.Permissions, error) {
+				return db.GetEffectivePermissions(database, orgID, role)
+			})
+			hasBase := err == nil
+			if err != nil && !errors.Is(err, ErrNoTableAccess) && len(grants) == 0 {
+				apierror.Internal(w, "permission lookup failed", err)
 				return
 			}
 
-			var ok bool
-			tablePerm, ok = perms[table]
-			if !ok {
-				http.Error(w, "no table access", http.StatusForbidden)
+			var applied []string
+			tablePerm, applied = applyElevations(database, orgID, basePerm, hasBase, table, grants)
+			if !hasBase && len(applied) == 0 {
+				apierror.Error(w, "no table access", http.StatusForbidden)
 				return
 			}
 
-			switch action {
-			case rbac.ActionView:
-				if !tablePerm.View {
-					http.Error(w, "view not allowed", http.StatusForbidden)
-					return
-				}
-			case rbac.ActionCreate:
-				if !tablePerm.Create {
-					http.Error(w, "create not allowed", http.StatusForbidden)
-					return
-				}
-			case rbac.ActionEdit:
-				if !tablePerm.Edit {
-					http.Error(w, "edit not allowed", http.StatusForbidden)
-					return
-				}
-			case rbac.ActionDelete:
-				if !tablePerm.Delete {
-					http.Error(w, "delete not allowed", http.StatusForbidden)
-					return
-				}
+			switch rbac.Evaluate(tablePerm, action, "") {
+			case rbac.EffectAllow:
+			case rbac.EffectDeny:
+				apierror.Error(w, action+" explicitly denied", http.StatusForbidden)
+				return
 			default:
-				http.Error(w, "unknown action", http.StatusForbidden)
+				apierror.Error(w, action+" not allowed", http.StatusForbidden)
 				return
 			}
+
+			if len(applied) > 0 && !rbac.Evaluate(basePerm, action, "").Allowed() {
+				auditElevationUse(database, orgID, userID, strings.Join(applied, ","), table, action)
+			}
 		}
 
 		ctx := context.WithValue(r.Context(), TablePermKey, tablePerm)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// auditElevationUse records that an elevation was needed to authorize a request.
+func auditElevationUse(database *sql.DB, orgID, userID, elevationIDs, table, action string) {
+	target := table + "." + action
+	if err := db.RecordAudit(database, orgID, userID, "elevation.use", target, elevationIDs); err != nil {
+		log.Printf("audit elevation use for %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+)
+
+func TestUsersEditorsKeepCreatingAccounts(t *testing.T) {
+	database := setupMiddlewareDB(t)
+	if err := db.SaveRole(database, models.Role{Name: "HR"}); err != nil {
+		t.Fatal(err)
+	}
+	hr := addMember(t, database, "HR")
+	// A config saved before creating checked users create: edit only.
+	perms := models.Permissions{rbac.TableUsers: {View: true, Edit: true}}
+	if _, err := db.UpdateRolePermissions(database, models.DefaultOrgID, "HR", perms, hr, ""); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := authorize(database, hr, "HR", rbac.TableUsers, rbac.ActionCreate); status != http.StatusForbidden {
+		t.Fatalf("users create before the upgrade: status %d, want 403", status)
+	}
+
+	upgrade, err := os.ReadFile("migrations/020_grant_users_create.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec(string(upgrade)); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := authorize(database, hr, "HR", rbac.TableUsers, rbac.ActionCreate); status != http.StatusOK {
+		t.Errorf("users create after the upgrade: status %d, want 200", status)
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+// Constraints is the separation-of-duties section of the RBAC config, stored
+// as a single JSON document in rbac_constraints.
+type Constraints struct {
+	// ExclusiveRoles lists sets of roles no single user may hold together,
+	// counting the assigned role and any active role elevation.
+	ExclusiveRoles []RoleConstraint `json:"exclusive_roles,omitempty"`
+	// Actions lists record-level actions refused when a condition matches.
+	Actions []ActionConstraint `json:"actions,omitempty"`
+}
+
+// RoleConstraint forbids holding more than one of Roles at the same time.
+type RoleConstraint struct {
+	ID      string   `json:"id"`
+	Roles   []string `json:"roles"`
+	Message string   `json:"message,omitempty"`
+}
+
+// ActionConstraint refuses Action on Table when DenyIf evaluates to true.
+// DenyIf uses the row-level condition language and additionally sees
+// `change`, the fields being written, e.g.
+// `record.created_by == user.id && change.status == "DONE"`.
+type ActionConstraint struct {
+	ID      string `json:"id"`
+	Table   string `json:"table"`
+	Action  string `json:"action"`
+	DenyIf  string `json:"deny_if"`
+	Message string `json:"message,omitempty"`
+}
+
+// ConstraintViolation describes which constraint refused an operation.
+type ConstraintViolation struct {
+	Constraint string   `json:"constraint"`
+	Message    string   `json:"message"`
+	Roles      []string `json:"roles,omitempty"`
+}
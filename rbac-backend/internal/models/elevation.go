@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Elevation statuses.
+const (
+	ElevationPending  = "PENDING"
+	ElevationApproved = "APPROVED"
+	ElevationDenied   = "DENIED"
+	ElevationRevoked  = "REVOKED"
+	ElevationExpired  = "EXPIRED"
+)
+
+// Elevation is a time-bound privilege grant: either a whole role (Role) or a
+// single table action (Table + Action). It becomes active when approved and
+// stops applying at ExpiresAt.
+type Elevation struct {
+	ID              string     `json:"id"`
+	OrgID           string     `json:"org_id"`
+	UserID          string     `json:"user_id"`
+	Role            string     `json:"role,omitempty"`
+	Table           string     `json:"table,omitempty"`
+	Action          string     `json:"action,omitempty"`
+	Justification   string     `json:"justification"`
+	DurationMinutes int        `json:"duration_minutes"`
+	Status          string     `json:"status"`
+	ApprovedBy      string     `json:"approved_by,omitempty"`
+	RequestedAt     time.Time  `json:"requested_at"`
+	ApprovedAt      *time.Time `json:"approved_at,omitempty"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	ClosedAt        *time.Time `json:"closed_at,omitempty"`
+}
+
+// AuditEntry is one row of the audit log.
+type AuditEntry struct {
+	ID        string    `json:"id"`
+	ActorID   string    `json:"actor_id"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
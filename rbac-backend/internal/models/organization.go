@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// DefaultOrgID is the organization created by the migrations. Data that
+// existed before organizations were introduced belongs to it, and settings
+// shared by every organization (role catalogue, inheritance, constraints,
+// new organizations) can only be changed from it.
+const DefaultOrgID = "default"
+
+// Organization is a tenant. Projects, tasks, elevations, audit entries and
+// role permissions belong to exactly one organization.
+type Organization struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Membership is a user's role in one organization.
+type Membership struct {
+	OrgID   string `json:"org_id"`
+	OrgName string `json:"org_name"`
+	UserID  string `json:"user_id"`
+	Role    string `json:"role"`
+}
+
+// Invitation asks the account with a given email to join OrgID with Role. It
+// becomes a membership only when that account accepts it.
+type Invitation struct {
+	ID        string    `json:"id"`
+	OrgID     string    `json:"org_id"`
+	OrgName   string    `json:"org_name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -1,23 +1,140 @@
 // internal/models/permission.go
 package models
 
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
 // FieldPermission defines field-level access (view / create / edit).
 // Used in JSON config in role_permissions.permissions.
+// Deny lists actions that are explicitly refused for the field and always
+// win over an allow (see rbac.Evaluate for the full precedence order).
+// Redact, when set, transforms the value of a viewable field in responses
+// ("mask", "hash", "truncate", "truncate:N" or "null"; see utils.Redact).
 type FieldPermission struct {
-	View   bool `json:"view"`
-	Create bool `json:"create"`
-	Edit   bool `json:"edit"`
+	View   bool     `json:"view"`
+	Create bool     `json:"create"`
+	Edit   bool     `json:"edit"`
+	Deny   []string `json:"deny,omitempty"`
+	Redact string   `json:"redact,omitempty"`
 }
 
 // ResourcePermission defines table-level and optional field-level permissions.
-// Permissions for other roles come ONLY from DB; ADMIN is handled in code.
+// Permissions come ONLY from DB; superuser roles bypass them in code.
+// Deny lists table actions that are explicitly refused regardless of any allow.
+// Delete moves records to the trash; Restore and Purge act on the trash.
+//
+// In JSON each table action is either a bool or a conditional grant of the
+// form {"if": "<expression>"}; a conditional grant sets the flag and records
+// the expression in Conditions, keyed by action.
 type ResourcePermission struct {
-	View   bool                       `json:"view"`
-	Create bool                       `json:"create"`
-	Edit   bool                       `json:"edit"`
-	Delete bool                       `json:"delete"`
-	Fields map[string]FieldPermission `json:"fields,omitempty"`
+	View       bool                       `json:"view"`
+	Create     bool                       `json:"create"`
+	Edit       bool                       `json:"edit"`
+	Delete     bool                       `json:"delete"`
+	Restore    bool                       `json:"restore"`
+	Purge      bool                       `json:"purge"`
+	Deny       []string                   `json:"deny,omitempty"`
+	Fields     map[string]FieldPermission `json:"fields,omitempty"`
+	Conditions map[string]string          `json:"-"`
 }
 
 // Permissions is keyed by table/resource name (e.g. "projects", "users").
 type Permissions map[string]ResourcePermission
+
+// actionValue is the JSON form of a table action: a bool or {"if": "..."}.
+type actionValue struct {
+	Allowed bool
+	If      string
+}
+
+func (a *actionValue) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		return nil
+	}
+	var cond struct {
+		If string `json:"if"`
+	}
+	if err := json.Unmarshal(data, &cond); err != nil {
+		return errors.New("action must be a bool or an {\"if\": ...} object")
+	}
+	if cond.If == "" {
+		return errors.New("conditional action requires a non-empty \"if\"")
+	}
+	a.Allowed = true
+	a.If = cond.If
+	return nil
+}
+
+func (a actionValue) MarshalJSON() ([]byte, error) {
+	if a.If != "" {
+		return json.Marshal(map[string]string{"if": a.If})
+	}
+	return json.Marshal(a.Allowed)
+}
+
+type resourcePermissionJSON struct {
+	View    actionValue                `json:"view"`
+	Create  actionValue                `json:"create"`
+	Edit    actionValue                `json:"edit"`
+	Delete  actionValue                `json:"delete"`
+	Restore actionValue                `json:"restore"`
+	Purge   actionValue                `json:"purge"`
+	Deny    []string                   `json:"deny,omitempty"`
+	Fields  map[string]FieldPermission `json:"fields,omitempty"`
+}
+
+func (p *ResourcePermission) UnmarshalJSON(data []byte) error {
+	var raw resourcePermissionJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*p = ResourcePermission{
+		View:    raw.View.Allowed,
+		Create:  raw.Create.Allowed,
+		Edit:    raw.Edit.Allowed,
+		Delete:  raw.Delete.Allowed,
+		Restore: raw.Restore.Allowed,
+		Purge:   raw.Purge.Allowed,
+		Deny:    raw.Deny,
+		Fields:  raw.Fields,
+	}
+	for action, v := range map[string]actionValue{
+		"view": raw.View, "create": raw.Create, "edit": raw.Edit, "delete": raw.Delete,
+		"restore": raw.Restore, "purge": raw.Purge,
+	} {
+		if v.If == "" {
+			continue
+		}
+		if p.Conditions == nil {
+			p.Conditions = make(map[string]string)
+		}
+		p.Conditions[action] = v.If
+	}
+	return nil
+}
+
+func (p ResourcePermission) MarshalJSON() ([]byte, error) {
+	return json.Marshal(resourcePermissionJSON{
+		View:    actionValue{Allowed: p.View, If: p.Conditions["view"]},
+		Create:  actionValue{Allowed: p.Create, If: p.Conditions["create"]},
+		Edit:    actionValue{Allowed: p.Edit, If: p.Conditions["edit"]},
+		Delete:  actionValue{Allowed: p.Delete, If: p.Conditions["delete"]},
+		Restore: actionValue{Allowed: p.Restore, If: p.Conditions["restore"]},
+		Purge:   actionValue{Allowed: p.Purge, If: p.Conditions["purge"]},
+		Deny:    p.Deny,
+		Fields:  p.Fields,
+	})
+}
+
+// RolePermissionVersion is one saved revision of a role's permissions.
+type RolePermissionVersion struct {
+	Role        string      `json:"role"`
+	Version     int         `json:"version"`
+	Permissions Permissions `json:"permissions"`
+	Author      string      `json:"author"`
+	Comment     string      `json:"comment,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
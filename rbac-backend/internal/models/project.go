@@ -1,9 +1,16 @@
 package models
 
+import "time"
+
 type Project struct {
 	ID                string   `json:"id"`
 	Name              string   `json:"name"`
 	Description       string   `json:"description"`
 	CreatedBy         string   `json:"created_by"`
 	AssignedEmployees []string `json:"assigned_employees,omitempty"`
+	// Version counts the project's updates; its ETag is derived from it.
+	Version int `json:"version"`
+	// DeletedAt and DeletedBy are set while the project is in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy string     `json:"deleted_by,omitempty"`
 }
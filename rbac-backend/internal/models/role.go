@@ -0,0 +1,9 @@
+package models
+
+// Role is an entry in the role catalogue. A superuser role has full access to
+// every table regardless of permission config.
+type Role struct {
+	Name        string `json:"name"`
+	Superuser   bool   `json:"superuser"`
+	Description string `json:"description"`
+}
@@ -15,4 +15,9 @@ type Task struct {
 	CompletedAt *time.Time `json:"completed_at,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
+	// Version counts the task's updates; its ETag is derived from it.
+	Version int `json:"version"`
+	// DeletedAt and DeletedBy are set while the task is in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy string     `json:"deleted_by,omitempty"`
 }
@@ -0,0 +1,226 @@
+// Package policy implements policy-as-code: a YAML/JSON bundle describing
+// role permissions, inheritance and separation-of-duties constraints that can
+// be exported from and imported into the database.
+package policy
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+)
+
+// BundleVersion is the bundle format version written by Export.
+const BundleVersion = 1
+
+// Bundle is the policy-as-code document. Its JSON field names are also its
+// YAML keys, so both formats share one schema.
+type Bundle struct {
+	Version     int                   `json:"version"`
+	Roles       map[string]RolePolicy `json:"roles"`
+	Constraints *models.Constraints   `json:"constraints,omitempty"`
+}
+
+// RolePolicy is one role's entry in a bundle. A superuser role has full
+// access regardless of its permissions.
+type RolePolicy struct {
+	Superuser   bool               `json:"superuser,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Inherits    []string           `json:"inherits,omitempty"`
+	Permissions models.Permissions `json:"permissions"`
+}
+
+// Parse decodes a bundle in YAML or JSON (JSON is valid YAML).
+func Parse(data []byte) (Bundle, error) {
+	var generic interface{}
+	if err := yaml.Unmarshal(data, &generic); err != nil {
+		return Bundle{}, err
+	}
+	// Round-trip through JSON so the custom JSON decoding of permissions
+	// (e.g. {"if": ...} conditions) applies to YAML input too.
+	raw, err := json.Marshal(generic)
+	if err != nil {
+		return Bundle{}, err
+	}
+	var b Bundle
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return Bundle{}, err
+	}
+	if b.Version != BundleVersion {
+		return Bundle{}, fmt.Errorf("unsupported bundle version %d", b.Version)
+	}
+	return b, nil
+}
+
+// Encode renders b as "yaml" or "json".
+func Encode(b Bundle, format string) ([]byte, error) {
+	raw, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "json":
+		return append(raw, '\n'), nil
+	case "yaml", "":
+		// JSON is YAML, so decoding it into a node keeps the field order;
+		// clearing the flow style makes the output block YAML.
+		var node yaml.Node
+		if err := yaml.Unmarshal(raw, &node); err != nil {
+			return nil, err
+		}
+		blockStyle(&node)
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(&node); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), enc.Close()
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+func blockStyle(n *yaml.Node) {
+	n.Style &^= yaml.FlowStyle | yaml.DoubleQuotedStyle
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+// Export reads the current policy from the database: every role in the
+// catalogue with its flag, inheritance and permissions in orgID, and the
+// constraints.
+func Export(database *sql.DB, orgID string) (Bundle, error) {
+	b := Bundle{Version: BundleVersion, Roles: map[string]RolePolicy{}}
+
+	roles, err := db.ListRoleDefinitions(database)
+	if err != nil {
+		return b, err
+	}
+	for _, role := range roles {
+		perms, err := db.GetPermissionsByRole(database, orgID, role.Name)
+		if err != nil && err != sql.ErrNoRows {
+			return b, err
+		}
+		if perms == nil {
+			perms = models.Permissions{}
+		}
+		parents, err := db.GetRoleParents(database, role.Name)
+		if err != nil {
+			return b, err
+		}
+		b.Roles[role.Name] = RolePolicy{
+			Superuser:   role.Superuser,
+			Description: role.Description,
+			Inherits:    parents,
+			Permissions: perms,
+		}
+	}
+
+	c, err := db.GetConstraints(database)
+	if err != nil {
+		return b, err
+	}
+	b.Constraints = &c
+	return b, nil
+}
+
+// Validate checks a bundle before it is planned or applied: role names must
+// be valid, permissions must pass rbac.ValidatePermissions, inherited roles
+// must exist in the bundle without cycles, at least one role must be a
+// superuser, and constraints must compile.
+func Validate(b Bundle) []rbac.Diagnostic {
+	var diags []rbac.Diagnostic
+	add := func(path, format string, args ...interface{}) {
+		diags = append(diags, rbac.Diagnostic{Severity: rbac.SeverityError, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, role := range sortedRoles(b.Roles) {
+		rp := b.Roles[role]
+		if !rbac.ValidRoleName(role) {
+			add("roles."+role, "invalid role name %q", role)
+			continue
+		}
+		for _, d := range rbac.ValidatePermissions(rp.Permissions).Errors {
+			d.Path = "roles." + role + ".permissions." + d.Path
+			diags = append(diags, d)
+		}
+		for _, parent := range rp.Inherits {
+			if _, ok := b.Roles[parent]; !ok {
+				add("roles."+role+".inherits", "inherited role %q is not defined in the bundle", parent)
+			}
+		}
+		if inheritsFrom(b.Roles, role, role, map[string]bool{}) {
+			add("roles."+role+".inherits", "inheritance cycle through %q", role)
+		}
+	}
+
+	hasSuperuser := false
+	for _, rp := range b.Roles {
+		hasSuperuser = hasSuperuser || rp.Superuser
+	}
+	if !hasSuperuser {
+		add("roles", "at least one role must be a superuser")
+	}
+
+	if b.Constraints != nil {
+		if err := rbac.ValidateConstraints(*b.Constraints); err != nil {
+			add("constraints", "%v", err)
+		}
+	}
+	return diags
+}
+
+func inheritsFrom(roles map[string]RolePolicy, from, target string, seen map[string]bool) bool {
+	for _, parent := range roles[from].Inherits {
+		if parent == target {
+			return true
+		}
+		if seen[parent] {
+			continue
+		}
+		seen[parent] = true
+		if inheritsFrom(roles, parent, target, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedRoles(m map[string]RolePolicy) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// EffectivePermissions resolves role's permissions within the bundle,
+// including inherited ones, the same way db.GetEffectivePermissions does.
+func (b Bundle) EffectivePermissions(role string) (models.Permissions, error) {
+	if _, ok := b.Roles[role]; !ok {
+		return nil, fmt.Errorf("role %q is not defined in the bundle", role)
+	}
+	return rbac.ResolveInherited(role, func(r string) (models.Permissions, error) {
+		return b.Roles[r].Permissions, nil
+	}, func(r string) ([]string, error) {
+		return b.Roles[r].Inherits, nil
+	})
+}
+
+// IsSuperuser reports whether role is a superuser role in the bundle.
+func (b Bundle) IsSuperuser(role string) (bool, error) {
+	rp, ok := b.Roles[role]
+	if !ok {
+		return false, fmt.Errorf("role %q is not defined in the bundle", role)
+	}
+	return rp.Superuser, nil
+}
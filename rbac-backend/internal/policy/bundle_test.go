@@ -0,0 +1,75 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+const testBundle = `
+version: 1
+roles:
+  ADMIN:
+    superuser: true
+    permissions: {}
+  EDITOR:
+    permissions:
+      tasks:
+        view: true
+        edit:
+          if: user.id in record.assignees
+  VIEWER:
+    inherits: [EDITOR]
+    permissions:
+      tasks:
+        view: true
+`
+
+func TestParseYAMLRoundTrip(t *testing.T) {
+	b, err := Parse([]byte(testBundle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	editor := b.Roles["EDITOR"].Permissions["tasks"]
+	if !editor.Edit || editor.Conditions["edit"] != "user.id in record.assignees" {
+		t.Fatalf("conditional edit not parsed: %+v", editor)
+	}
+	if diags := Validate(b); len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics: %+v", diags)
+	}
+
+	for _, format := range []string{"yaml", "json"} {
+		data, err := Encode(b, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := Parse(data)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if again.Roles["EDITOR"].Permissions["tasks"].Conditions["edit"] != editor.Conditions["edit"] {
+			t.Fatalf("%s round trip lost the condition:\n%s", format, data)
+		}
+	}
+}
+
+func TestValidateRejectsInheritanceCycle(t *testing.T) {
+	b, err := Parse([]byte(strings.Replace(testBundle, "  EDITOR:\n", "  EDITOR:\n    inherits: [VIEWER]\n", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := Validate(b)
+	if len(diags) == 0 || !strings.Contains(diags[0].Message, "cycle") {
+		t.Fatalf("expected cycle diagnostic, got %+v", diags)
+	}
+}
+
+func TestValidateRequiresASuperuser(t *testing.T) {
+	b, err := Parse([]byte(strings.Replace(testBundle, "superuser: true", "superuser: false", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := Validate(b)
+	if len(diags) != 1 || diags[0].Path != "roles" {
+		t.Fatalf("expected a missing superuser diagnostic, got %+v", diags)
+	}
+}
@@ -0,0 +1,159 @@
+package policy
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/middleware"
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+	"rbac-backend/internal/utils"
+)
+
+// Expected outcomes of a test case.
+const (
+	ExpectAllow = "allow"
+	ExpectDeny  = "deny"
+)
+
+// Case is one policy assertion, e.g. "VIEWER cannot view tasks.created_by".
+// Field is optional; without it the case checks the table action only.
+type Case struct {
+	Name   string `json:"name,omitempty"`
+	Role   string `json:"role"`
+	Table  string `json:"table"`
+	Action string `json:"action"`
+	Field  string `json:"field,omitempty"`
+	Expect string `json:"expect"`
+}
+
+func (c Case) String() string {
+	target := c.Table
+	if c.Field != "" {
+		target += "." + c.Field
+	}
+	s := fmt.Sprintf("%s %s %s", c.Role, c.Action, target)
+	if c.Name != "" {
+		s = c.Name + " (" + s + ")"
+	}
+	return s
+}
+
+// Result is the outcome of running one Case.
+type Result struct {
+	Case   Case
+	Got    string
+	Reason string
+	Err    error
+}
+
+// Passed reports whether the case got its expected outcome.
+func (r Result) Passed() bool {
+	return r.Err == nil && r.Got == r.Case.Expect
+}
+
+// LoadCases decodes a YAML or JSON file holding {"cases": [...]}.
+func LoadCases(data []byte) ([]Case, error) {
+	var generic interface{}
+	if err := yaml.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(generic)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Cases []Case `json:"cases"`
+	}
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, err
+	}
+	for i, c := range file.Cases {
+		if c.Role == "" || c.Table == "" || c.Action == "" {
+			return nil, fmt.Errorf("case %d: role, table and action are required", i+1)
+		}
+		if c.Expect != ExpectAllow && c.Expect != ExpectDeny {
+			return nil, fmt.Errorf("case %d: expect must be %q or %q", i+1, ExpectAllow, ExpectDeny)
+		}
+	}
+	return file.Cases, nil
+}
+
+// Source provides the role data a Case is decided against: a Bundle, or
+// DBSource for the live database.
+type Source interface {
+	EffectivePermissions(role string) (models.Permissions, error)
+	IsSuperuser(role string) (bool, error)
+}
+
+// DBSource reads roles from the database the same way RBACMiddleware does,
+// with permissions from organization OrgID.
+type DBSource struct {
+	DB    *sql.DB
+	OrgID string
+}
+
+func (s DBSource) EffectivePermissions(role string) (models.Permissions, error) {
+	return db.GetEffectivePermissions(s.DB, s.OrgID, role)
+}
+
+func (s DBSource) IsSuperuser(role string) (bool, error) {
+	return db.IsSuperuser(s.DB, role)
+}
+
+// RunCases evaluates every case against src. Elevations are not considered.
+func RunCases(cases []Case, src Source) []Result {
+	results := make([]Result, 0, len(cases))
+	for _, c := range cases {
+		got, reason, err := decide(c, src)
+		results = append(results, Result{Case: c, Got: got, Reason: reason, Err: err})
+	}
+	return results
+}
+
+// decide mirrors a request: RBACMiddleware's table check first, then the
+// handler's field filtering (utils.FilterFields for view,
+// utils.FilterEditableFields for create and edit).
+func decide(c Case, src Source) (string, string, error) {
+	superuser, err := src.IsSuperuser(c.Role)
+	if err != nil {
+		return "", "", err
+	}
+	perm, err := middleware.ResolveTablePermission(c.Role, superuser, c.Table, src.EffectivePermissions)
+	switch {
+	case errors.Is(err, middleware.ErrNoTableAccess):
+		return ExpectDeny, err.Error(), nil
+	case err != nil:
+		return "", "", err
+	}
+
+	switch rbac.Evaluate(perm, c.Action, "") {
+	case rbac.EffectDeny:
+		return ExpectDeny, c.Action + " explicitly denied", nil
+	case rbac.EffectDefaultDeny:
+		return ExpectDeny, c.Action + " not allowed", nil
+	}
+
+	if c.Field != "" {
+		row := map[string]interface{}{c.Field: nil}
+		var kept map[string]interface{}
+		if c.Action == rbac.ActionView {
+			kept = utils.FilterFields(row, perm.Fields)
+		} else {
+			kept = utils.FilterEditableFields(row, perm.Fields)
+		}
+		if _, ok := kept[c.Field]; !ok {
+			return ExpectDeny, "field filtered out", nil
+		}
+	}
+
+	if cond := perm.Conditions[c.Action]; cond != "" {
+		return ExpectAllow, "only records where " + cond, nil
+	}
+	return ExpectAllow, "", nil
+}
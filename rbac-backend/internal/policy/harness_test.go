@@ -0,0 +1,28 @@
+package policy
+
+import "testing"
+
+func TestRunCasesAgainstBundle(t *testing.T) {
+	b, err := Parse([]byte(testBundle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []Case{
+		{Role: "VIEWER", Table: "tasks", Action: "edit", Expect: ExpectAllow}, // inherited from EDITOR
+		{Role: "VIEWER", Table: "tasks", Action: "delete", Expect: ExpectDeny},
+		{Role: "VIEWER", Table: "projects", Action: "view", Expect: ExpectDeny},
+		{Role: "VIEWER", Table: "users", Action: "view", Expect: ExpectDeny},
+		{Role: "ADMIN", Table: "users", Action: "delete", Expect: ExpectAllow},
+		{Role: "EDITOR", Table: "tasks", Action: "view", Field: "title", Expect: ExpectAllow},
+	}
+	for _, res := range RunCases(cases, b) {
+		if !res.Passed() {
+			t.Errorf("%s: expected %s, got %s (%s, err=%v)", res.Case, res.Case.Expect, res.Got, res.Reason, res.Err)
+		}
+	}
+
+	res := RunCases([]Case{{Role: "MANAGER", Table: "tasks", Action: "view", Expect: ExpectDeny}}, b)
+	if res[0].Err == nil {
+		t.Fatal("expected an error for a role missing from the bundle")
+	}
+}
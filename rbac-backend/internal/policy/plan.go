@@ -0,0 +1,217 @@
+package policy
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+)
+
+// Plan actions for a role.
+const (
+	PlanCreate    = "create"
+	PlanUpdate    = "update"
+	PlanUnchanged = "unchanged"
+	PlanUnmanaged = "unmanaged"
+)
+
+// RolePlan describes what importing a bundle would do to one role.
+type RolePlan struct {
+	Role            string        `json:"role"`
+	Action          string        `json:"action"`
+	Changes         []rbac.Change `json:"changes,omitempty"`
+	InheritsFrom    []string      `json:"inherits_from,omitempty"`
+	InheritsTo      []string      `json:"inherits_to,omitempty"`
+	InheritsChanged bool          `json:"inherits_changed,omitempty"`
+	SettingsChanged bool          `json:"settings_changed,omitempty"`
+	Superuser       bool          `json:"superuser"`
+}
+
+// Plan is the full set of changes an import would make.
+type Plan struct {
+	Roles              []RolePlan `json:"roles"`
+	ConstraintsChanged bool       `json:"constraints_changed"`
+}
+
+// HasChanges reports whether applying the plan would modify anything.
+func (p Plan) HasChanges() bool {
+	if p.ConstraintsChanged {
+		return true
+	}
+	for _, r := range p.Roles {
+		if r.Action == PlanCreate || r.Action == PlanUpdate {
+			return true
+		}
+	}
+	return false
+}
+
+// MakePlan compares b with the database as seen from orgID. Roles present in
+// the database but not in the bundle are reported as unmanaged and left
+// untouched.
+func MakePlan(database *sql.DB, orgID string, b Bundle) (Plan, error) {
+	current, err := Export(database, orgID)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	var plan Plan
+	for _, role := range sortedRoles(b.Roles) {
+		want := b.Roles[role]
+		have, exists := current.Roles[role]
+
+		rp := RolePlan{Role: role, Action: PlanUnchanged, Superuser: want.Superuser}
+		if !exists {
+			rp.Action = PlanCreate
+			rp.Changes = rbac.DiffPermissions(nil, want.Permissions)
+			rp.InheritsTo = sortedCopy(want.Inherits)
+			rp.InheritsChanged = len(want.Inherits) > 0
+			rp.SettingsChanged = true
+		} else {
+			rp.Changes = rbac.DiffPermissions(have.Permissions, want.Permissions)
+			if !sameSet(have.Inherits, want.Inherits) {
+				rp.InheritsChanged = true
+				rp.InheritsFrom = sortedCopy(have.Inherits)
+				rp.InheritsTo = sortedCopy(want.Inherits)
+			}
+			rp.SettingsChanged = have.Superuser != want.Superuser || have.Description != want.Description
+			if len(rp.Changes) > 0 || rp.InheritsChanged || rp.SettingsChanged {
+				rp.Action = PlanUpdate
+			}
+		}
+		plan.Roles = append(plan.Roles, rp)
+	}
+	for _, role := range sortedRoles(current.Roles) {
+		if _, ok := b.Roles[role]; !ok {
+			plan.Roles = append(plan.Roles, RolePlan{Role: role, Action: PlanUnmanaged})
+		}
+	}
+
+	if b.Constraints != nil {
+		plan.ConstraintsChanged = !reflect.DeepEqual(normalizeConstraints(*current.Constraints), normalizeConstraints(*b.Constraints))
+	}
+	return plan, nil
+}
+
+// ErrSharedSettings is returned by Apply when a plan for an organization other
+// than the default one would change the role catalogue, inheritance or
+// constraints, which all organizations share.
+var ErrSharedSettings = errors.New("roles, inheritance and constraints can only be changed in the default organization")
+
+// Apply writes the changes in plan from b to the database, with permissions
+// going to orgID. Permission changes go through db.UpdateRolePermissions so
+// they are recorded as new versions.
+func Apply(database *sql.DB, orgID string, b Bundle, plan Plan, author string) error {
+	if orgID != models.DefaultOrgID && plan.changesShared() {
+		return ErrSharedSettings
+	}
+	for _, rp := range plan.Roles {
+		if rp.Action != PlanCreate && rp.Action != PlanUpdate {
+			continue
+		}
+		want := b.Roles[rp.Role]
+		if rp.SettingsChanged {
+			role := models.Role{Name: rp.Role, Superuser: want.Superuser, Description: want.Description}
+			if err := db.SaveRole(database, role); err != nil {
+				return fmt.Errorf("%s: %w", rp.Role, err)
+			}
+		}
+		if len(rp.Changes) > 0 || rp.Action == PlanCreate {
+			if _, err := db.UpdateRolePermissions(database, orgID, rp.Role, want.Permissions, author, "policy import"); err != nil {
+				return fmt.Errorf("%s: %w", rp.Role, err)
+			}
+		}
+		if rp.InheritsChanged {
+			if err := db.SetRoleParents(database, rp.Role, want.Inherits); err != nil {
+				return fmt.Errorf("%s inheritance: %w", rp.Role, err)
+			}
+		}
+	}
+	if plan.ConstraintsChanged {
+		if err := db.UpdateConstraints(database, *b.Constraints); err != nil {
+			return fmt.Errorf("constraints: %w", err)
+		}
+	}
+	return nil
+}
+
+// changesShared reports whether the plan touches settings shared by every
+// organization.
+func (p Plan) changesShared() bool {
+	if p.ConstraintsChanged {
+		return true
+	}
+	for _, r := range p.Roles {
+		if r.SettingsChanged || r.InheritsChanged {
+			return true
+		}
+	}
+	return false
+}
+
+// WritePlan prints plan in a human-readable form.
+func WritePlan(w io.Writer, plan Plan) {
+	for _, rp := range plan.Roles {
+		switch rp.Action {
+		case PlanUnchanged:
+			fmt.Fprintf(w, "  %s: no changes\n", rp.Role)
+			continue
+		case PlanUnmanaged:
+			fmt.Fprintf(w, "  %s: not in bundle, left unchanged\n", rp.Role)
+			continue
+		}
+		fmt.Fprintf(w, "~ %s (%s)\n", rp.Role, rp.Action)
+		if rp.SettingsChanged {
+			fmt.Fprintf(w, "    superuser: %t\n", rp.Superuser)
+		}
+		if rp.InheritsChanged {
+			fmt.Fprintf(w, "    inherits: [%s] -> [%s]\n", strings.Join(rp.InheritsFrom, ", "), strings.Join(rp.InheritsTo, ", "))
+		}
+		for _, c := range rp.Changes {
+			switch c.Kind {
+			case rbac.ChangeAdded:
+				fmt.Fprintf(w, "    + %s = %v\n", c.Path, c.To)
+			case rbac.ChangeRemoved:
+				fmt.Fprintf(w, "    - %s (was %v)\n", c.Path, c.From)
+			default:
+				fmt.Fprintf(w, "    ~ %s: %v -> %v\n", c.Path, c.From, c.To)
+			}
+		}
+	}
+	if plan.ConstraintsChanged {
+		fmt.Fprintln(w, "~ constraints (update)")
+	}
+	if !plan.HasChanges() {
+		fmt.Fprintln(w, "No changes.")
+	}
+}
+
+func normalizeConstraints(c models.Constraints) models.Constraints {
+	if len(c.ExclusiveRoles) == 0 {
+		c.ExclusiveRoles = nil
+	}
+	if len(c.Actions) == 0 {
+		c.Actions = nil
+	}
+	return c
+}
+
+func sameSet(a, b []string) bool {
+	return reflect.DeepEqual(sortedCopy(a), sortedCopy(b))
+}
+
+func sortedCopy(in []string) []string {
+	if len(in) == 0 {
+		return nil
+	}
+	out := append([]string(nil), in...)
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,82 @@
+package rbac
+
+import "rbac-backend/internal/models"
+
+// Access change kinds reported by CompareAccess.
+const (
+	AccessGained           = "gained"
+	AccessLost             = "lost"
+	AccessConditionChanged = "condition_changed"
+)
+
+// AccessChange is one decision that differs between two permission sets.
+// Field is empty for table actions. For AccessConditionChanged, From and To
+// hold the row-level conditions ("" means unconditional).
+type AccessChange struct {
+	Table  string `json:"table"`
+	Action string `json:"action"`
+	Field  string `json:"field,omitempty"`
+	Change string `json:"change"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+}
+
+// CompareAccess reports the table actions and field actions whose outcome
+// under Evaluate differs between before and after. Unlike DiffPermissions it
+// compares decisions, not rules: rewriting a rule without changing what it
+// allows is not reported. A field action counts as allowed only when the
+// table allows the same action.
+func CompareAccess(before, after models.Permissions) []AccessChange {
+	tables := map[string]bool{}
+	for t := range before {
+		tables[t] = true
+	}
+	for t := range after {
+		tables[t] = true
+	}
+
+	changes := []AccessChange{}
+	for _, table := range sortedKeys(tables) {
+		b, a := before[table], after[table]
+		for _, action := range TableActions {
+			bAllowed, aAllowed := Evaluate(b, action, "").Allowed(), Evaluate(a, action, "").Allowed()
+			switch {
+			case bAllowed != aAllowed:
+				changes = append(changes, AccessChange{Table: table, Action: action, Change: accessKind(aAllowed)})
+			case aAllowed && b.Conditions[action] != a.Conditions[action]:
+				changes = append(changes, AccessChange{
+					Table: table, Action: action, Change: AccessConditionChanged,
+					From: b.Conditions[action], To: a.Conditions[action],
+				})
+			}
+		}
+
+		fields := map[string]bool{}
+		for _, f := range Registry[table] {
+			fields[f] = true
+		}
+		for f := range b.Fields {
+			fields[f] = true
+		}
+		for f := range a.Fields {
+			fields[f] = true
+		}
+		for _, field := range sortedKeys(fields) {
+			for _, action := range FieldActions {
+				bAllowed := Evaluate(b, action, "").Allowed() && Evaluate(b, action, field).Allowed()
+				aAllowed := Evaluate(a, action, "").Allowed() && Evaluate(a, action, field).Allowed()
+				if bAllowed != aAllowed {
+					changes = append(changes, AccessChange{Table: table, Action: action, Field: field, Change: accessKind(aAllowed)})
+				}
+			}
+		}
+	}
+	return changes
+}
+
+func accessKind(allowed bool) string {
+	if allowed {
+		return AccessGained
+	}
+	return AccessLost
+}
@@ -0,0 +1,426 @@
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Condition is a compiled row-level rule such as
+// `record.created_by == user.id || user.id in record.assignees`.
+//
+// The language is deliberately small: dotted paths into the evaluation
+// environment, string/number/true/false/null literals, ==, !=, in, !, &&, ||
+// and parentheses. It has no function calls and no assignment, so a condition
+// stored in role config can only read the values it is given.
+type Condition struct {
+	src  string
+	root node
+}
+
+// ParseCondition compiles src into a Condition.
+func ParseCondition(src string) (*Condition, error) {
+	p := &condParser{lex: condLexer{src: src}}
+	p.next()
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("condition %q: %w", src, err)
+	}
+	if p.tok.kind != tokEOF {
+		return nil, fmt.Errorf("condition %q: unexpected %q", src, p.tok.text)
+	}
+	return &Condition{src: src, root: root}, nil
+}
+
+// String returns the source of the condition.
+func (c *Condition) String() string {
+	return c.src
+}
+
+// Eval evaluates the condition against env; the result must be a boolean.
+func (c *Condition) Eval(env map[string]interface{}) (bool, error) {
+	v, err := c.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition %q: result is not a boolean", c.src)
+	}
+	return b, nil
+}
+
+var conditionCache sync.Map
+
+// compileCondition parses src once and reuses the result for later calls.
+func compileCondition(src string) (*Condition, error) {
+	if c, ok := conditionCache.Load(src); ok {
+		return c.(*Condition), nil
+	}
+	c, err := ParseCondition(src)
+	if err != nil {
+		return nil, err
+	}
+	conditionCache.Store(src, c)
+	return c, nil
+}
+
+// ---- AST ----
+
+type node interface {
+	eval(env map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct{ value interface{} }
+
+func (n literalNode) eval(map[string]interface{}) (interface{}, error) { return n.value, nil }
+
+type pathNode struct{ parts []string }
+
+func (n pathNode) eval(env map[string]interface{}) (interface{}, error) {
+	var cur interface{} = env
+	for _, part := range n.parts {
+		m, ok := asMap(cur)
+		if !ok {
+			return nil, nil
+		}
+		cur = m[part]
+	}
+	return cur, nil
+}
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(env map[string]interface{}) (interface{}, error) {
+	v, err := evalBool(n.operand, env)
+	if err != nil {
+		return nil, err
+	}
+	return !v, nil
+}
+
+type logicalNode struct {
+	and         bool
+	left, right node
+}
+
+func (n logicalNode) eval(env map[string]interface{}) (interface{}, error) {
+	l, err := evalBool(n.left, env)
+	if err != nil {
+		return nil, err
+	}
+	if n.and && !l {
+		return false, nil
+	}
+	if !n.and && l {
+		return true, nil
+	}
+	return evalBool(n.right, env)
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) eval(env map[string]interface{}) (interface{}, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return valuesEqual(l, r), nil
+	case "!=":
+		return !valuesEqual(l, r), nil
+	case "in":
+		return contains(r, l), nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", n.op)
+}
+
+func evalBool(n node, env map[string]interface{}) (bool, error) {
+	v, err := n.eval(env)
+	if err != nil {
+		return false, err
+	}
+	switch b := v.(type) {
+	case bool:
+		return b, nil
+	case nil:
+		return false, nil
+	}
+	return false, errors.New("operand is not a boolean")
+}
+
+func asMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[string]string:
+		out := make(map[string]interface{}, len(m))
+		for k, s := range m {
+			out[k] = s
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+func normalize(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float32:
+		return float64(n)
+	case *string:
+		if n == nil {
+			return nil
+		}
+		return *n
+	}
+	return v
+}
+
+func valuesEqual(a, b interface{}) bool {
+	a, b = normalize(a), normalize(b)
+	switch a.(type) {
+	case nil, string, float64, bool:
+	default:
+		return false
+	}
+	return a == b
+}
+
+func contains(collection, item interface{}) bool {
+	switch c := collection.(type) {
+	case []string:
+		for _, v := range c {
+			if valuesEqual(v, item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, v := range c {
+			if valuesEqual(v, item) {
+				return true
+			}
+		}
+	case string:
+		s, ok := item.(string)
+		return ok && strings.Contains(c, s)
+	}
+	return false
+}
+
+// ---- lexer ----
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+}
+
+type condLexer struct {
+	src string
+	pos int
+}
+
+func (l *condLexer) next() (token, error) {
+	for l.pos < len(l.src) && (l.src[l.pos] == ' ' || l.src[l.pos] == '\t' || l.src[l.pos] == '\n') {
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF}, nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case c == '"' || c == '\'':
+		end := strings.IndexByte(l.src[l.pos+1:], c)
+		if end < 0 {
+			return token{}, errors.New("unterminated string")
+		}
+		text := l.src[l.pos+1 : l.pos+1+end]
+		l.pos += end + 2
+		return token{kind: tokString, text: text}, nil
+	case isIdentStart(c):
+		start := l.pos
+		for l.pos < len(l.src) && (isIdentStart(l.src[l.pos]) || isDigit(l.src[l.pos]) || l.src[l.pos] == '.') {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: l.src[start:l.pos]}, nil
+	case isDigit(c) || c == '-':
+		start := l.pos
+		l.pos++
+		for l.pos < len(l.src) && (isDigit(l.src[l.pos]) || l.src[l.pos] == '.') {
+			l.pos++
+		}
+		return token{kind: tokNumber, text: l.src[start:l.pos]}, nil
+	}
+	for _, op := range []string{"==", "!=", "&&", "||", "!", "(", ")"} {
+		if strings.HasPrefix(l.src[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokOp, text: op}, nil
+		}
+	}
+	return token{}, fmt.Errorf("unexpected character %q", c)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// ---- parser ----
+
+type condParser struct {
+	lex condLexer
+	tok token
+	err error
+}
+
+func (p *condParser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lex.next()
+}
+
+func (p *condParser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp && p.tok.text == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{and: false, left: left, right: right}
+	}
+	return left, p.err
+}
+
+func (p *condParser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp && p.tok.text == "&&" {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{and: true, left: left, right: right}
+	}
+	return left, p.err
+}
+
+func (p *condParser) parseNot() (node, error) {
+	if p.tok.kind == tokOp && p.tok.text == "!" {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *condParser) parseCompare() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	var op string
+	switch {
+	case p.tok.kind == tokOp && (p.tok.text == "==" || p.tok.text == "!="):
+		op = p.tok.text
+	case p.tok.kind == tokIdent && p.tok.text == "in":
+		op = "in"
+	default:
+		return left, p.err
+	}
+	p.next()
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return compareNode{op: op, left: left, right: right}, nil
+}
+
+func (p *condParser) parseOperand() (node, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	tok := p.tok
+	switch tok.kind {
+	case tokOp:
+		if tok.text != "(" {
+			return nil, fmt.Errorf("unexpected %q", tok.text)
+		}
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokOp || p.tok.text != ")" {
+			return nil, errors.New("missing )")
+		}
+		p.next()
+		return inner, p.err
+	case tokString:
+		p.next()
+		return literalNode{value: tok.text}, p.err
+	case tokNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		p.next()
+		return literalNode{value: f}, p.err
+	case tokIdent:
+		p.next()
+		switch tok.text {
+		case "true":
+			return literalNode{value: true}, p.err
+		case "false":
+			return literalNode{value: false}, p.err
+		case "null":
+			return literalNode{value: nil}, p.err
+		case "in":
+			return nil, errors.New("unexpected in")
+		}
+		parts := strings.Split(tok.text, ".")
+		for _, part := range parts {
+			if part == "" {
+				return nil, fmt.Errorf("invalid path %q", tok.text)
+			}
+		}
+		return pathNode{parts: parts}, p.err
+	}
+	return nil, errors.New("unexpected end of condition")
+}
@@ -0,0 +1,215 @@
+package rbac
+
+import (
+	"fmt"
+	"strings"
+
+	"rbac-backend/internal/models"
+)
+
+// SQLMapping describes how the record fields of one table map onto SQL so a
+// Condition can be pushed down into a repository query.
+type SQLMapping struct {
+	// Columns maps a scalar record field to a column expression, e.g.
+	// "created_by" -> "tasks.created_by".
+	Columns map[string]string
+	// Collections maps a list-valued record field to a membership predicate
+	// in which %s is replaced by the SQL for the item, e.g.
+	// "assignees" -> "EXISTS (SELECT 1 FROM json_each(tasks.assignee) WHERE value = %s)".
+	Collections map[string]string
+}
+
+// RowFilter is a SQL predicate with its bound arguments. An empty Where means
+// the query is not restricted.
+type RowFilter struct {
+	Where string
+	Args  []interface{}
+}
+
+// And returns the filter joined onto an existing WHERE clause body.
+func (f RowFilter) And(where string, args ...interface{}) (string, []interface{}) {
+	if f.Where == "" {
+		return where, args
+	}
+	if where == "" {
+		return f.Where, f.Args
+	}
+	return where + " AND " + f.Where, append(append([]interface{}{}, args...), f.Args...)
+}
+
+// CompileRecordFilter translates the row-level condition for action into a
+// RowFilter using mapping. It is the SQL counterpart of EvaluateRecord: rows
+// matched by the filter are exactly the records EvaluateRecord would allow.
+// Every predicate it emits is true or false, never NULL, so a NULL column
+// cannot drop a row through NOT; a NULL text column searched with in is
+// searched as "", as the record's empty string is by Eval.
+func CompileRecordFilter(
+	perm models.ResourcePermission,
+	action string,
+	subject Subject,
+	mapping SQLMapping,
+) (RowFilter, error) {
+	if !Evaluate(perm, action, "").Allowed() {
+		return RowFilter{Where: "0"}, nil
+	}
+	src, ok := perm.Conditions[action]
+	if !ok {
+		return RowFilter{}, nil
+	}
+	cond, err := compileCondition(src)
+	if err != nil {
+		return RowFilter{}, err
+	}
+	return compileSQL(cond, subject, mapping)
+}
+
+func compileSQL(cond *Condition, subject Subject, mapping SQLMapping) (RowFilter, error) {
+	c := sqlCompiler{mapping: mapping, subject: subject}
+	where, err := c.predicate(cond.root)
+	if err != nil {
+		return RowFilter{}, fmt.Errorf("condition %q: %w", cond.src, err)
+	}
+	return RowFilter{Where: where, Args: c.args}, nil
+}
+
+// sqlMappings holds the mapping each table is listed with.
+var sqlMappings = map[string]SQLMapping{}
+
+// RegisterSQLMapping records the mapping the repository for table lists it
+// with, so ValidatePermissions can refuse conditions that would not compile
+// to SQL. Repositories register theirs when the package is initialized.
+func RegisterSQLMapping(table string, mapping SQLMapping) {
+	sqlMappings[table] = mapping
+}
+
+type sqlCompiler struct {
+	mapping SQLMapping
+	subject Subject
+	args    []interface{}
+}
+
+// predicate compiles n in a boolean position.
+func (c *sqlCompiler) predicate(n node) (string, error) {
+	switch n := n.(type) {
+	case logicalNode:
+		l, err := c.predicate(n.left)
+		if err != nil {
+			return "", err
+		}
+		r, err := c.predicate(n.right)
+		if err != nil {
+			return "", err
+		}
+		op := " OR "
+		if n.and {
+			op = " AND "
+		}
+		return "(" + l + op + r + ")", nil
+	case notNode:
+		inner, err := c.predicate(n.operand)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + inner, nil
+	case compareNode:
+		return c.compare(n)
+	case literalNode:
+		if b, ok := n.value.(bool); ok {
+			if b {
+				return "1", nil
+			}
+			return "0", nil
+		}
+		if n.value == nil {
+			return "0", nil
+		}
+		return "", fmt.Errorf("literal %v is not a boolean", n.value)
+	case pathNode:
+		expr, err := c.value(n)
+		if err != nil {
+			return "", err
+		}
+		return "COALESCE(" + expr + ", 0) = 1", nil
+	}
+	return "", fmt.Errorf("unsupported expression %T", n)
+}
+
+func (c *sqlCompiler) compare(n compareNode) (string, error) {
+	if n.op == "in" {
+		item, err := c.value(n.left)
+		if err != nil {
+			return "", err
+		}
+		if p, ok := n.right.(pathNode); ok && len(p.parts) == 2 && p.parts[0] == "record" {
+			if tmpl, ok := c.mapping.Collections[p.parts[1]]; ok {
+				return "(" + fmt.Sprintf(tmpl, item) + ")", nil
+			}
+		}
+		haystack, err := c.value(n.right)
+		if err != nil {
+			return "", err
+		}
+		return "(COALESCE(instr(COALESCE(" + haystack + ", ''), " + item + "), 0) > 0)", nil
+	}
+
+	l, err := c.value(n.left)
+	if err != nil {
+		return "", err
+	}
+	r, err := c.value(n.right)
+	if err != nil {
+		return "", err
+	}
+	// IS / IS NOT are SQLite's null-safe comparisons, matching == on nil in Eval.
+	if n.op == "==" {
+		return "(" + l + " IS " + r + ")", nil
+	}
+	return "(" + l + " IS NOT " + r + ")", nil
+}
+
+// value compiles n in a value position.
+func (c *sqlCompiler) value(n node) (string, error) {
+	switch n := n.(type) {
+	case literalNode:
+		if n.value == nil {
+			return "NULL", nil
+		}
+		return c.bind(n.value), nil
+	case pathNode:
+		if len(n.parts) != 2 {
+			return "", fmt.Errorf("unsupported path %q", strings.Join(n.parts, "."))
+		}
+		switch n.parts[0] {
+		case "user":
+			switch n.parts[1] {
+			case "id":
+				return c.bind(c.subject.ID), nil
+			case "role":
+				return c.bind(c.subject.Role), nil
+			}
+			return "NULL", nil
+		case "record":
+			if col, ok := c.mapping.Columns[n.parts[1]]; ok {
+				return col, nil
+			}
+			if _, ok := c.mapping.Collections[n.parts[1]]; ok {
+				return "", fmt.Errorf("list field %q can only be used on the right of in", n.parts[1])
+			}
+			return "NULL", nil
+		}
+		return "NULL", nil
+	}
+	return "", fmt.Errorf("unsupported value expression %T", n)
+}
+
+func (c *sqlCompiler) bind(v interface{}) string {
+	if b, ok := v.(bool); ok {
+		if b {
+			v = 1
+		} else {
+			v = 0
+		}
+	}
+	c.args = append(c.args, v)
+	return "?"
+}
@@ -0,0 +1,117 @@
+package rbac
+
+import (
+	"encoding/json"
+	"testing"
+
+	"rbac-backend/internal/models"
+)
+
+func TestConditionEval(t *testing.T) {
+	env := map[string]interface{}{
+		"user": map[string]interface{}{"id": "u1", "role": "VIEWER"},
+		"record": map[string]interface{}{
+			"created_by": "u2",
+			"assignees":  []string{"u3", "u1"},
+			"status":     "DONE",
+		},
+	}
+	cases := map[string]bool{
+		`record.created_by == user.id || user.id in record.assignees`: true,
+		`record.created_by == user.id`:                                false,
+		`!(record.status == 'DONE') && user.role == "VIEWER"`:         false,
+		`record.missing == null`:                                      true,
+		`user.role != "ADMIN" && "u3" in record.assignees`:            true,
+	}
+	for src, want := range cases {
+		c, err := ParseCondition(src)
+		if err != nil {
+			t.Fatalf("parse %q: %v", src, err)
+		}
+		got, err := c.Eval(env)
+		if err != nil {
+			t.Fatalf("eval %q: %v", src, err)
+		}
+		if got != want {
+			t.Errorf("%q = %v, want %v", src, got, want)
+		}
+	}
+}
+
+func TestParseConditionRejectsInvalid(t *testing.T) {
+	for _, src := range []string{"", "user.id ==", "(user.id", "user.id = 1", "record..x", "len(x)"} {
+		if _, err := ParseCondition(src); err == nil {
+			t.Errorf("expected error for %q", src)
+		}
+	}
+}
+
+func TestEvaluateRecordFromJSONConfig(t *testing.T) {
+	var perm models.ResourcePermission
+	raw := `{"view": {"if": "user.id in record.assignees"}, "edit": false}`
+	if err := json.Unmarshal([]byte(raw), &perm); err != nil {
+		t.Fatal(err)
+	}
+	if !perm.View || perm.Conditions[ActionView] == "" {
+		t.Fatalf("conditional view not decoded: %+v", perm)
+	}
+
+	subject := Subject{ID: "u1", Role: RoleViewer}
+	if !EvaluateRecord(perm, ActionView, subject, map[string]interface{}{"assignees": []string{"u1"}}).Allowed() {
+		t.Error("assignee should see the record")
+	}
+	if EvaluateRecord(perm, ActionView, subject, map[string]interface{}{"assignees": []string{"u2"}}).Allowed() {
+		t.Error("non-assignee should not see the record")
+	}
+
+	out, err := json.Marshal(perm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back models.ResourcePermission
+	if err := json.Unmarshal(out, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Conditions[ActionView] != perm.Conditions[ActionView] {
+		t.Errorf("condition lost in round trip: %s", out)
+	}
+}
+
+func TestCheckConstraints(t *testing.T) {
+	c := models.Constraints{
+		ExclusiveRoles: []models.RoleConstraint{{ID: "admin-auditor", Roles: []string{RoleAdmin, "AUDITOR"}}},
+		Actions: []models.ActionConstraint{{
+			ID:     "creator-cannot-close",
+			Table:  "tasks",
+			Action: ActionEdit,
+			DenyIf: `record.created_by == user.id && change.status == "DONE"`,
+		}},
+	}
+	if err := ValidateConstraints(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := CheckRoleAssignment(c, []string{RoleAdmin, RoleManager}); v != nil {
+		t.Errorf("unexpected violation: %+v", v)
+	}
+	if v := CheckRoleAssignment(c, []string{RoleAdmin, "AUDITOR"}); v == nil || v.Constraint != "admin-auditor" {
+		t.Errorf("expected admin-auditor violation, got %+v", v)
+	}
+
+	subject := Subject{ID: "u1"}
+	record := map[string]interface{}{"created_by": "u1", "status": "REVIEW"}
+	if v := CheckAction(c, "tasks", ActionEdit, subject, record, map[string]interface{}{"status": "DONE"}); v == nil {
+		t.Error("creator moving own task to DONE should be refused")
+	}
+	if v := CheckAction(c, "tasks", ActionEdit, subject, record, map[string]interface{}{"title": "x"}); v != nil {
+		t.Errorf("unrelated edit refused: %+v", v)
+	}
+	if v := CheckAction(c, "tasks", ActionEdit, Subject{ID: "u2"}, record, map[string]interface{}{"status": "DONE"}); v != nil {
+		t.Errorf("other user refused: %+v", v)
+	}
+
+	unchecked := models.Constraints{Actions: []models.ActionConstraint{{ID: "keep", Table: "projects", Action: ActionDelete, DenyIf: "true"}}}
+	if err := ValidateConstraints(unchecked); err == nil {
+		t.Error("a constraint on an action no request checks was accepted")
+	}
+}
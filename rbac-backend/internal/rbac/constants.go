@@ -1,6 +1,7 @@
 package rbac
 
-// Role names. ADMIN is special (full access in code); others use DB config only.
+// Built-in role names seeded into the roles table. ADMIN is seeded as a
+// superuser; which roles are superusers is config, not code.
 const (
 	RoleAdmin   = "ADMIN"
 	RoleManager = "MANAGER"
@@ -11,13 +12,31 @@ const (
 // Table/resource names used in permission config.
 const (
 	TableProjects = "projects"
+	TableTasks    = "tasks"
 	TableUsers    = "users"
 )
 
-// Actions for table-level checks.
+// Actions for table-level checks. Delete moves a record to the trash,
+// restore takes it back out and purge deletes it from the trash for good.
 const (
-	ActionView   = "view"
-	ActionCreate = "create"
-	ActionEdit   = "edit"
-	ActionDelete = "delete"
+	ActionView    = "view"
+	ActionCreate  = "create"
+	ActionEdit    = "edit"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionPurge   = "purge"
 )
+
+// ValidRoleName reports whether name can be used as a role: 1-32 upper-case
+// letters, digits or underscores, starting with a letter.
+func ValidRoleName(name string) bool {
+	if name == "" || len(name) > 32 || name[0] < 'A' || name[0] > 'Z' {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package rbac
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"rbac-backend/internal/models"
+)
+
+// CheckRoleAssignment reports the first exclusive-roles constraint violated
+// when a user holds all of roles at once, or nil.
+func CheckRoleAssignment(c models.Constraints, roles []string) *models.ConstraintViolation {
+	held := map[string]bool{}
+	for _, r := range roles {
+		held[r] = true
+	}
+	for _, rc := range c.ExclusiveRoles {
+		var conflicting []string
+		for _, r := range rc.Roles {
+			if held[r] {
+				conflicting = append(conflicting, r)
+			}
+		}
+		if len(conflicting) > 1 {
+			msg := rc.Message
+			if msg == "" {
+				msg = "roles " + strings.Join(conflicting, ", ") + " cannot be held by the same user"
+			}
+			return &models.ConstraintViolation{Constraint: rc.ID, Message: msg, Roles: conflicting}
+		}
+	}
+	return nil
+}
+
+// ConstrainedActions are the table actions whose requests check the action
+// constraints: creating and editing projects and tasks, assignment included.
+// A constraint on any other action would never be checked.
+var ConstrainedActions = map[string][]string{
+	TableProjects: {ActionCreate, ActionEdit},
+	TableTasks:    {ActionCreate, ActionEdit},
+}
+
+// CheckAction reports the first action constraint for table/action whose
+// deny_if matches, or nil. record is the current row, or the new one on
+// create, and change the fields being written. A constraint that fails to evaluate is treated as violated.
+func CheckAction(
+	c models.Constraints,
+	table, action string,
+	subject Subject,
+	record, change map[string]interface{},
+) *models.ConstraintViolation {
+	env := map[string]interface{}{
+		"user":   map[string]interface{}{"id": subject.ID, "role": subject.Role},
+		"record": record,
+		"change": change,
+	}
+	for _, ac := range c.Actions {
+		if ac.Table != table || ac.Action != action {
+			continue
+		}
+		cond, err := compileCondition(ac.DenyIf)
+		matched := false
+		if err == nil {
+			matched, err = cond.Eval(env)
+		}
+		if err != nil || matched {
+			msg := ac.Message
+			if msg == "" {
+				msg = fmt.Sprintf("%s on %s refused by constraint %s", action, table, ac.ID)
+			}
+			return &models.ConstraintViolation{Constraint: ac.ID, Message: msg}
+		}
+	}
+	return nil
+}
+
+// ValidateConstraints checks that every constraint is well formed.
+func ValidateConstraints(c models.Constraints) error {
+	for _, rc := range c.ExclusiveRoles {
+		if rc.ID == "" || len(rc.Roles) < 2 {
+			return fmt.Errorf("exclusive_roles %q: id and at least two roles required", rc.ID)
+		}
+	}
+	for _, ac := range c.Actions {
+		if ac.ID == "" || ac.Table == "" || ac.Action == "" {
+			return fmt.Errorf("actions %q: id, table and action required", ac.ID)
+		}
+		if !slices.Contains(ConstrainedActions[ac.Table], ac.Action) {
+			return fmt.Errorf("actions %q: %s on %s is not checked by any request", ac.ID, ac.Action, ac.Table)
+		}
+		if _, err := compileCondition(ac.DenyIf); err != nil {
+			return fmt.Errorf("actions %q: %w", ac.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package rbac
+
+import (
+	"reflect"
+	"sort"
+
+	"rbac-backend/internal/models"
+)
+
+// Change kinds reported by DiffPermissions.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// Change is one difference between two permission configs. Path uses the
+// same dotted form as validation diagnostics, e.g. "tasks.fields.title.edit".
+type Change struct {
+	Kind string      `json:"kind"`
+	Path string      `json:"path"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// DiffPermissions lists the rule-level differences from a to b, sorted by path.
+func DiffPermissions(a, b models.Permissions) []Change {
+	fa, fb := flattenPermissions(a), flattenPermissions(b)
+
+	changes := []Change{}
+	for path, va := range fa {
+		vb, ok := fb[path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: ChangeRemoved, Path: path, From: va})
+		case !reflect.DeepEqual(va, vb):
+			changes = append(changes, Change{Kind: ChangeChanged, Path: path, From: va, To: vb})
+		}
+	}
+	for path, vb := range fb {
+		if _, ok := fa[path]; !ok {
+			changes = append(changes, Change{Kind: ChangeAdded, Path: path, To: vb})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// flattenPermissions maps every rule in perms to its dotted path. Only rules
+// that are set appear, so a rule going from absent to false is not a change.
+func flattenPermissions(perms models.Permissions) map[string]interface{} {
+	out := map[string]interface{}{}
+	for table, perm := range perms {
+		for _, action := range TableActions {
+			if tableAllows(perm, action) {
+				out[table+"."+action] = true
+			}
+			if cond, ok := perm.Conditions[action]; ok {
+				out[table+"."+action+".if"] = cond
+			}
+		}
+		if len(perm.Deny) > 0 {
+			out[table+".deny"] = sortedCopy(perm.Deny)
+		}
+		for field, fp := range perm.Fields {
+			prefix := table + ".fields." + field
+			// Listing a field restricts the table even with no grants, so
+			// its presence is part of the rule set.
+			out[prefix] = true
+			for _, action := range FieldActions {
+				if fieldAllows(fp, action) {
+					out[prefix+"."+action] = true
+				}
+			}
+			if len(fp.Deny) > 0 {
+				out[prefix+".deny"] = sortedCopy(fp.Deny)
+			}
+			if fp.Redact != "" {
+				out[prefix+".redact"] = fp.Redact
+			}
+		}
+	}
+	return out
+}
+
+func sortedCopy(in []string) []string {
+	out := append([]string(nil), in...)
+	sort.Strings(out)
+	return out
+}
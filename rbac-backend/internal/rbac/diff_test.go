@@ -0,0 +1,89 @@
+package rbac
+
+import (
+	"testing"
+
+	"rbac-backend/internal/models"
+)
+
+func TestDiffPermissions(t *testing.T) {
+	a := models.Permissions{
+		"tasks": {
+			View: true,
+			Edit: true,
+			Fields: map[string]models.FieldPermission{
+				"title": {View: true, Edit: true},
+			},
+		},
+	}
+	b := models.Permissions{
+		"tasks": {
+			View:       true,
+			Conditions: map[string]string{ActionView: "user.id in record.assignees"},
+			Deny:       []string{ActionDelete},
+			Fields: map[string]models.FieldPermission{
+				"title": {View: true},
+			},
+		},
+	}
+
+	got := map[string]string{}
+	for _, c := range DiffPermissions(a, b) {
+		got[c.Path] = c.Kind
+	}
+	want := map[string]string{
+		"tasks.edit":              ChangeRemoved,
+		"tasks.fields.title.edit": ChangeRemoved,
+		"tasks.view.if":           ChangeAdded,
+		"tasks.deny":              ChangeAdded,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for path, kind := range want {
+		if got[path] != kind {
+			t.Errorf("%s: got %q, want %q", path, got[path], kind)
+		}
+	}
+
+	if changes := DiffPermissions(a, a); len(changes) != 0 {
+		t.Errorf("identical configs should not differ: %+v", changes)
+	}
+}
+
+func TestCompareAccessReportsDecisionsNotRules(t *testing.T) {
+	before := models.Permissions{
+		"tasks": {View: true, Edit: true, Fields: map[string]models.FieldPermission{
+			"title":      {View: true, Edit: true},
+			"created_by": {View: true},
+		}},
+	}
+	after := models.Permissions{
+		"tasks": {
+			View:       true,
+			Conditions: map[string]string{ActionView: "user.id in record.assignees"},
+			Fields: map[string]models.FieldPermission{
+				"title":      {View: true, Edit: true}, // edit is lost with the table edit
+				"created_by": {View: true},
+			},
+		},
+	}
+
+	got := map[string]string{}
+	for _, c := range CompareAccess(before, after) {
+		got[c.Table+"."+c.Action+"."+c.Field] = c.Change
+	}
+	want := map[string]string{
+		"tasks.view.":      AccessConditionChanged,
+		"tasks.edit.":      AccessLost,
+		"tasks.edit.title": AccessLost,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for key, kind := range want {
+		if got[key] != kind {
+			t.Errorf("%s: got %q, want %q", key, got[key], kind)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package rbac
+
+import (
+	"reflect"
+	"testing"
+
+	"rbac-backend/internal/models"
+)
+
+func TestEvaluatePrecedence(t *testing.T) {
+	perm := models.ResourcePermission{
+		View: true,
+		Edit: true,
+		Deny: []string{ActionDelete},
+		Fields: map[string]models.FieldPermission{
+			"title":      {View: true, Edit: true},
+			"created_by": {View: true, Deny: []string{ActionView}},
+		},
+	}
+
+	cases := []struct {
+		action, field string
+		want          Effect
+	}{
+		{ActionView, "", EffectAllow},
+		{ActionCreate, "", EffectDefaultDeny},
+		{ActionDelete, "", EffectDeny},
+		{ActionView, "title", EffectAllow},
+		{ActionView, "created_by", EffectDeny},
+		{ActionEdit, "created_by", EffectDefaultDeny},
+		{ActionView, "status", EffectDefaultDeny},
+	}
+	for _, c := range cases {
+		if got := Evaluate(perm, c.action, c.field); got != c.want {
+			t.Errorf("Evaluate(%q, %q) = %v, want %v", c.action, c.field, got, c.want)
+		}
+	}
+}
+
+func TestEvaluateDenyOnlyFieldsKeepOthersVisible(t *testing.T) {
+	perm := models.ResourcePermission{
+		View: true,
+		Fields: map[string]models.FieldPermission{
+			"created_by": {Deny: []string{ActionView}},
+		},
+	}
+	if !Evaluate(perm, ActionView, "title").Allowed() {
+		t.Fatal("title should be visible when fields only carry denies")
+	}
+	if Evaluate(perm, ActionView, "created_by").Allowed() {
+		t.Fatal("created_by should be denied")
+	}
+}
+
+func TestMergeKeepsDenyPrecedence(t *testing.T) {
+	base := models.ResourcePermission{
+		View: true,
+		Deny: []string{ActionDelete},
+		Fields: map[string]models.FieldPermission{
+			"title": {View: true},
+		},
+	}
+	grant := models.ResourcePermission{Edit: true, Delete: true}
+
+	merged := Merge(base, grant)
+	if !Evaluate(merged, ActionEdit, "").Allowed() {
+		t.Error("edit should be granted by the merge")
+	}
+	if Evaluate(merged, ActionDelete, "") != EffectDeny {
+		t.Error("deny from base must survive the merge")
+	}
+	if !Evaluate(merged, ActionView, "status").Allowed() {
+		t.Error("grant without field rules should open all fields")
+	}
+}
+
+func TestMergeRedactsOnlyWhenEverySideRedacts(t *testing.T) {
+	masked := models.ResourcePermission{View: true, Fields: map[string]models.FieldPermission{
+		"title": {View: true, Redact: RedactMask},
+	}}
+	hashed := models.ResourcePermission{View: true, Fields: map[string]models.FieldPermission{
+		"title": {View: true, Redact: RedactHash},
+	}}
+	plain := models.ResourcePermission{View: true, Fields: map[string]models.FieldPermission{
+		"title": {View: true},
+	}}
+
+	if got := Merge(masked, hashed).Fields["title"].Redact; got != RedactMask {
+		t.Errorf("both sides redact: got %q", got)
+	}
+	if got := Merge(masked, plain).Fields["title"].Redact; got != "" {
+		t.Errorf("an unredacted grant should win, got %q", got)
+	}
+}
+
+func TestEvaluateFieldPathPrecedence(t *testing.T) {
+	perm := models.ResourcePermission{View: true, Fields: map[string]models.FieldPermission{
+		"*":           {View: true},
+		"owner":       {View: false},
+		"owner.name":  {View: true},
+		"meta":        {View: true, Deny: []string{ActionView}},
+		"meta.public": {View: true},
+	}}
+	cases := map[string]Effect{
+		"title":       EffectAllow,       // "*"
+		"owner":       EffectDefaultDeny, // exact rule beats "*"
+		"owner.email": EffectDefaultDeny, // inherits "owner"
+		"owner.name":  EffectAllow,       // most specific wins
+		"meta.public": EffectDeny,        // a deny on an ancestor always wins
+	}
+	for field, want := range cases {
+		if got := Evaluate(perm, ActionView, field); got != want {
+			t.Errorf("%s: got %v, want %v", field, got, want)
+		}
+	}
+
+	if chain := FieldRuleChain("assignees[*].email"); !reflect.DeepEqual(chain, []string{
+		"assignees[*].email", "assignees[*].*", "assignees[*]", "assignees.*", "assignees", "*",
+	}) {
+		t.Errorf("unexpected chain %v", chain)
+	}
+}
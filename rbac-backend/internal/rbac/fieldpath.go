@@ -0,0 +1,91 @@
+package rbac
+
+import (
+	"strings"
+
+	"rbac-backend/internal/models"
+)
+
+// Field rules are keyed by path:
+//
+//	title                 a top-level field
+//	owner.email           a field of a nested object
+//	assignees[*].email    a field of every element of a list
+//	*, meta.*             every field at that level
+//
+// FieldRuleChain lists the rule keys that can apply to path, most specific
+// first: the path itself, then for each ancestor from the closest outwards
+// its wildcard and the ancestor itself, and finally "*". For example
+// "owner.email" yields "owner.email", "owner.*", "owner", "*".
+func FieldRuleChain(path string) []string {
+	chain := []string{path}
+	for {
+		parent, ok := parentPath(path)
+		if !ok {
+			break
+		}
+		chain = append(chain, parent+".*", parent)
+		path = parent
+	}
+	if chain[len(chain)-1] != "*" {
+		chain = append(chain, "*")
+	}
+	return chain
+}
+
+// parentPath strips the last segment of path: "a.b" -> "a",
+// "a[*]" -> "a", "a[*].b" -> "a[*]".
+func parentPath(path string) (string, bool) {
+	if strings.HasSuffix(path, "[*]") {
+		return strings.TrimSuffix(path, "[*]"), true
+	}
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[:i], true
+	}
+	return "", false
+}
+
+// fieldRuleFor returns the most specific rule for path that carries a grant
+// (see hasFieldRules: an entry that only lists denies does not).
+func fieldRuleFor(fields map[string]models.FieldPermission, path string) (models.FieldPermission, bool) {
+	for _, key := range FieldRuleChain(path) {
+		if fp, ok := fields[key]; ok && (len(fp.Deny) == 0 || fp.View || fp.Create || fp.Edit) {
+			return fp, true
+		}
+	}
+	return models.FieldPermission{}, false
+}
+
+// fieldDenied reports whether any rule on path's chain denies action, so a
+// deny on an ancestor or a wildcard covers everything below it.
+func fieldDenied(fields map[string]models.FieldPermission, path, action string) bool {
+	for _, key := range FieldRuleChain(path) {
+		if fp, ok := fields[key]; ok && containsAction(fp.Deny, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasNestedFieldRules reports whether any rule targets something below path,
+// i.e. whether a filter must descend into the value at path instead of
+// treating it as a whole.
+func HasNestedFieldRules(fields map[string]models.FieldPermission, path string) bool {
+	for key := range fields {
+		if strings.HasPrefix(key, path+".") || strings.HasPrefix(key, path+"[*]") {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldRedaction returns the redact setting of the most specific rule on
+// path's chain that has one, or "".
+func FieldRedaction(fields map[string]models.FieldPermission, path string) string {
+	for _, key := range FieldRuleChain(path) {
+		if fp, ok := fields[key]; ok && fp.Redact != "" {
+			return fp.Redact
+		}
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package rbac
+
+import "rbac-backend/internal/models"
+
+// ResolveInherited returns role's own permissions merged with those of every
+// role it inherits from, directly or transitively, using Merge so a deny
+// anywhere in the chain still wins. perms and parents look up a single role;
+// perms may return nil for a role without config. Cycles are ignored.
+func ResolveInherited(
+	role string,
+	perms func(role string) (models.Permissions, error),
+	parents func(role string) ([]string, error),
+) (models.Permissions, error) {
+	own, err := perms(role)
+	if err != nil {
+		return nil, err
+	}
+	out := models.Permissions{}
+	for table, p := range own {
+		out[table] = p
+	}
+
+	visited := map[string]bool{role: true}
+	queue, err := parents(role)
+	if err != nil {
+		return nil, err
+	}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		if visited[parent] {
+			continue
+		}
+		visited[parent] = true
+
+		parentPerms, err := perms(parent)
+		if err != nil {
+			return nil, err
+		}
+		for table, p := range parentPerms {
+			if cur, ok := out[table]; ok {
+				out[table] = Merge(cur, p)
+			} else {
+				out[table] = p
+			}
+		}
+
+		grand, err := parents(parent)
+		if err != nil {
+			return nil, err
+		}
+		queue = append(queue, grand...)
+	}
+	return out, nil
+}
@@ -0,0 +1,109 @@
+package rbac
+
+import "rbac-backend/internal/models"
+
+// Merge combines two permission sources for the same table (e.g. a role and
+// a temporary elevation). Allows are unioned, denies are unioned and keep
+// their precedence in Evaluate, so a deny from either source still wins.
+//
+// A side without field rules allows every field, so the merge keeps only the
+// field denies in that case. Conditional grants are ORed; an unconditional
+// grant on either side makes the merged grant unconditional. Likewise a field
+// is only redacted when every side that lets it be viewed redacts it.
+func Merge(a, b models.ResourcePermission) models.ResourcePermission {
+	out := models.ResourcePermission{
+		View:    a.View || b.View,
+		Create:  a.Create || b.Create,
+		Edit:    a.Edit || b.Edit,
+		Delete:  a.Delete || b.Delete,
+		Restore: a.Restore || b.Restore,
+		Purge:   a.Purge || b.Purge,
+		Deny:    unionActions(a.Deny, b.Deny),
+	}
+
+	for _, action := range TableActions {
+		if cond := mergeCondition(a, b, action); cond != "" {
+			if out.Conditions == nil {
+				out.Conditions = make(map[string]string)
+			}
+			out.Conditions[action] = cond
+		}
+	}
+
+	aAll, bAll := !hasFieldRules(a.Fields), !hasFieldRules(b.Fields)
+	if len(a.Fields) == 0 && len(b.Fields) == 0 {
+		return out
+	}
+	out.Fields = make(map[string]models.FieldPermission)
+	for _, side := range []map[string]models.FieldPermission{a.Fields, b.Fields} {
+		for name, fp := range side {
+			cur := out.Fields[name]
+			cur.View = cur.View || fp.View
+			cur.Create = cur.Create || fp.Create
+			cur.Edit = cur.Edit || fp.Edit
+			cur.Deny = unionActions(cur.Deny, fp.Deny)
+			out.Fields[name] = cur
+		}
+	}
+	for name, fp := range out.Fields {
+		fp.Redact = mergeRedact(a.Fields[name], b.Fields[name])
+		out.Fields[name] = fp
+	}
+	if aAll || bAll {
+		for name, fp := range out.Fields {
+			if len(fp.Deny) == 0 {
+				delete(out.Fields, name)
+				continue
+			}
+			out.Fields[name] = models.FieldPermission{Deny: fp.Deny}
+		}
+	}
+	return out
+}
+
+func mergeCondition(a, b models.ResourcePermission, action string) string {
+	aGrant, bGrant := tableAllows(a, action), tableAllows(b, action)
+	aCond, bCond := a.Conditions[action], b.Conditions[action]
+	switch {
+	case aGrant && aCond == "", bGrant && bCond == "":
+		return ""
+	case aGrant && bGrant && aCond == bCond:
+		return aCond
+	case aGrant && bGrant:
+		return "(" + aCond + ") || (" + bCond + ")"
+	case aGrant:
+		return aCond
+	case bGrant:
+		return bCond
+	}
+	return ""
+}
+
+func mergeRedact(a, b models.FieldPermission) string {
+	switch {
+	case a.View && a.Redact == "", b.View && b.Redact == "":
+		return ""
+	case a.View:
+		return a.Redact
+	case b.View, a.Redact == "":
+		return b.Redact
+	}
+	return a.Redact
+}
+
+func unionActions(a, b []string) []string {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	var out []string
+	seen := map[string]bool{}
+	for _, list := range [][]string{a, b} {
+		for _, action := range list {
+			if !seen[action] {
+				seen[action] = true
+				out = append(out, action)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,41 @@
+package rbac
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Redaction modes for field rules.
+const (
+	RedactMask     = "mask"
+	RedactHash     = "hash"
+	RedactTruncate = "truncate"
+	RedactNull     = "null"
+)
+
+// DefaultTruncateLength is the number of characters "truncate" keeps.
+const DefaultTruncateLength = 4
+
+// ParseRedaction splits a field's redact setting into its mode and, for
+// "truncate:N", the number of characters to keep.
+func ParseRedaction(spec string) (string, int, error) {
+	mode, arg, hasArg := strings.Cut(spec, ":")
+	switch mode {
+	case RedactMask, RedactHash, RedactNull:
+		if hasArg {
+			return "", 0, fmt.Errorf("redaction %q takes no argument", mode)
+		}
+		return mode, 0, nil
+	case RedactTruncate:
+		if !hasArg {
+			return mode, DefaultTruncateLength, nil
+		}
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return "", 0, fmt.Errorf("invalid truncate length %q", arg)
+		}
+		return mode, n, nil
+	}
+	return "", 0, fmt.Errorf("unknown redaction %q", spec)
+}
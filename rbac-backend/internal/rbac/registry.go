@@ -0,0 +1,50 @@
+package rbac
+
+import "strings"
+
+// Registry lists the tables that can appear in permission config and the
+// fields each one exposes through the API. Keep it in sync with the row maps
+// built by the handlers (taskRow, projectRow) and models.User.
+var Registry = map[string][]string{
+	TableProjects: {"id", "name", "description", "created_by", "assigned_employees"},
+	TableTasks: {
+		"id", "project_id", "title", "description", "status", "assignees",
+		"created_by", "started_at", "completed_at", "created_at", "updated_at",
+	},
+	TableUsers: {"id", "name", "email", "role", "is_active", "created_at", "updated_at"},
+}
+
+// TableActions are the actions a table-level rule can grant or deny.
+var TableActions = []string{ActionView, ActionCreate, ActionEdit, ActionDelete, ActionRestore, ActionPurge}
+
+// FieldActions are the actions a field-level rule can grant or deny.
+var FieldActions = []string{ActionView, ActionCreate, ActionEdit}
+
+// KnownFieldPath reports whether a field rule key can apply to table: "*" or
+// a path whose top-level field is registered. Segments below the top level
+// are not checked because nested values have no fixed schema.
+func KnownFieldPath(table, path string) bool {
+	if path == "*" {
+		return true
+	}
+	return KnownField(table, rootField(path))
+}
+
+// rootField returns the top-level field of a path: "owner.email" -> "owner",
+// "assignees[*].email" -> "assignees".
+func rootField(path string) string {
+	if i := strings.IndexAny(path, ".["); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// KnownField reports whether field is registered for table.
+func KnownField(table, field string) bool {
+	for _, f := range Registry[table] {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"rbac-backend/internal/models"
+)
+
+// Diagnostic severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is one finding about a permission config. Path points at the
+// offending entry, e.g. "tasks.fields.asignees".
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+}
+
+// ValidationReport collects the diagnostics for a permission config.
+type ValidationReport struct {
+	Valid    bool         `json:"valid"`
+	Errors   []Diagnostic `json:"errors"`
+	Warnings []Diagnostic `json:"warnings"`
+}
+
+func (r *ValidationReport) add(severity, path, format string, args ...interface{}) {
+	d := Diagnostic{Severity: severity, Path: path, Message: fmt.Sprintf(format, args...)}
+	if severity == SeverityError {
+		r.Errors = append(r.Errors, d)
+	} else {
+		r.Warnings = append(r.Warnings, d)
+	}
+}
+
+var knownResourceKeys = map[string]bool{
+	"view": true, "create": true, "edit": true, "delete": true, "restore": true, "purge": true,
+	"deny": true, "fields": true,
+}
+
+var knownFieldKeys = map[string]bool{
+	"view": true, "create": true, "edit": true, "deny": true, "redact": true,
+}
+
+// ValidatePermissionsJSON decodes a role permission document and validates
+// it, including keys the decoder would otherwise silently ignore.
+func ValidatePermissionsJSON(raw []byte) (models.Permissions, ValidationReport, error) {
+	var perms models.Permissions
+	if err := json.Unmarshal(raw, &perms); err != nil {
+		return nil, ValidationReport{}, err
+	}
+
+	report := ValidatePermissions(perms)
+
+	var shape map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &shape); err == nil {
+		for _, table := range sortedKeys(shape) {
+			for _, key := range sortedKeys(shape[table]) {
+				if !knownResourceKeys[key] {
+					report.add(SeverityError, table+"."+key, "unknown key %q", key)
+				}
+			}
+			var fields map[string]map[string]json.RawMessage
+			if rawFields, ok := shape[table]["fields"]; ok && json.Unmarshal(rawFields, &fields) == nil {
+				for _, field := range sortedKeys(fields) {
+					for _, key := range sortedKeys(fields[field]) {
+						if !knownFieldKeys[key] {
+							report.add(SeverityError, table+".fields."+field+"."+key, "unknown key %q", key)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	report.Valid = len(report.Errors) == 0
+	return perms, report, nil
+}
+
+// ValidatePermissions checks perms against the Registry: unknown tables,
+// fields and actions, field grants the table does not allow, conditions
+// that do not compile or read unknown fields and unknown redaction modes are
+// errors; registered fields left out of a table's field rules are warnings.
+// A condition must also compile to SQL with the table's registered
+// SQLMapping, so a saved policy cannot break the table's list endpoints.
+func ValidatePermissions(perms models.Permissions) ValidationReport {
+	report := ValidationReport{Errors: []Diagnostic{}, Warnings: []Diagnostic{}}
+
+	for _, table := range sortedKeys(perms) {
+		perm := perms[table]
+		if _, ok := Registry[table]; !ok {
+			report.add(SeverityError, table, "unknown table %q", table)
+			continue
+		}
+
+		for _, a := range perm.Deny {
+			if a != "*" && !containsAction(TableActions, a) {
+				report.add(SeverityError, table+".deny", "unknown action %q", a)
+			}
+		}
+		for _, action := range sortedKeys(perm.Conditions) {
+			validateCondition(&report, table, table+"."+action+".if", perm.Conditions[action])
+		}
+
+		for _, field := range sortedKeys(perm.Fields) {
+			fp := perm.Fields[field]
+			path := table + ".fields." + field
+			if !KnownFieldPath(table, field) {
+				report.add(SeverityError, path, "unknown field %q for table %q", field, table)
+				continue
+			}
+			if !validFieldPath(field) {
+				report.add(SeverityError, path, "malformed field path %q", field)
+				continue
+			}
+			for _, a := range fp.Deny {
+				if a != "*" && !containsAction(FieldActions, a) {
+					report.add(SeverityError, path+".deny", "unknown action %q", a)
+				}
+			}
+			if fp.Redact != "" {
+				if _, _, err := ParseRedaction(fp.Redact); err != nil {
+					report.add(SeverityError, path+".redact", "%v", err)
+				}
+			}
+			if fp.View && !perm.View {
+				report.add(SeverityError, path+".view", "field view granted without table view")
+			}
+			if fp.Create && !perm.Create {
+				report.add(SeverityError, path+".create", "field create granted without table create")
+			}
+			if fp.Edit && !perm.Edit {
+				report.add(SeverityError, path+".edit", "field edit granted without table edit")
+			}
+		}
+
+		if hasFieldRules(perm.Fields) && !hasFieldRule(perm.Fields, "*") {
+			for _, field := range Registry[table] {
+				if !mentionsField(perm.Fields, field) {
+					report.add(SeverityWarning, table+".fields."+field, "field %q is not mentioned and will be hidden", field)
+				}
+			}
+		}
+	}
+
+	report.Valid = len(report.Errors) == 0
+	return report
+}
+
+// validateCondition reports a condition that does not compile, reads a path
+// other than user.<field> or record.<registered field>, or cannot be compiled
+// to SQL for table.
+func validateCondition(report *ValidationReport, table, path, src string) {
+	cond, err := compileCondition(src)
+	if err != nil {
+		report.add(SeverityError, path, "%v", err)
+		return
+	}
+	unknown := false
+	for _, p := range conditionPaths(cond.root) {
+		name := strings.Join(p.parts, ".")
+		switch {
+		case len(p.parts) < 2:
+			report.add(SeverityError, path, "unknown path %q", name)
+		case p.parts[0] == "user" && len(p.parts) == 2 && (p.parts[1] == "id" || p.parts[1] == "role"):
+			continue
+		case p.parts[0] == "record" && KnownField(table, p.parts[1]):
+			continue
+		case p.parts[0] == "record":
+			report.add(SeverityError, path, "unknown field %q for table %q", p.parts[1], table)
+		default:
+			report.add(SeverityError, path, "unknown path %q", name)
+		}
+		unknown = true
+	}
+	if mapping, ok := sqlMappings[table]; ok && !unknown {
+		if _, err := compileSQL(cond, Subject{}, mapping); err != nil {
+			report.add(SeverityError, path, "%v", err)
+		}
+	}
+}
+
+// conditionPaths returns the paths n reads.
+func conditionPaths(n node) []pathNode {
+	switch n := n.(type) {
+	case pathNode:
+		return []pathNode{n}
+	case notNode:
+		return conditionPaths(n.operand)
+	case logicalNode:
+		return append(conditionPaths(n.left), conditionPaths(n.right)...)
+	case compareNode:
+		return append(conditionPaths(n.left), conditionPaths(n.right)...)
+	}
+	return nil
+}
+
+func hasFieldRule(fields map[string]models.FieldPermission, key string) bool {
+	_, ok := fields[key]
+	return ok
+}
+
+// mentionsField reports whether any rule targets field or something inside it.
+func mentionsField(fields map[string]models.FieldPermission, field string) bool {
+	for key := range fields {
+		if rootField(key) == field {
+			return true
+		}
+	}
+	return false
+}
+
+// validFieldPath checks the syntax of a field rule key: dot-separated
+// segments, each a name optionally followed by "[*]", with "*" allowed as
+// the last segment.
+func validFieldPath(path string) bool {
+	segments := strings.Split(path, ".")
+	for i, seg := range segments {
+		if seg == "*" && i == len(segments)-1 {
+			continue
+		}
+		name := strings.TrimSuffix(seg, "[*]")
+		if name == "" || strings.ContainsAny(name, "*[]") {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,138 @@
+package rbac
+
+import (
+	"testing"
+
+	"rbac-backend/internal/models"
+)
+
+func TestValidatePermissionsJSON(t *testing.T) {
+	raw := `{
+	  "projcts": { "view": true },
+	  "tasks": {
+	    "view": true,
+	    "edit": false,
+	    "veiw": true,
+	    "deny": ["archive"],
+	    "fields": {
+	      "title":    { "view": true, "edit": true },
+	      "asignees": { "view": true },
+	      "status":   { "view": true, "edti": true }
+	    }
+	  }
+	}`
+	_, report, err := ValidatePermissionsJSON([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Valid {
+		t.Fatal("config with typos must be invalid")
+	}
+
+	want := map[string]bool{
+		"projcts":                  false,
+		"tasks.veiw":               false,
+		"tasks.deny":               false,
+		"tasks.fields.asignees":    false,
+		"tasks.fields.title.edit":  false,
+		"tasks.fields.status.edti": false,
+	}
+	for _, d := range report.Errors {
+		if _, ok := want[d.Path]; ok {
+			want[d.Path] = true
+		}
+	}
+	for path, seen := range want {
+		if !seen {
+			t.Errorf("missing error for %s; got %+v", path, report.Errors)
+		}
+	}
+
+	warned := false
+	for _, d := range report.Warnings {
+		if d.Path == "tasks.fields.assignees" {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Errorf("expected warning for unmentioned tasks.assignees; got %+v", report.Warnings)
+	}
+}
+
+func TestValidatePermissionsAcceptsSeedShape(t *testing.T) {
+	raw := `{
+	  "projects": {
+	    "view": {"if": "user.id in record.assigned_employees"},
+	    "create": true, "edit": true, "delete": true,
+	    "fields": {
+	      "id": { "view": true, "edit": false },
+	      "name": { "view": true, "edit": true },
+	      "description": { "view": true, "edit": true },
+	      "created_by": { "view": true, "edit": false },
+	      "assigned_employees": { "view": true }
+	    }
+	  }
+	}`
+	_, report, err := ValidatePermissionsJSON([]byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid || len(report.Warnings) != 0 {
+		t.Fatalf("unexpected diagnostics: %+v", report)
+	}
+}
+
+func TestValidateFieldPaths(t *testing.T) {
+	perms := models.Permissions{
+		"tasks": {View: true, Fields: map[string]models.FieldPermission{
+			"*":                  {View: true},
+			"assignees[*].email": {View: true},
+			"owner.email":        {View: true},
+			"title[*":            {View: true},
+		}},
+	}
+	report := ValidatePermissions(perms)
+	got := map[string]bool{}
+	for _, d := range report.Errors {
+		got[d.Path] = true
+	}
+	if len(report.Errors) != 2 || !got["tasks.fields.owner.email"] || !got["tasks.fields.title[*"] {
+		t.Fatalf("unexpected errors: %+v", report.Errors)
+	}
+	if len(report.Warnings) != 0 {
+		t.Errorf("a \"*\" rule mentions every field: %+v", report.Warnings)
+	}
+}
+
+func TestValidateConditions(t *testing.T) {
+	RegisterSQLMapping(TableTasks, SQLMapping{
+		Columns:     map[string]string{"id": "tasks.id", "created_by": "tasks.created_by"},
+		Collections: map[string]string{"assignees": "EXISTS (SELECT 1 FROM json_each(tasks.assignee) WHERE value = %s)"},
+	})
+	t.Cleanup(func() { delete(sqlMappings, TableTasks) })
+
+	tests := []struct {
+		cond  string
+		valid bool
+	}{
+		{`user.id in record.assignees || record.created_by == user.id`, true},
+		{`user.role == "VIEWER"`, true},
+		{`user.id in record.asignees`, false},     // unknown record field
+		{`record.created_by == user.name`, false}, // unknown user field
+		{`owner.id == user.id`, false},            // unknown root
+		{`record.assignees == user.id`, false},    // lists only work with in
+	}
+	for _, tt := range tests {
+		perms := models.Permissions{"tasks": {View: true, Conditions: map[string]string{ActionView: tt.cond}}}
+		report := ValidatePermissions(perms)
+		if report.Valid != tt.valid {
+			t.Errorf("%s: valid = %v, want %v: %+v", tt.cond, report.Valid, tt.valid, report.Errors)
+			continue
+		}
+		for _, d := range report.Errors {
+			if d.Path != "tasks.view.if" {
+				t.Errorf("%s: error at %s, want tasks.view.if", tt.cond, d.Path)
+			}
+		}
+	}
+}
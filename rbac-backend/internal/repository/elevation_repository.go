@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"rbac-backend/internal/models"
+)
+
+// ElevationRepository reads and writes the elevation requests of one
+// organization. A grant only applies in the organization it was requested in.
+type ElevationRepository struct {
+	DB    *sql.DB
+	OrgID string
+}
+
+func NewElevationRepository(db *sql.DB) *ElevationRepository {
+	return &ElevationRepository{DB: db}
+}
+
+// ForOrg returns a copy of the repository scoped to orgID.
+func (r *ElevationRepository) ForOrg(orgID string) *ElevationRepository {
+	return &ElevationRepository{DB: r.DB, OrgID: orgID}
+}
+
+const elevationColumns = `id, org_id, user_id, COALESCE(role, ''), COALESCE(table_name, ''), COALESCE(action, ''),
+	justification, duration_minutes, status, COALESCE(approved_by, ''), requested_at, approved_at, expires_at, closed_at`
+
+func (r *ElevationRepository) CreateElevation(e models.Elevation) error {
+	if r.OrgID == "" {
+		return ErrNoOrganization
+	}
+	_, err := r.DB.Exec(
+		`INSERT INTO elevation_requests (id, org_id, user_id, role, table_name, action, justification, duration_minutes, status, requested_at)
+		 VALUES (?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?)`,
+		e.ID, r.OrgID, e.UserID, e.Role, e.Table, e.Action, e.Justification, e.DurationMinutes, e.Status, e.RequestedAt,
+	)
+	return dbError(err)
+}
+
+// GetElevationByID returns the request, or nil when it does not exist.
+func (r *ElevationRepository) GetElevationByID(id string) (*models.Elevation, error) {
+	rows, err := r.DB.Query(`SELECT `+elevationColumns+` FROM elevation_requests WHERE id = ? AND org_id = ?`, id, r.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	list, err := scanElevations(rows)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+// ListElevations returns requests newest first; an empty userID lists everyone's.
+func (r *ElevationRepository) ListElevations(userID string) ([]models.Elevation, error) {
+	query := `SELECT ` + elevationColumns + ` FROM elevation_requests WHERE org_id = ?`
+	args := []interface{}{r.OrgID}
+	if userID != "" {
+		query += ` AND user_id = ?`
+		args = append(args, userID)
+	}
+	rows, err := r.DB.Query(query+` ORDER BY requested_at DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanElevations(rows)
+}
+
+// ActiveForUser returns the user's approved grants that have not expired at now.
+func (r *ElevationRepository) ActiveForUser(userID string, now time.Time) ([]models.Elevation, error) {
+	rows, err := r.DB.Query(
+		`SELECT `+elevationColumns+` FROM elevation_requests WHERE org_id = ? AND user_id = ? AND status = ? AND expires_at > ?`,
+		r.OrgID, userID, models.ElevationApproved, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanElevations(rows)
+}
+
+// Approve activates a pending request for its requested duration starting at now.
+// It reports false when the request was no longer pending.
+func (r *ElevationRepository) Approve(id, approverID string, now time.Time) (bool, error) {
+	e, err := r.GetElevationByID(id)
+	if err != nil || e == nil {
+		return false, err
+	}
+	expires := now.Add(time.Duration(e.DurationMinutes) * time.Minute)
+	res, err := r.DB.Exec(
+		`UPDATE elevation_requests SET status = ?, approved_by = ?, approved_at = ?, expires_at = ? WHERE id = ? AND org_id = ? AND status = ?`,
+		models.ElevationApproved, approverID, now, expires, id, r.OrgID, models.ElevationPending,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Close moves a request from one of the from statuses to status (DENIED,
+// REVOKED or EXPIRED). It reports false when the request was in another state.
+func (r *ElevationRepository) Close(id, status string, now time.Time, from ...string) (bool, error) {
+	query := `UPDATE elevation_requests SET status = ?, closed_at = ? WHERE id = ? AND org_id = ? AND status IN (`
+	args := []interface{}{status, now, id, r.OrgID}
+	for i, s := range from {
+		if i > 0 {
+			query += ", "
+		}
+		query += "?"
+		args = append(args, s)
+	}
+	res, err := r.DB.Exec(query+")", args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ExpireDue marks approved grants whose expiry has passed as EXPIRED, in
+// every organization, and returns them so callers can audit the revocation.
+func (r *ElevationRepository) ExpireDue(now time.Time) ([]models.Elevation, error) {
+	rows, err := r.DB.Query(
+		`SELECT `+elevationColumns+` FROM elevation_requests WHERE status = ? AND expires_at <= ?`,
+		models.ElevationApproved, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	due, err := scanElevations(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []models.Elevation
+	for _, e := range due {
+		ok, err := r.ForOrg(e.OrgID).Close(e.ID, models.ElevationExpired, now, models.ElevationApproved)
+		if err != nil {
+			return expired, err
+		}
+		if ok {
+			expired = append(expired, e)
+		}
+	}
+	return expired, nil
+}
+
+func scanElevations(rows *sql.Rows) ([]models.Elevation, error) {
+	defer rows.Close()
+
+	var list []models.Elevation
+	for rows.Next() {
+		var e models.Elevation
+		var approvedAt, expiresAt, closedAt sql.NullTime
+		if err := rows.Scan(&e.ID, &e.OrgID, &e.UserID, &e.Role, &e.Table, &e.Action, &e.Justification, &e.DurationMinutes,
+			&e.Status, &e.ApprovedBy, &e.RequestedAt, &approvedAt, &expiresAt, &closedAt); err != nil {
+			return nil, err
+		}
+		if approvedAt.Valid {
+			e.ApprovedAt = &approvedAt.Time
+		}
+		if expiresAt.Valid {
+			e.ExpiresAt = &expiresAt.Time
+		}
+		if closedAt.Valid {
+			e.ClosedAt = &closedAt.Time
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Kinds of repository errors. Errors returned by the repositories match one
+// of them with errors.Is when the cause is known, so handlers can pick the
+// status without inspecting driver errors.
+var (
+	// ErrNotFound: the record, or a record it refers to, does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict: the write would duplicate a unique value, e.g. an email.
+	ErrConflict = errors.New("conflict")
+	// ErrConstraint: the write would break a foreign key or check, e.g.
+	// deleting a project that still has tasks, or restoring a task whose
+	// project is in the trash.
+	ErrConstraint = errors.New("constraint violation")
+	// ErrStale: the record changed since the version the update expected.
+	ErrStale = errors.New("record was changed by another request")
+)
+
+// kindError is an error of one of the kinds above with its own message.
+type kindError struct {
+	kind error
+	msg  string
+	err  error
+}
+
+func (e *kindError) Error() string { return e.msg }
+
+func (e *kindError) Unwrap() []error {
+	if e.err == nil {
+		return []error{e.kind}
+	}
+	return []error{e.kind, e.err}
+}
+
+func newKindError(kind error, msg string) error {
+	return &kindError{kind: kind, msg: msg}
+}
+
+// dbError classifies SQLite constraint failures as ErrConflict or
+// ErrConstraint, keeping the driver error wrapped. Other errors are returned
+// unchanged.
+func dbError(err error) error {
+	var se *sqlite.Error
+	if !errors.As(err, &se) {
+		return err
+	}
+	switch se.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		msg := "duplicate value"
+		// "constraint failed: UNIQUE constraint failed: users.email (2067)"
+		if i := strings.LastIndex(se.Error(), "failed: "); i >= 0 {
+			cols, _, _ := strings.Cut(se.Error()[i+len("failed: "):], " (")
+			msg = "duplicate value for " + cols
+		}
+		return &kindError{kind: ErrConflict, msg: msg, err: err}
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		return &kindError{kind: ErrConstraint, msg: "record is still referenced or refers to a missing record", err: err}
+	case sqlite3.SQLITE_CONSTRAINT_CHECK, sqlite3.SQLITE_CONSTRAINT_NOTNULL:
+		return &kindError{kind: ErrConstraint, msg: "value not allowed", err: err}
+	}
+	return err
+}
+
+// versionedUpdate checks the result of an update of table guarded by
+// "version = ?": when no row changed, the record either no longer exists or
+// is in the trash (ErrNotFound), or has another version (ErrStale).
+func versionedUpdate(db *sql.DB, table, orgID, id string, res sql.Result, err error) error {
+	if err != nil {
+		return dbError(err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	var exists int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE id = ? AND org_id = ? AND deleted_at IS NULL`, id, orgID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists == 0 {
+		return newKindError(ErrNotFound, strings.TrimSuffix(table, "s")+" not found")
+	}
+	return ErrStale
+}
@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+
+	"rbac-backend/internal/rbac"
+)
+
+// listIDs returns the ids of the rows of table in orgID matching filter,
+// ordered by id, leaving out the trash. table is always a constant supplied
+// by the repository, never user input.
+func listIDs(db *sql.DB, table, orgID string, filter rbac.RowFilter) ([]string, error) {
+	where, args := filter.And(table+".org_id = ? AND "+table+".deleted_at IS NULL", orgID)
+	query := `SELECT ` + table + `.id FROM ` + table + ` WHERE ` + where
+	rows, err := db.Query(query+` ORDER BY `+table+`.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// existsQueries look up a record by id within an organization, by kind.
+var existsQueries = map[string]string{
+	"project": `SELECT 1 FROM projects WHERE id = ? AND org_id = ? AND deleted_at IS NULL`,
+	"member":  `SELECT 1 FROM organization_members WHERE user_id = ? AND org_id = ?`,
+}
+
+// Exists returns a lookup for validate.Validator.Exists that reports whether
+// a project or member ("project", "member") with the id is in orgID. A
+// project in the trash does not exist.
+func Exists(db *sql.DB, orgID string) func(kind, id string) (bool, error) {
+	return func(kind, id string) (bool, error) {
+		query, ok := existsQueries[kind]
+		if !ok {
+			return false, fmt.Errorf("unknown record kind %q", kind)
+		}
+		var one int
+		err := db.QueryRow(query, id, orgID).Scan(&one)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return err == nil, err
+	}
+}
@@ -0,0 +1,132 @@
+package repositories
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"rbac-backend/internal/models"
+)
+
+// InvitationRepository records invitations to join an organization and lets
+// the invited account list, accept and decline them. Inviting is scoped to
+// the organization with ForOrg; the invitee's side is scoped to their email.
+type InvitationRepository struct {
+	DB    *sql.DB
+	OrgID string
+}
+
+func NewInvitationRepository(db *sql.DB) *InvitationRepository {
+	return &InvitationRepository{DB: db}
+}
+
+// ForOrg returns a copy of the repository scoped to orgID.
+func (r *InvitationRepository) ForOrg(orgID string) *InvitationRepository {
+	return &InvitationRepository{DB: r.DB, OrgID: orgID}
+}
+
+// normalizeEmail is how invitations store and match emails.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Invite records an invitation for email to join the organization with role,
+// replacing an earlier one for the same email. It returns ErrAlreadyMember
+// when the account with that email already belongs to the organization; it
+// does not say whether such an account exists anywhere else.
+func (r *InvitationRepository) Invite(id, email, role, invitedBy string) error {
+	if r.OrgID == "" {
+		return ErrNoOrganization
+	}
+	email = normalizeEmail(email)
+	var member int
+	err := r.DB.QueryRow(
+		`SELECT COUNT(*) FROM organization_members m JOIN users u ON u.id = m.user_id
+		 WHERE m.org_id = ? AND lower(u.email) = ?`,
+		r.OrgID, email,
+	).Scan(&member)
+	if err != nil {
+		return err
+	}
+	if member > 0 {
+		return ErrAlreadyMember
+	}
+	_, err = r.DB.Exec(
+		`INSERT INTO organization_invitations (id, org_id, email, role, invited_by, created_at) VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (org_id, email) DO UPDATE SET id = excluded.id, role = excluded.role,
+		     invited_by = excluded.invited_by, created_at = excluded.created_at`,
+		id, r.OrgID, email, role, invitedBy, time.Now().UTC(),
+	)
+	return dbError(err)
+}
+
+// ForEmail returns the invitations for email, oldest first.
+func (r *InvitationRepository) ForEmail(email string) ([]models.Invitation, error) {
+	rows, err := r.DB.Query(
+		`SELECT i.id, i.org_id, o.name, i.role, i.created_at
+		 FROM organization_invitations i JOIN organizations o ON o.id = i.org_id
+		 WHERE i.email = ? ORDER BY i.created_at, i.id`,
+		normalizeEmail(email),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Invitation{}
+	for rows.Next() {
+		var inv models.Invitation
+		if err := rows.Scan(&inv.ID, &inv.OrgID, &inv.OrgName, &inv.Role, &inv.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, inv)
+	}
+	return list, rows.Err()
+}
+
+// Get returns the invitation id if it was made for email, or nil.
+func (r *InvitationRepository) Get(id, email string) (*models.Invitation, error) {
+	list, err := r.ForEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	for _, inv := range list {
+		if inv.ID == id {
+			return &inv, nil
+		}
+	}
+	return nil, nil
+}
+
+// Accept makes userID a member of the invitation's organization with its role
+// and deletes the invitation. A user who already belongs to the organization
+// keeps the role they have.
+func (r *InvitationRepository) Accept(inv models.Invitation, userID string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		`INSERT OR IGNORE INTO organization_members (org_id, user_id, role) VALUES (?, ?, ?)`,
+		inv.OrgID, userID, inv.Role,
+	); err != nil {
+		return dbError(err)
+	}
+	if _, err := tx.Exec(`DELETE FROM organization_invitations WHERE id = ?`, inv.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Decline deletes the invitation id if it was made for email. It reports
+// false when there is no such invitation.
+func (r *InvitationRepository) Decline(id, email string) (bool, error) {
+	res, err := r.DB.Exec(`DELETE FROM organization_invitations WHERE id = ? AND email = ?`, id, normalizeEmail(email))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
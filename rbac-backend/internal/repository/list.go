@@ -0,0 +1,272 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"rbac-backend/internal/rbac"
+)
+
+// ErrBadCursor is returned for a cursor that was not issued for the list's
+// current sort order, or whose record is gone or no longer visible.
+var ErrBadCursor = errors.New("invalid cursor")
+
+// SortKey orders a list by a field.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ListQuery selects one page of a list. Fields are record fields of the
+// Listing; the handler has already checked the caller may use them.
+type ListQuery struct {
+	// Equal keeps records whose field equals the value. For collections
+	// (e.g. assignees) the value must be one of the items.
+	Equal map[string]string
+	// From and To bound date fields, inclusive, as "2006-01-02 15:04:05".
+	From, To map[string]string
+	// Text keeps records where one of TextFields contains it.
+	Text       string
+	TextFields []string
+	// Sort defaults to the Listing's DefaultSort. The id always breaks ties.
+	Sort   []SortKey
+	Limit  int
+	Cursor string
+	// Trash lists the records in the trash instead of the others. It needs
+	// a Listing with Deleted.
+	Trash bool
+}
+
+// Listing describes how the records of a table are listed and paged. Pages
+// use keyset pagination: the cursor names the last record of the page, whose
+// sort values are read again for the next one, so pages stay consistent
+// while other records are added or removed. Cursors hold no field values,
+// which the caller may not be allowed to see.
+type Listing struct {
+	From string // FROM clause
+	ID   string // id column
+	Org  string // organization column
+	// Deleted is the column set on records in the trash, which are left out
+	// unless the query asks for the trash. Empty for tables without one.
+	Deleted string
+	SQL     rbac.SQLMapping
+	// Dates are the fields holding timestamps. Values are compared on their
+	// first 19 characters, as SQLite and the Go driver write them differently.
+	Dates []string
+	// Sortable, Filters and Searchable list the fields that may be used to
+	// sort, filter with equality and search with text.
+	Sortable    []string
+	Filters     []string
+	Searchable  []string
+	DefaultSort []SortKey
+	// Params renames filters in the query string, e.g. "assignee" for the
+	// assignees collection.
+	Params map[string]string
+}
+
+type cursor struct {
+	Sort string `json:"s"`
+	ID   string `json:"id"`
+}
+
+// expr is the SQL expression a field is sorted and compared by.
+func (l Listing) expr(field string) string {
+	col := "COALESCE(" + l.SQL.Columns[field] + ", '')"
+	for _, d := range l.Dates {
+		if d == field {
+			return "substr(" + col + ", 1, 19)"
+		}
+	}
+	return col
+}
+
+// page returns the ids of the page of records in orgID matching filter and q,
+// in order, and the cursor of the next page ("" on the last one).
+func (l Listing) page(db *sql.DB, orgID string, filter rbac.RowFilter, q ListQuery) ([]string, string, error) {
+	conds := []string{l.Org + " = ?"}
+	args := []interface{}{orgID}
+	add := func(cond string, a ...interface{}) {
+		conds = append(conds, cond)
+		args = append(args, a...)
+	}
+	switch {
+	case q.Trash && l.Deleted == "":
+		return nil, "", errors.New("listing has no trash")
+	case q.Trash:
+		add(l.Deleted + " IS NOT NULL")
+	case l.Deleted != "":
+		add(l.Deleted + " IS NULL")
+	}
+
+	for _, field := range sortedKeys(q.Equal) {
+		if coll, ok := l.SQL.Collections[field]; ok {
+			add(fmt.Sprintf(coll, "?"), q.Equal[field])
+		} else {
+			add(l.SQL.Columns[field]+" = ?", q.Equal[field])
+		}
+	}
+	for _, field := range sortedKeys(q.From) {
+		add(l.expr(field)+" >= ?", q.From[field])
+	}
+	for _, field := range sortedKeys(q.To) {
+		add(l.expr(field)+" <= ?", q.To[field])
+	}
+	if q.Text != "" {
+		if len(q.TextFields) == 0 {
+			add("0") // nothing the caller may search
+		} else {
+			like := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q.Text) + "%"
+			var ors []string
+			for _, field := range q.TextFields {
+				ors = append(ors, l.expr(field)+` LIKE ? ESCAPE '\'`)
+				args = append(args, like)
+			}
+			conds = append(conds, "("+strings.Join(ors, " OR ")+")")
+		}
+	}
+
+	keys := q.Sort
+	if len(keys) == 0 {
+		keys = l.DefaultSort
+	}
+	exprs := make([]string, len(keys))
+	order := make([]string, len(keys)+1)
+	for i, k := range keys {
+		exprs[i] = l.expr(k.Field)
+		order[i] = exprs[i] + map[bool]string{false: " ASC", true: " DESC"}[k.Desc]
+	}
+	order[len(keys)] = l.ID + " ASC"
+	sortSpec := sortString(keys)
+
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil || c.Sort != sortSpec {
+			return nil, "", ErrBadCursor
+		}
+		values, err := l.sortValues(db, orgID, filter, exprs, c.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		// (k1, ..., kn, id) after the cursor's, each key in its direction.
+		var ors []string
+		var keysetArgs []interface{}
+		for i := 0; i <= len(keys); i++ {
+			var and []string
+			for j := 0; j < i; j++ {
+				and = append(and, exprs[j]+" = ?")
+				keysetArgs = append(keysetArgs, values[j])
+			}
+			if i == len(keys) {
+				and = append(and, l.ID+" > ?")
+				keysetArgs = append(keysetArgs, c.ID)
+			} else {
+				and = append(and, exprs[i]+map[bool]string{false: " > ?", true: " < ?"}[keys[i].Desc])
+				keysetArgs = append(keysetArgs, values[i])
+			}
+			ors = append(ors, "("+strings.Join(and, " AND ")+")")
+		}
+		add("("+strings.Join(ors, " OR ")+")", keysetArgs...)
+	}
+
+	where, args := filter.And(strings.Join(conds, " AND "), args...)
+	rows, err := db.Query(`SELECT `+l.ID+` FROM `+l.From+` WHERE `+where+
+		` ORDER BY `+strings.Join(order, ", ")+` LIMIT ?`, append(args, q.Limit+1)...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, "", err
+		}
+		if len(ids) == q.Limit {
+			return ids, encodeCursor(cursor{Sort: sortSpec, ID: ids[len(ids)-1]}), rows.Err()
+		}
+		ids = append(ids, id)
+	}
+	return ids, "", rows.Err()
+}
+
+// sortValues reads exprs for the cursor's record id. The record may have
+// moved to the trash since, but must still be in orgID and match filter, so
+// a forged cursor cannot compare against records the caller cannot view.
+func (l Listing) sortValues(db *sql.DB, orgID string, filter rbac.RowFilter, exprs []string, id string) ([]interface{}, error) {
+	where, args := filter.And(l.Org+" = ? AND "+l.ID+" = ?", orgID, id)
+	values := make([]interface{}, len(exprs))
+	dest := []interface{}{new(string)}
+	for i := range values {
+		dest = append(dest, &values[i])
+	}
+	err := db.QueryRow(`SELECT `+strings.Join(append([]string{l.ID}, exprs...), ", ")+` FROM `+l.From+` WHERE `+where, args...).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return nil, ErrBadCursor
+	}
+	return values, err
+}
+
+// inIDs is a filter on column for ids.
+func inIDs(column string, ids []string) rbac.RowFilter {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return rbac.RowFilter{Where: column + " IN (" + strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",") + ")", Args: args}
+}
+
+// inOrder returns items in the order of ids.
+func inOrder[T any](ids []string, items []T, id func(T) string) []T {
+	byID := make(map[string]T, len(items))
+	for _, item := range items {
+		byID[id(item)] = item
+	}
+	out := make([]T, 0, len(ids))
+	for _, i := range ids {
+		if item, ok := byID[i]; ok {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// sortString is the sort parameter form of keys, e.g. "-created_at,title".
+func sortString(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.Field
+		if k.Desc {
+			parts[i] = "-" + k.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	return c, json.Unmarshal(b, &c)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
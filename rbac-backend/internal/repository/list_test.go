@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+)
+
+func TestListTasksPagesWithCursor(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewTaskRepository(db).ForOrg("o1")
+	for i, status := range []string{"TODO", "DONE", "TODO", "DONE", "TODO"} {
+		task := models.Task{ID: fmt.Sprintf("t%d", i), ProjectID: "p1", Title: fmt.Sprintf("Task %d", i), CreatedBy: "u1", Status: status}
+		if i == 3 {
+			task.Description = "needs 100% review"
+		}
+		if err := repo.CreateTask(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []string
+	q := ListQuery{Sort: []SortKey{{Field: "status"}, {Field: "title", Desc: true}}, Limit: 2}
+	for page := 0; page < 5; page++ {
+		tasks, next, err := repo.ListTasks(rbac.RowFilter{}, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, task := range tasks {
+			got = append(got, task.ID)
+		}
+		if next == "" {
+			break
+		}
+		q.Cursor = next
+	}
+	if want := []string{"t3", "t1", "t4", "t2", "t0"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("pages = %v, want %v", got, want)
+	}
+
+	// The cursor names the last record; its sort values stay in the database.
+	if c, err := decodeCursor(q.Cursor); err != nil || c != (cursor{Sort: "status,-title", ID: "t2"}) {
+		t.Errorf("cursor = %+v, %v", c, err)
+	}
+	hidden := rbac.RowFilter{Where: "tasks.id <> ?", Args: []interface{}{"t2"}}
+	if _, _, err := repo.ListTasks(hidden, q); !errors.Is(err, ErrBadCursor) {
+		t.Errorf("cursor on a record outside the filter: got %v, want ErrBadCursor", err)
+	}
+
+	q.Sort = nil
+	if _, _, err := repo.ListTasks(rbac.RowFilter{}, q); !errors.Is(err, ErrBadCursor) {
+		t.Fatalf("cursor for another sort: got %v, want ErrBadCursor", err)
+	}
+}
+
+func TestListTasksFilters(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewTaskRepository(db).ForOrg("o1")
+	for i, status := range []string{"TODO", "DONE", "TODO"} {
+		task := models.Task{ID: fmt.Sprintf("t%d", i), ProjectID: "p1", Title: fmt.Sprintf("Task %d", i), CreatedBy: "u1", Status: status, Assignees: []string{fmt.Sprintf("u%d", i)}}
+		if i == 2 {
+			task.Description = "needs 100% review"
+		}
+		if err := repo.CreateTask(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Exec(`UPDATE tasks SET created_at = '2024-05-1' || substr(id, 2) || ' 10:00:00'`)
+
+	cases := []struct {
+		name string
+		q    ListQuery
+		want []string
+	}{
+		{"status", ListQuery{Equal: map[string]string{"status": "TODO"}}, []string{"t2", "t0"}},
+		{"assignee", ListQuery{Equal: map[string]string{"assignees": "u1"}}, []string{"t1"}},
+		{"dates", ListQuery{From: map[string]string{"created_at": "2024-05-11 00:00:00"}, To: map[string]string{"created_at": "2024-05-11 23:59:59"}}, []string{"t1"}},
+		{"text escapes wildcards", ListQuery{Text: "100%", TextFields: []string{"title", "description"}}, []string{"t2"}},
+		{"text without fields", ListQuery{Text: "Task"}, nil},
+	}
+	for _, c := range cases {
+		c.q.Limit = 10
+		tasks, _, err := repo.ListTasks(rbac.RowFilter{}, c.q)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		var ids []string
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+		if !reflect.DeepEqual(ids, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, ids, c.want)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+package repositories
+
+import "errors"
+
+// ErrNoOrganization is returned when writing through a repository that has
+// not been scoped to an organization with ForOrg. Reads through such a
+// repository find nothing.
+var ErrNoOrganization = errors.New("repository is not scoped to an organization")
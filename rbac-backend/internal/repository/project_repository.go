@@ -4,38 +4,56 @@ import (
 	"database/sql"
 	"errors"
 	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
 	"strings"
+	"time"
 )
 
+// ProjectRepository reads and writes the projects of one organization.
 type ProjectRepository struct {
-	DB *sql.DB
+	DB    *sql.DB
+	OrgID string
 }
 
 func NewProjectRepository(db *sql.DB) *ProjectRepository {
 	return &ProjectRepository{DB: db}
 }
+
+// ForOrg returns a copy of the repository scoped to orgID.
+func (r *ProjectRepository) ForOrg(orgID string) *ProjectRepository {
+	return &ProjectRepository{DB: r.DB, OrgID: orgID}
+}
+
 func (r *ProjectRepository) CreateProject(project models.Project) error {
+	if r.OrgID == "" {
+		return ErrNoOrganization
+	}
 
 	_, err := r.DB.Exec(
-		`INSERT INTO projects (id, name, description, created_by)
-		 VALUES (?, ?, ?, ?)`,
+		`INSERT INTO projects (id, org_id, name, description, created_by)
+		 VALUES (?, ?, ?, ?, ?)`,
 		project.ID,
+		r.OrgID,
 		project.Name,
 		project.Description,
 		project.CreatedBy,
 	)
 
-	return err
+	return dbError(err)
 }
 func (r *ProjectRepository) CreateProjectDynamic(data map[string]interface{}) error {
 
 	if len(data) == 0 {
 		return errors.New("no data provided")
 	}
+	if r.OrgID == "" {
+		return ErrNoOrganization
+	}
+	delete(data, "org_id") // always the repository's organization
 
-	columns := []string{}
-	placeholders := []string{}
-	args := []interface{}{}
+	columns := []string{"org_id"}
+	placeholders := []string{"?"}
+	args := []interface{}{r.OrgID}
 
 	for col, val := range data {
 		columns = append(columns, col)
@@ -43,24 +61,9 @@ func (r *ProjectRepository) CreateProjectDynamic(data map[string]interface{}) er
 		args = append(args, val)
 	}
 
-	_ = "INSERT INTO projects (" +
-		strings.Join(columns, ",") +
-		") VALUES (" +
-		strings.Join(placeholders, ",") +
-		")"
-
 	var assignments []string
 	if a, ok := data["assigned_employees"]; ok {
-		switch v := a.(type) {
-		case []string:
-			assignments = v
-		case []interface{}:
-			for _, item := range v {
-				if s, ok := item.(string); ok {
-					assignments = append(assignments, s)
-				}
-			}
-		}
+		assignments = assignmentList(a)
 
 		newCols := []string{}
 		newPlaceholders := []string{}
@@ -80,7 +83,7 @@ func (r *ProjectRepository) CreateProjectDynamic(data map[string]interface{}) er
 
 	_, err := r.DB.Exec("INSERT INTO projects ("+strings.Join(columns, ",")+") VALUES ("+strings.Join(placeholders, ",")+")", args...)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
 	if len(assignments) > 0 {
@@ -101,7 +104,7 @@ func (r *ProjectRepository) CreateProjectDynamic(data map[string]interface{}) er
 		for _, uid := range assignments {
 			if _, err := stmt.Exec(pid, uid); err != nil {
 				tx.Rollback()
-				return err
+				return dbError(err)
 			}
 		}
 		if err := tx.Commit(); err != nil {
@@ -111,43 +114,151 @@ func (r *ProjectRepository) CreateProjectDynamic(data map[string]interface{}) er
 	return nil
 }
 
-func (r *ProjectRepository) GetProjects() ([]models.Project, error) {
+// ProjectSQL maps project record fields onto SQL so row-level policies can be
+// applied inside queries.
+var ProjectSQL = rbac.SQLMapping{
+	Columns: map[string]string{
+		"id":          "projects.id",
+		"name":        "projects.name",
+		"description": "projects.description",
+		"created_by":  "projects.created_by",
+	},
+	Collections: map[string]string{
+		"assigned_employees": "EXISTS (SELECT 1 FROM project_assignments pa WHERE pa.project_id = projects.id AND pa.user_id = %s)",
+	},
+}
 
-	rows, err := r.DB.Query(`SELECT id, name, description, created_by FROM projects`)
+func init() { rbac.RegisterSQLMapping(rbac.TableProjects, ProjectSQL) }
+
+// ProjectListing is how GET /projects lists projects.
+var ProjectListing = Listing{
+	From:        "projects",
+	ID:          "projects.id",
+	Org:         "projects.org_id",
+	Deleted:     "projects.deleted_at",
+	SQL:         ProjectSQL,
+	Sortable:    []string{"name"},
+	Filters:     []string{"created_by", "assigned_employees"},
+	Searchable:  []string{"name", "description"},
+	DefaultSort: []SortKey{{Field: "name"}},
+	Params:      map[string]string{"assignee": "assigned_employees"},
+}
+
+// ListProjects returns the page of projects matching filter and q, and the
+// cursor of the next page.
+func (r *ProjectRepository) ListProjects(filter rbac.RowFilter, q ListQuery) ([]models.Project, string, error) {
+	ids, next, err := ProjectListing.page(r.DB, r.OrgID, filter, q)
+	if err != nil || len(ids) == 0 {
+		return nil, next, err
+	}
+	projects, err := r.GetProjectsByIDs(ids)
+	return projects, next, err
+}
+
+// GetProjectsByIDs returns the projects with ids, in the order of ids,
+// whether or not they are in the trash.
+func (r *ProjectRepository) GetProjectsByIDs(ids []string) ([]models.Project, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	projects, err := r.GetProjects(inIDs("projects.id", ids))
+	if err != nil {
+		return nil, err
+	}
+	return inOrder(ids, projects, func(p models.Project) string { return p.ID }), nil
+}
+
+// GetProjects returns the projects matching filter together with their
+// assignments, whether or not they are in the trash. Assignments are loaded
+// with a single query for all rows.
+func (r *ProjectRepository) GetProjects(filter rbac.RowFilter) ([]models.Project, error) {
+	where, args := filter.And("projects.org_id = ?", r.OrgID)
+	query := `SELECT projects.id, projects.name, projects.description, projects.created_by, projects.version, projects.deleted_at, projects.deleted_by FROM projects WHERE ` + where
+
+	rows, err := r.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	var projects []models.Project
+	index := map[string]int{}
 
 	for rows.Next() {
 		var p models.Project
+		var desc, deletedBy sql.NullString
+		var deleted sql.NullTime
 
-		err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.CreatedBy)
+		err := rows.Scan(&p.ID, &p.Name, &desc, &p.CreatedBy, &p.Version, &deleted, &deletedBy)
 		if err != nil {
 			return nil, err
 		}
-
-		assignRows, err := r.DB.Query(`SELECT user_id FROM project_assignments WHERE project_id = ?`, p.ID)
-		if err == nil {
-			defer assignRows.Close()
-			var assigns []string
-			for assignRows.Next() {
-				var uid string
-				if err := assignRows.Scan(&uid); err == nil {
-					assigns = append(assigns, uid)
-				}
-			}
-			p.AssignedEmployees = assigns
+		p.Description = desc.String
+		if deleted.Valid {
+			p.DeletedAt = &deleted.Time
 		}
+		p.DeletedBy = deletedBy.String
 
+		index[p.ID] = len(projects)
 		projects = append(projects, p)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(projects) == 0 {
+		return projects, nil
+	}
+
+	assignQuery := `SELECT project_id, user_id FROM project_assignments WHERE project_id IN (SELECT projects.id FROM projects WHERE ` + where + `)`
+
+	assignRows, err := r.DB.Query(assignQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer assignRows.Close()
+	for assignRows.Next() {
+		var pid, uid string
+		if err := assignRows.Scan(&pid, &uid); err != nil {
+			return nil, err
+		}
+		if i, ok := index[pid]; ok {
+			projects[i].AssignedEmployees = append(projects[i].AssignedEmployees, uid)
+		}
+	}
 
-	return projects, nil
+	return projects, assignRows.Err()
 }
-func (r *ProjectRepository) UpdateProjectDynamic(data map[string]interface{}) error {
+
+// ListProjectIDs returns the ids of the projects matching filter.
+func (r *ProjectRepository) ListProjectIDs(filter rbac.RowFilter) ([]string, error) {
+	return listIDs(r.DB, "projects", r.OrgID, filter)
+}
+
+// GetProjectByID returns the project with its assignments, or nil when it
+// does not exist or is in the trash.
+func (r *ProjectRepository) GetProjectByID(id string) (*models.Project, error) {
+	return r.getProject("projects.id = ? AND projects.deleted_at IS NULL", id)
+}
+
+// GetTrashedProject returns the project if it is in the trash, and nil
+// otherwise.
+func (r *ProjectRepository) GetTrashedProject(id string) (*models.Project, error) {
+	return r.getProject("projects.id = ? AND projects.deleted_at IS NOT NULL", id)
+}
+
+func (r *ProjectRepository) getProject(where string, id string) (*models.Project, error) {
+	projects, err := r.GetProjects(rbac.RowFilter{Where: where, Args: []interface{}{id}})
+	if err != nil || len(projects) == 0 {
+		return nil, err
+	}
+	return &projects[0], nil
+}
+
+// UpdateProjectDynamic sets the columns in data on the project data["id"] if
+// it is still at version, and increments the version. It returns ErrStale
+// when another update came first, and ErrNotFound when the project is in the
+// trash.
+func (r *ProjectRepository) UpdateProjectDynamic(data map[string]interface{}, version int) error {
 
 	idVal, ok := data["id"]
 	if !ok {
@@ -155,34 +266,120 @@ func (r *ProjectRepository) UpdateProjectDynamic(data map[string]interface{}) er
 	}
 	id := idVal.(string)
 
-	delete(data, "id") // do not update ID
+	delete(data, "id")     // do not update ID
+	delete(data, "org_id") // projects never move between organizations
 
 	if len(data) == 0 {
 		return errors.New("no editable fields provided")
 	}
 
+	// assigned_employees is stored in project_assignments; it is replaced in
+	// the same transaction as the version bump.
+	assigned, assign := data["assigned_employees"]
+	delete(data, "assigned_employees")
+
 	query := "UPDATE projects SET "
 	args := []interface{}{}
-	i := 0
 
 	for field, value := range data {
-		if i > 0 {
-			query += ", "
-		}
-		query += field + "=?"
+		query += field + "=?, "
 		args = append(args, value)
-		i++
 	}
 
-	query += " WHERE id=?"
-	args = append(args, id)
+	query += "version=version+1 WHERE id=? AND org_id=? AND deleted_at IS NULL AND version=?"
+	args = append(args, id, r.OrgID, version)
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return dbError(err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		// Tell a stale version from a missing project outside the transaction.
+		tx.Rollback()
+		return versionedUpdate(r.DB, "projects", r.OrgID, id, res, err)
+	}
+
+	if assign {
+		if _, err := tx.Exec(`DELETE FROM project_assignments WHERE project_id = ?`, id); err != nil {
+			return err
+		}
+		for _, uid := range assignmentList(assigned) {
+			if _, err := tx.Exec(`INSERT OR REPLACE INTO project_assignments (project_id, user_id) VALUES (?, ?)`, id, uid); err != nil {
+				return dbError(err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// assignmentList returns the user ids of an assigned_employees value, as
+// decoded from JSON or set by the caller.
+func assignmentList(v interface{}) []string {
+	switch v := v.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		var ids []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				ids = append(ids, s)
+			}
+		}
+		return ids
+	}
+	return nil
+}
 
-	_, err := r.DB.Exec(query, args...)
-	return err
+// DeleteProject moves the project to the trash, recording userID as the one
+// who deleted it. It returns ErrConstraint while the project has tasks
+// outside the trash.
+func (r *ProjectRepository) DeleteProject(id, userID string) error {
+	res, err := r.DB.Exec(`UPDATE projects SET deleted_at=?, deleted_by=?, version=version+1
+		WHERE id=? AND org_id=? AND deleted_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM tasks WHERE tasks.project_id = projects.id AND tasks.deleted_at IS NULL)`,
+		time.Now().UTC(), userID, id, r.OrgID)
+	notFound := newKindError(ErrNotFound, "project not found")
+	if err := changedOne(res, err, notFound); err != notFound {
+		return err
+	}
+	// Nothing changed: the project does not exist, or still has tasks.
+	p, err := r.GetProjectByID(id)
+	switch {
+	case err != nil:
+		return err
+	case p == nil:
+		return notFound
+	}
+	return errProjectHasTasks
 }
 
-func (r *ProjectRepository) DeleteProject(id string) error {
+// RestoreProject takes the project out of the trash. Its tasks in the trash
+// stay there.
+func (r *ProjectRepository) RestoreProject(id string) error {
+	res, err := r.DB.Exec(`UPDATE projects SET deleted_at=NULL, deleted_by=NULL, version=version+1 WHERE id=? AND org_id=? AND deleted_at IS NOT NULL`, id, r.OrgID)
+	return changedOne(res, err, errNotInTrash)
+}
 
-	_, err := r.DB.Exec(`DELETE FROM projects WHERE id=?`, id)
-	return err
+// PurgeProject deletes the project from the trash for good. It returns
+// ErrConstraint while its tasks are still in the trash.
+func (r *ProjectRepository) PurgeProject(id string) error {
+	res, err := r.DB.Exec(`DELETE FROM projects WHERE id=? AND org_id=? AND deleted_at IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM tasks WHERE tasks.project_id = projects.id)`, id, r.OrgID)
+	if err := changedOne(res, err, errNotInTrash); err != errNotInTrash {
+		return err
+	}
+	p, err := r.GetTrashedProject(id)
+	switch {
+	case err != nil:
+		return err
+	case p == nil:
+		return errNotInTrash
+	}
+	return errProjectHasTrashedTasks
 }
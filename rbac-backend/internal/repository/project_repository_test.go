@@ -0,0 +1,27 @@
+package repositories
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUpdateProjectKeepsAssignmentsOnStaleVersion(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewProjectRepository(db).ForOrg("o1")
+
+	if err := repo.UpdateProjectDynamic(map[string]interface{}{"id": "p1", "assigned_employees": []interface{}{"u1", "u2"}}, 1); err != nil {
+		t.Fatal(err)
+	}
+	err := repo.UpdateProjectDynamic(map[string]interface{}{"id": "p1", "assigned_employees": []interface{}{"u3"}}, 1)
+	if !errors.Is(err, ErrStale) {
+		t.Fatalf("update at version 1 of a version 2 project: %v, want ErrStale", err)
+	}
+
+	p, err := repo.GetProjectByID("p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Version != 2 || len(p.AssignedEmployees) != 2 {
+		t.Errorf("project after the stale update: version %d, assigned %v; want 2, [u1 u2]", p.Version, p.AssignedEmployees)
+	}
+}
@@ -0,0 +1,261 @@
+package repositories
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+
+	"rbac-backend/internal/rbac"
+)
+
+// Searchers mark matches in snippets with these control characters, which
+// cannot appear in stored text; Markup turns them into <mark> tags.
+const (
+	matchStart = "\x02"
+	matchEnd   = "\x03"
+)
+
+// snippetTokens is the length of a snippet in words.
+const snippetTokens = 12
+
+// SearchKind describes a kind of record that can be searched.
+type SearchKind struct {
+	Table string // table, which is also the RBAC table
+	SQL   rbac.SQLMapping
+	// Fields maps the searchable record fields to the search_index column
+	// holding them.
+	Fields map[string]string
+}
+
+// SearchKinds are the records GET /search finds, by kind. Records in the
+// trash are never found.
+var SearchKinds = map[string]SearchKind{
+	"project": {Table: "projects", SQL: ProjectSQL, Fields: map[string]string{"name": "title", "description": "body"}},
+	"task":    {Table: "tasks", SQL: TaskSQL, Fields: map[string]string{"title": "title", "description": "body"}},
+}
+
+// SearchScope is what a caller may search in one kind of record.
+type SearchScope struct {
+	// Fields are the fields to match and snippet, those the caller can view.
+	Fields []string
+	// Filter keeps the records the caller can view.
+	Filter rbac.RowFilter
+}
+
+// SearchQuery is one search. Records match when each word of Text is found in
+// one of the scope's fields.
+type SearchQuery struct {
+	Text   string
+	Scopes map[string]SearchScope // by kind; other kinds are not searched
+	Limit  int
+}
+
+// SearchHit is a matching record. Snippets holds, for each field that
+// matched, an excerpt with the matches marked (see Markup).
+type SearchHit struct {
+	Kind     string
+	ID       string
+	Score    float64 // lower is better
+	Snippets map[string]string
+}
+
+// Searcher finds records by text. FTS5Searcher uses the SQLite full-text
+// index; LikeSearcher only needs the tables, for databases without it.
+type Searcher interface {
+	Search(orgID string, q SearchQuery) ([]SearchHit, error)
+}
+
+// NewSearcher returns an FTS5Searcher when db has the search index, and a
+// LikeSearcher otherwise.
+func NewSearcher(db *sql.DB) Searcher {
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'search_index'`).Scan(&n); err == nil && n > 0 {
+		return FTS5Searcher{DB: db}
+	}
+	return LikeSearcher{DB: db}
+}
+
+// FTS5Searcher searches the search_index FTS5 table, ranked by bm25. Words
+// match as prefixes of indexed words, ignoring case and diacritics.
+type FTS5Searcher struct {
+	DB *sql.DB
+}
+
+func (s FTS5Searcher) Search(orgID string, q SearchQuery) ([]SearchHit, error) {
+	terms := strings.Fields(q.Text)
+	return search(q, func(kind string, k SearchKind, scope SearchScope) ([]SearchHit, error) {
+		// {title body} : ("word"* "other"*), quoting each word so that
+		// none is read as FTS5 syntax.
+		var columns, phrases []string
+		for _, f := range scope.Fields {
+			columns = append(columns, k.Fields[f])
+		}
+		for _, t := range terms {
+			phrases = append(phrases, `"`+strings.ReplaceAll(t, `"`, `""`)+`"*`)
+		}
+		match := "{" + strings.Join(columns, " ") + "} : (" + strings.Join(phrases, " ") + ")"
+
+		where, args := scope.Filter.And(`search_index MATCH ? AND search_index.kind = ? AND `+k.Table+`.org_id = ? AND `+k.Table+`.deleted_at IS NULL`, match, kind, orgID)
+		snippet := func(column int) string {
+			return fmt.Sprintf(`snippet(search_index, %d, char(2), char(3), '…', %d)`, column, snippetTokens)
+		}
+		rows, err := s.DB.Query(`SELECT search_index.record_id, bm25(search_index), `+snippet(2)+`, `+snippet(3)+
+			` FROM search_index JOIN `+k.Table+` ON `+k.SQL.Columns["id"]+` = search_index.record_id`+
+			` WHERE `+where+` ORDER BY bm25(search_index) LIMIT ?`, append(args, q.Limit)...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var hits []SearchHit
+		for rows.Next() {
+			h := SearchHit{Kind: kind, Snippets: map[string]string{}}
+			var title, body string
+			if err := rows.Scan(&h.ID, &h.Score, &title, &body); err != nil {
+				return nil, err
+			}
+			byColumn := map[string]string{"title": title, "body": body}
+			for _, f := range scope.Fields {
+				if text := byColumn[k.Fields[f]]; strings.Contains(text, matchStart) {
+					h.Snippets[f] = text
+				}
+			}
+			hits = append(hits, h)
+		}
+		return hits, rows.Err()
+	})
+}
+
+// LikeSearcher matches words with LIKE on the tables themselves, anywhere in
+// the text and ignoring ASCII case. It does not rank: hits come in id order,
+// with score 0.
+type LikeSearcher struct {
+	DB *sql.DB
+}
+
+func (s LikeSearcher) Search(orgID string, q SearchQuery) ([]SearchHit, error) {
+	terms := strings.Fields(q.Text)
+	mark := highlighter(terms)
+	return search(q, func(kind string, k SearchKind, scope SearchScope) ([]SearchHit, error) {
+		conds := []string{k.Table + ".org_id = ?", k.Table + ".deleted_at IS NULL"}
+		args := []interface{}{orgID}
+		columns := make([]string, len(scope.Fields))
+		for i, f := range scope.Fields {
+			columns[i] = "COALESCE(" + k.SQL.Columns[f] + ", '')"
+		}
+		for _, t := range terms {
+			like := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(t) + "%"
+			var ors []string
+			for _, c := range columns {
+				ors = append(ors, c+` LIKE ? ESCAPE '\'`)
+				args = append(args, like)
+			}
+			conds = append(conds, "("+strings.Join(ors, " OR ")+")")
+		}
+
+		where, args := scope.Filter.And(strings.Join(conds, " AND "), args...)
+		rows, err := s.DB.Query(`SELECT `+k.SQL.Columns["id"]+`, `+strings.Join(columns, ", ")+
+			` FROM `+k.Table+` WHERE `+where+` ORDER BY `+k.SQL.Columns["id"]+` LIMIT ?`, append(args, q.Limit)...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var hits []SearchHit
+		for rows.Next() {
+			h := SearchHit{Kind: kind, Snippets: map[string]string{}}
+			texts := make([]string, len(columns))
+			dest := []interface{}{&h.ID}
+			for i := range texts {
+				dest = append(dest, &texts[i])
+			}
+			if err := rows.Scan(dest...); err != nil {
+				return nil, err
+			}
+			for i, f := range scope.Fields {
+				if snippet, ok := mark(texts[i]); ok {
+					h.Snippets[f] = snippet
+				}
+			}
+			hits = append(hits, h)
+		}
+		return hits, rows.Err()
+	})
+}
+
+// search runs one query per kind in scope, in kind order, and merges the hits
+// by score. A kind with no fields to search is skipped.
+func search(q SearchQuery, kind func(string, SearchKind, SearchScope) ([]SearchHit, error)) ([]SearchHit, error) {
+	if len(strings.Fields(q.Text)) == 0 {
+		return nil, nil
+	}
+	names := make([]string, 0, len(q.Scopes))
+	for name := range q.Scopes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var hits []SearchHit
+	for _, name := range names {
+		scope := q.Scopes[name]
+		if len(scope.Fields) == 0 {
+			continue
+		}
+		found, err := kind(name, SearchKinds[name], scope)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, found...)
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score < hits[j].Score })
+	if len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+	return hits, nil
+}
+
+// highlighter returns a function that marks, in each word of text, the first
+// occurrence of one of terms, ignoring case, and cuts the text to
+// snippetTokens words around the first match, like FTS5's snippet(). ok is
+// false when nothing matched.
+func highlighter(terms []string) func(text string) (snippet string, ok bool) {
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = regexp.QuoteMeta(t)
+	}
+	re := regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+	return func(text string) (string, bool) {
+		words := strings.Fields(text)
+		first := -1
+		for i, w := range words {
+			if loc := re.FindStringIndex(w); loc != nil {
+				words[i] = w[:loc[0]] + matchStart + w[loc[0]:loc[1]] + matchEnd + w[loc[1]:]
+				if first < 0 {
+					first = i
+				}
+			}
+		}
+		if first < 0 {
+			return "", false
+		}
+		start := max(0, min(first-snippetTokens/4, len(words)-snippetTokens))
+		end := min(len(words), start+snippetTokens)
+		snippet := strings.Join(words[start:end], " ")
+		if start > 0 {
+			snippet = "…" + snippet
+		}
+		if end < len(words) {
+			snippet += "…"
+		}
+		return snippet, true
+	}
+}
+
+// Markup returns a snippet as HTML: the text escaped and matches wrapped in
+// <mark>.
+func Markup(snippet string) string {
+	return strings.NewReplacer(matchStart, "<mark>", matchEnd, "</mark>").Replace(html.EscapeString(snippet))
+}
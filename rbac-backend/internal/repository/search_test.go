@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"database/sql"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"rbac-backend/internal/rbac"
+)
+
+func setupSearchDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := `
+    CREATE TABLE projects (id TEXT PRIMARY KEY, org_id TEXT NOT NULL, name TEXT, description TEXT, created_by TEXT, deleted_at DATETIME);
+    CREATE TABLE tasks (id TEXT PRIMARY KEY, org_id TEXT NOT NULL, project_id TEXT, title TEXT, description TEXT, created_by TEXT, assignee TEXT, deleted_at DATETIME);
+    INSERT INTO projects VALUES ('p1', 'o1', 'Login revamp', 'Rework the <login> flow', 'u1', NULL);
+    `
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+	index, err := os.ReadFile("../../migrations/016_create_search_index.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(string(index)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`
+    INSERT INTO tasks VALUES ('t1', 'o1', 'p1', 'Fix logins on Safari', 'Users are logged out', 'u1', '[]', NULL);
+    INSERT INTO tasks VALUES ('t2', 'o1', 'p1', 'Write docs', 'Explain the login page', 'u2', '[]', NULL);
+    INSERT INTO tasks VALUES ('t3', 'o2', 'p9', 'Login for other org', '', 'u9', '[]', NULL);
+    INSERT INTO tasks VALUES ('t4', 'o1', 'p1', 'Old title', '', 'u1', '[]', NULL);
+    UPDATE tasks SET title = 'Login audit' WHERE id = 't4';
+    DELETE FROM tasks WHERE id = 't2';
+    INSERT INTO tasks VALUES ('t2', 'o1', 'p1', 'Write docs', 'Explain the login page', 'u2', '[]', NULL);
+    INSERT INTO tasks VALUES ('t5', 'o1', 'p1', 'Login in the trash', '', 'u1', '[]', '2024-01-01 00:00:00');
+    `); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func hitIDs(hits []SearchHit) []string {
+	var ids []string
+	for _, h := range hits {
+		ids = append(ids, h.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestSearchersAgree(t *testing.T) {
+	db := setupSearchDB(t)
+	if _, ok := NewSearcher(db).(FTS5Searcher); !ok {
+		t.Fatal("NewSearcher did not pick FTS5 with the index present")
+	}
+	all := map[string]SearchScope{
+		"project": {Fields: []string{"name", "description"}},
+		"task":    {Fields: []string{"title", "description"}},
+	}
+	mine := rbac.RowFilter{Where: "tasks.created_by = ?", Args: []interface{}{"u1"}}
+
+	cases := []struct {
+		name   string
+		text   string
+		scopes map[string]SearchScope
+		want   []string
+	}{
+		{"all fields", "login", all, []string{"p1", "t1", "t2", "t4"}},
+		{"every word", "login safari", all, []string{"t1"}},
+		{"viewable fields only", "login", map[string]SearchScope{"task": {Fields: []string{"title"}}}, []string{"t1", "t4"}},
+		{"row filter", "login", map[string]SearchScope{"task": {Fields: []string{"title", "description"}, Filter: mine}}, []string{"t1", "t4"}},
+		{"no fields", "login", map[string]SearchScope{"task": {}}, nil},
+		{"syntax is literal", `login" OR "docs`, all, nil},
+	}
+	for _, s := range []Searcher{FTS5Searcher{DB: db}, LikeSearcher{DB: db}} {
+		for _, c := range cases {
+			hits, err := s.Search("o1", SearchQuery{Text: c.text, Scopes: c.scopes, Limit: 10})
+			if err != nil {
+				t.Fatalf("%T %s: %v", s, c.name, err)
+			}
+			if got := hitIDs(hits); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("%T %s: got %v, want %v", s, c.name, got, c.want)
+			}
+		}
+	}
+}
+
+func TestSearchSnippetsMarkVisibleMatches(t *testing.T) {
+	db := setupSearchDB(t)
+	scopes := map[string]SearchScope{"project": {Fields: []string{"name", "description"}}}
+	for _, s := range []Searcher{FTS5Searcher{DB: db}, LikeSearcher{DB: db}} {
+		hits, err := s.Search("o1", SearchQuery{Text: "login", Scopes: scopes, Limit: 10})
+		if err != nil || len(hits) != 1 {
+			t.Fatalf("%T: %v, %v", s, hits, err)
+		}
+		want := map[string]string{
+			"name":        "<mark>Login</mark> revamp",
+			"description": "Rework the &lt;<mark>login</mark>&gt; flow",
+		}
+		got := map[string]string{}
+		for f, snippet := range hits[0].Snippets {
+			got[f] = Markup(snippet)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%T: snippets %v, want %v", s, got, want)
+		}
+	}
+}
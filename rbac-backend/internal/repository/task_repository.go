@@ -4,98 +4,147 @@ import (
 	"database/sql"
 	"encoding/json"
 	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
 	"time"
 )
 
+// TaskRepository reads and writes the tasks of one organization.
 type TaskRepository struct {
-	DB *sql.DB
+	DB    *sql.DB
+	OrgID string
 }
 
 func NewTaskRepository(db *sql.DB) *TaskRepository {
 	return &TaskRepository{DB: db}
 }
 
+// ForOrg returns a copy of the repository scoped to orgID.
+func (r *TaskRepository) ForOrg(orgID string) *TaskRepository {
+	return &TaskRepository{DB: r.DB, OrgID: orgID}
+}
+
+// ErrProjectNotFound is returned when creating a task for a project that is
+// not in the repository's organization, or is in the trash.
+var ErrProjectNotFound = newKindError(ErrNotFound, "project not found")
+
+// TaskSQL maps task record fields onto SQL so row-level policies can be
+// applied inside queries. Assignees are stored as a JSON array in tasks.assignee.
+var TaskSQL = rbac.SQLMapping{
+	Columns: map[string]string{
+		"id":           "tasks.id",
+		"project_id":   "tasks.project_id",
+		"title":        "tasks.title",
+		"description":  "tasks.description",
+		"status":       "tasks.status",
+		"created_by":   "tasks.created_by",
+		"started_at":   "tasks.started_at",
+		"completed_at": "tasks.completed_at",
+		"created_at":   "tasks.created_at",
+		"updated_at":   "tasks.updated_at",
+	},
+	Collections: map[string]string{
+		"assignees": "EXISTS (SELECT 1 FROM json_each(CASE WHEN json_valid(tasks.assignee) THEN tasks.assignee ELSE '[]' END) WHERE json_each.value = %s)",
+	},
+}
+
+func init() { rbac.RegisterSQLMapping(rbac.TableTasks, TaskSQL) }
+
+// CreateTask inserts t, provided its project belongs to the organization and
+// is not in the trash.
 func (r *TaskRepository) CreateTask(t models.Task) error {
+	if r.OrgID == "" {
+		return ErrNoOrganization
+	}
 	var ajson sql.NullString
 	if len(t.Assignees) > 0 {
 		b, _ := json.Marshal(t.Assignees)
 		ajson = sql.NullString{String: string(b), Valid: true}
 	}
 
-	_, err := r.DB.Exec(`INSERT INTO tasks (id, project_id, title, description, status, assignee, created_by, started_at, completed_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		t.ID, t.ProjectID, t.Title, t.Description, t.Status, ajson, t.CreatedBy, t.StartedAt, t.CompletedAt,
+	res, err := r.DB.Exec(`INSERT INTO tasks (id, org_id, project_id, title, description, status, assignee, created_by, started_at, completed_at)
+		SELECT ?, org_id, id, ?, ?, ?, ?, ?, ?, ? FROM projects WHERE id = ? AND org_id = ? AND deleted_at IS NULL`,
+		t.ID, t.Title, t.Description, t.Status, ajson, t.CreatedBy, t.StartedAt, t.CompletedAt, t.ProjectID, r.OrgID,
 	)
-	return err
+	if err != nil {
+		return dbError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrProjectNotFound
+	}
+	return nil
 }
 
+// GetTaskByID returns the task, or nil when it does not exist or is in the
+// trash.
 func (r *TaskRepository) GetTaskByID(id string) (*models.Task, error) {
-	row := r.DB.QueryRow(`SELECT id, project_id, title, description, status, assignee, created_by, started_at, completed_at, created_at, updated_at FROM tasks WHERE id = ?`, id)
+	return r.getTask("id = ? AND org_id = ? AND deleted_at IS NULL", id, r.OrgID)
+}
 
-	var t models.Task
-	var started sql.NullTime
-	var completed sql.NullTime
+// GetTrashedTask returns the task if it is in the trash, and nil otherwise.
+func (r *TaskRepository) GetTrashedTask(id string) (*models.Task, error) {
+	return r.getTask("id = ? AND org_id = ? AND deleted_at IS NOT NULL", id, r.OrgID)
+}
 
-	var astring sql.NullString
-	err := row.Scan(&t.ID, &t.ProjectID, &t.Title, &t.Description, &t.Status, &astring, &t.CreatedBy, &started, &completed, &t.CreatedAt, &t.UpdatedAt)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
+func (r *TaskRepository) getTask(where string, args ...interface{}) (*models.Task, error) {
+	tasks, err := r.queryTasks(where, args...)
+	if err != nil || len(tasks) == 0 {
 		return nil, err
 	}
+	return &tasks[0], nil
+}
 
-	if started.Valid {
-		t.StartedAt = &started.Time
-	}
-	if completed.Valid {
-		t.CompletedAt = &completed.Time
-	}
+// ListTaskIDs returns the ids of all tasks matching filter.
+func (r *TaskRepository) ListTaskIDs(filter rbac.RowFilter) ([]string, error) {
+	return listIDs(r.DB, "tasks", r.OrgID, filter)
+}
 
-	if astring.Valid && astring.String != "" {
-		var arr []string
-		if err := json.Unmarshal([]byte(astring.String), &arr); err == nil {
-			t.Assignees = arr
-		}
-	}
+// TaskListing is how GET /tasks lists tasks.
+var TaskListing = Listing{
+	From:        "tasks",
+	ID:          "tasks.id",
+	Org:         "tasks.org_id",
+	Deleted:     "tasks.deleted_at",
+	SQL:         TaskSQL,
+	Dates:       []string{"created_at", "updated_at", "started_at", "completed_at"},
+	Sortable:    []string{"title", "status", "created_at", "updated_at", "started_at", "completed_at"},
+	Filters:     []string{"project_id", "status", "assignees", "created_by"},
+	Searchable:  []string{"title", "description"},
+	DefaultSort: []SortKey{{Field: "created_at", Desc: true}},
+	Params:      map[string]string{"assignee": "assignees"},
+}
 
-	return &t, nil
+// ListTasks returns the page of tasks matching filter and q, and the cursor
+// of the next page.
+func (r *TaskRepository) ListTasks(filter rbac.RowFilter, q ListQuery) ([]models.Task, string, error) {
+	ids, next, err := TaskListing.page(r.DB, r.OrgID, filter, q)
+	if err != nil || len(ids) == 0 {
+		return nil, next, err
+	}
+	tasks, err := r.GetTasksByIDs(ids)
+	return tasks, next, err
 }
 
-func (r *TaskRepository) ListTasksByProject(projectID string) ([]models.Task, error) {
-	rows, err := r.DB.Query(`SELECT id, project_id, title, description, status, assignee, created_by, started_at, completed_at, created_at, updated_at FROM tasks WHERE project_id=? ORDER BY created_at DESC`, projectID)
+// GetTasksByIDs returns the tasks with ids, in the order of ids, whether or
+// not they are in the trash.
+func (r *TaskRepository) GetTasksByIDs(ids []string) ([]models.Task, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	where, args := inIDs("id", ids).And("org_id=?", r.OrgID)
+	tasks, err := r.queryTasks(where, args...)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var tasks []models.Task
-	for rows.Next() {
-		var t models.Task
-		var started sql.NullTime
-		var completed sql.NullTime
-		var astring sql.NullString
-		if err := rows.Scan(&t.ID, &t.ProjectID, &t.Title, &t.Description, &t.Status, &astring, &t.CreatedBy, &started, &completed, &t.CreatedAt, &t.UpdatedAt); err != nil {
-			return nil, err
-		}
-		if started.Valid {
-			t.StartedAt = &started.Time
-		}
-		if completed.Valid {
-			t.CompletedAt = &completed.Time
-		}
-		if astring.Valid && astring.String != "" {
-			var arr []string
-			if err := json.Unmarshal([]byte(astring.String), &arr); err == nil {
-				t.Assignees = arr
-			}
-		}
-		tasks = append(tasks, t)
-	}
-	return tasks, rows.Err()
+	return inOrder(ids, tasks, func(t models.Task) string { return t.ID }), nil
 }
 
-func (r *TaskRepository) ListTasksByAssignee(userID string) ([]models.Task, error) {
-	rows, err := r.DB.Query(`SELECT id, project_id, title, description, status, assignee, created_by, started_at, completed_at, created_at, updated_at FROM tasks WHERE assignee LIKE ? ORDER BY created_at DESC`, "%"+userID+"%")
+// queryTasks returns the tasks matching where, which may end with ORDER BY.
+func (r *TaskRepository) queryTasks(where string, args ...interface{}) ([]models.Task, error) {
+	rows, err := r.DB.Query(`SELECT id, project_id, title, description, status, assignee, created_by, started_at, completed_at, created_at, updated_at, version, deleted_at, deleted_by FROM tasks WHERE `+where, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -104,12 +153,15 @@ func (r *TaskRepository) ListTasksByAssignee(userID string) ([]models.Task, erro
 	var tasks []models.Task
 	for rows.Next() {
 		var t models.Task
-		var started sql.NullTime
-		var completed sql.NullTime
-		var astring sql.NullString
-		if err := rows.Scan(&t.ID, &t.ProjectID, &t.Title, &t.Description, &t.Status, &astring, &t.CreatedBy, &started, &completed, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		var started, completed, deleted sql.NullTime
+		var astring, deletedBy sql.NullString
+		if err := rows.Scan(&t.ID, &t.ProjectID, &t.Title, &t.Description, &t.Status, &astring, &t.CreatedBy, &started, &completed, &t.CreatedAt, &t.UpdatedAt, &t.Version, &deleted, &deletedBy); err != nil {
 			return nil, err
 		}
+		if deleted.Valid {
+			t.DeletedAt = &deleted.Time
+		}
+		t.DeletedBy = deletedBy.String
 		if started.Valid {
 			t.StartedAt = &started.Time
 		}
@@ -127,23 +179,31 @@ func (r *TaskRepository) ListTasksByAssignee(userID string) ([]models.Task, erro
 	return tasks, rows.Err()
 }
 
+// UpdateTask writes t if the stored task is still at t.Version, and
+// increments the version. It returns ErrStale when another update came first,
+// and ErrNotFound when the task is in the trash.
 func (r *TaskRepository) UpdateTask(t models.Task) error {
 	var ajson sql.NullString
 	if len(t.Assignees) > 0 {
 		b, _ := json.Marshal(t.Assignees)
 		ajson = sql.NullString{String: string(b), Valid: true}
 	}
-	_, err := r.DB.Exec(`UPDATE tasks SET title=?, description=?, status=?, assignee=?, started_at=?, completed_at=?, updated_at=? WHERE id=?`,
-		t.Title, t.Description, t.Status, ajson, t.StartedAt, t.CompletedAt, time.Now(), t.ID,
+	res, err := r.DB.Exec(`UPDATE tasks SET title=?, description=?, status=?, assignee=?, started_at=?, completed_at=?, updated_at=?, version=version+1 WHERE id=? AND org_id=? AND deleted_at IS NULL AND version=?`,
+		t.Title, t.Description, t.Status, ajson, t.StartedAt, t.CompletedAt, time.Now(), t.ID, r.OrgID, t.Version,
 	)
-	return err
+	return versionedUpdate(r.DB, "tasks", r.OrgID, t.ID, res, err)
 }
 
-func (r *TaskRepository) AssignTask(taskID, userID string) error {
+// AssignTask adds userID to the task's assignees, if the task is still at
+// version (see UpdateTask).
+func (r *TaskRepository) AssignTask(taskID, userID string, version int) error {
 	t, err := r.GetTaskByID(taskID)
-	if err != nil || t == nil {
+	if err != nil {
 		return err
 	}
+	if t == nil {
+		return newKindError(ErrNotFound, "task not found")
+	}
 	exists := false
 	for _, a := range t.Assignees {
 		if a == userID {
@@ -154,23 +214,40 @@ func (r *TaskRepository) AssignTask(taskID, userID string) error {
 	if !exists {
 		t.Assignees = append(t.Assignees, userID)
 	}
+	t.Version = version
 	return r.UpdateTask(*t)
 }
 
-func (r *TaskRepository) UpdateStatus(taskID, status string) error {
-	if status == "IN_PROGRESS" {
-		_, err := r.DB.Exec(`UPDATE tasks SET status=?, started_at = COALESCE(started_at, ?), updated_at=? WHERE id=?`, status, time.Now(), time.Now(), taskID)
+// DeleteTask moves the task to the trash, recording userID as the one who
+// deleted it.
+func (r *TaskRepository) DeleteTask(id, userID string) error {
+	res, err := r.DB.Exec(`UPDATE tasks SET deleted_at=?, deleted_by=?, version=version+1 WHERE id=? AND org_id=? AND deleted_at IS NULL`,
+		time.Now().UTC(), userID, id, r.OrgID)
+	return changedOne(res, err, newKindError(ErrNotFound, "task not found"))
+}
+
+// RestoreTask takes the task out of the trash. It returns ErrConstraint while
+// the task's project is in the trash.
+func (r *TaskRepository) RestoreTask(id string) error {
+	res, err := r.DB.Exec(`UPDATE tasks SET deleted_at=NULL, deleted_by=NULL, version=version+1
+		WHERE id=? AND org_id=? AND deleted_at IS NOT NULL
+		AND EXISTS (SELECT 1 FROM projects WHERE projects.id = tasks.project_id AND projects.deleted_at IS NULL)`, id, r.OrgID)
+	if err := changedOne(res, err, errNotInTrash); err != errNotInTrash {
 		return err
 	}
-	if status == "DONE" || status == "ARCHIVED" {
-		_, err := r.DB.Exec(`UPDATE tasks SET status=?, completed_at = ?, updated_at=? WHERE id=?`, status, time.Now(), time.Now(), taskID)
+	// Nothing changed: the task is not in the trash, or its project is.
+	t, err := r.GetTrashedTask(id)
+	switch {
+	case err != nil:
 		return err
+	case t == nil:
+		return errNotInTrash
 	}
-	_, err := r.DB.Exec(`UPDATE tasks SET status=?, updated_at=? WHERE id=?`, status, time.Now(), taskID)
-	return err
+	return errProjectInTrash
 }
 
-func (r *TaskRepository) DeleteTask(id string) error {
-	_, err := r.DB.Exec(`DELETE FROM tasks WHERE id=?`, id)
-	return err
+// PurgeTask deletes the task from the trash for good.
+func (r *TaskRepository) PurgeTask(id string) error {
+	res, err := r.DB.Exec(`DELETE FROM tasks WHERE id=? AND org_id=? AND deleted_at IS NOT NULL`, id, r.OrgID)
+	return changedOne(res, err, errNotInTrash)
 }
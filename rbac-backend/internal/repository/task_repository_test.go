@@ -2,9 +2,11 @@ package repositories
 
 import (
 	"database/sql"
+	"errors"
 	"testing"
 
 	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
 
 	_ "modernc.org/sqlite"
 )
@@ -17,8 +19,10 @@ func setupTestDB(t *testing.T) *sql.DB {
 
 	schema := `
     CREATE TABLE users (id TEXT PRIMARY KEY, name TEXT);
-    CREATE TABLE projects (id TEXT PRIMARY KEY, name TEXT, created_by TEXT);
-    CREATE TABLE tasks (id TEXT PRIMARY KEY, project_id TEXT NOT NULL, title TEXT NOT NULL, description TEXT, status TEXT NOT NULL DEFAULT 'TODO', assignee TEXT, created_by TEXT NOT NULL, started_at DATETIME, completed_at DATETIME, created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+    CREATE TABLE projects (id TEXT PRIMARY KEY, org_id TEXT NOT NULL, name TEXT, description TEXT, created_by TEXT, version INTEGER NOT NULL DEFAULT 1, deleted_at DATETIME, deleted_by TEXT);
+    CREATE TABLE project_assignments (project_id TEXT NOT NULL, user_id TEXT NOT NULL);
+    CREATE TABLE tasks (id TEXT PRIMARY KEY, org_id TEXT NOT NULL, project_id TEXT NOT NULL, title TEXT NOT NULL, description TEXT, status TEXT NOT NULL DEFAULT 'TODO', assignee TEXT, created_by TEXT NOT NULL, started_at DATETIME, completed_at DATETIME, created_at DATETIME DEFAULT CURRENT_TIMESTAMP, updated_at DATETIME DEFAULT CURRENT_TIMESTAMP, version INTEGER NOT NULL DEFAULT 1, deleted_at DATETIME, deleted_by TEXT);
+    INSERT INTO projects (id, org_id, name, created_by) VALUES ('pid1', 'o1', 'P1', 'u1'), ('p1', 'o1', 'P', 'u1'), ('p2', 'o2', 'Other', 'u9');
     `
 	if _, err := db.Exec(schema); err != nil {
 		t.Fatal(err)
@@ -28,7 +32,7 @@ func setupTestDB(t *testing.T) *sql.DB {
 
 func TestCreateAndGetTask(t *testing.T) {
 	db := setupTestDB(t)
-	repo := NewTaskRepository(db)
+	repo := NewTaskRepository(db).ForOrg("o1")
 
 	task := models.Task{ID: "tid1", ProjectID: "pid1", Title: "Test", CreatedBy: "u1", Status: "TODO"}
 
@@ -44,3 +48,164 @@ func TestCreateAndGetTask(t *testing.T) {
 		t.Fatalf("unexpected task: %+v", got)
 	}
 }
+
+func TestListTasksAppliesRowFilter(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewTaskRepository(db).ForOrg("o1")
+
+	tasks := []models.Task{
+		{ID: "t1", ProjectID: "p1", Title: "mine", CreatedBy: "u1", Status: "TODO"},
+		{ID: "t2", ProjectID: "p1", Title: "assigned", CreatedBy: "u2", Status: "TODO", Assignees: []string{"u3", "u1"}},
+		{ID: "t3", ProjectID: "p1", Title: "other", CreatedBy: "u2", Status: "TODO", Assignees: []string{"u3"}},
+		{ID: "t4", ProjectID: "pid1", Title: "elsewhere", CreatedBy: "u1", Status: "TODO"},
+	}
+	for _, task := range tasks {
+		if err := repo.CreateTask(task); err != nil {
+			t.Fatalf("create failed: %v", err)
+		}
+	}
+
+	perm := models.ResourcePermission{
+		View:       true,
+		Conditions: map[string]string{rbac.ActionView: "record.created_by == user.id || user.id in record.assignees"},
+	}
+	filter, err := rbac.CompileRecordFilter(perm, rbac.ActionView, rbac.Subject{ID: "u1"}, TaskSQL)
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	got, _, err := repo.ListTasks(filter, ListQuery{Equal: map[string]string{"project_id": "p1"}, Limit: 10})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 visible tasks, got %+v", got)
+	}
+	for _, task := range got {
+		if task.ID == "t3" || task.ID == "t4" {
+			t.Fatalf("%s should be filtered out", task.ID)
+		}
+	}
+}
+
+func TestTasksAreIsolatedByOrganization(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewTaskRepository(db).ForOrg("o1")
+	other := NewTaskRepository(db).ForOrg("o2")
+
+	if err := other.CreateTask(models.Task{ID: "t9", ProjectID: "p2", Title: "theirs", CreatedBy: "u9", Status: "TODO"}); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	got, err := repo.GetTaskByID("t9")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if got != nil {
+		t.Fatalf("task of another organization should not be visible: %+v", got)
+	}
+
+	err = repo.CreateTask(models.Task{ID: "t10", ProjectID: "p2", Title: "sneaky", CreatedBy: "u1", Status: "TODO"})
+	if err != ErrProjectNotFound {
+		t.Fatalf("expected ErrProjectNotFound for another organization's project, got %v", err)
+	}
+
+	if err := NewTaskRepository(db).CreateTask(models.Task{ID: "t11", ProjectID: "p1", Title: "x", CreatedBy: "u1"}); err != ErrNoOrganization {
+		t.Fatalf("expected ErrNoOrganization from an unscoped repository, got %v", err)
+	}
+}
+
+func TestCreateTaskMapsDatabaseErrors(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewTaskRepository(db).ForOrg("o1")
+
+	task := models.Task{ID: "tid1", ProjectID: "pid1", Title: "Test", CreatedBy: "u1", Status: "TODO"}
+	if err := repo.CreateTask(task); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	err := repo.CreateTask(task)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("duplicate id: got %v, want ErrConflict", err)
+	}
+	if err.Error() != "duplicate value for tasks.id" {
+		t.Fatalf("duplicate id message: %q", err)
+	}
+
+	task.ID, task.ProjectID = "tid2", "p2" // another organization's project
+	if err := repo.CreateTask(task); !errors.Is(err, ErrNotFound) || !errors.Is(err, ErrProjectNotFound) {
+		t.Fatalf("foreign project: got %v, want ErrProjectNotFound", err)
+	}
+}
+
+func TestUpdateTaskChecksVersion(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewTaskRepository(db).ForOrg("o1")
+	if err := repo.CreateTask(models.Task{ID: "t1", ProjectID: "p1", Title: "v1", CreatedBy: "u1", Status: "TODO"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two clients read version 1; the second write must not overwrite the first.
+	first, _ := repo.GetTaskByID("t1")
+	second, _ := repo.GetTaskByID("t1")
+	first.Title = "first"
+	if err := repo.UpdateTask(*first); err != nil {
+		t.Fatalf("first update: %v", err)
+	}
+	second.Title = "second"
+	if err := repo.UpdateTask(*second); !errors.Is(err, ErrStale) {
+		t.Fatalf("second update: got %v, want ErrStale", err)
+	}
+	got, _ := repo.GetTaskByID("t1")
+	if got.Title != "first" || got.Version != 2 {
+		t.Fatalf("task = %q at version %d, want \"first\" at 2", got.Title, got.Version)
+	}
+
+	if err := repo.AssignTask("t1", "u2", 1); !errors.Is(err, ErrStale) {
+		t.Fatalf("assign at old version: got %v, want ErrStale", err)
+	}
+	got.ID = "missing"
+	if err := repo.UpdateTask(*got); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("update of missing task: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestRowFilterMatchesEvaluateOnNullColumns(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewProjectRepository(db).ForOrg("o1")
+	if _, err := db.Exec(`INSERT INTO projects (id, org_id, name, description, created_by) VALUES ('p3', 'o1', 'Secret', 'a secret plan', 'u1')`); err != nil {
+		t.Fatal(err)
+	}
+
+	perm := models.ResourcePermission{
+		View:       true,
+		Conditions: map[string]string{rbac.ActionView: `!("secret" in record.description)`},
+	}
+	filter, err := rbac.CompileRecordFilter(perm, rbac.ActionView, rbac.Subject{ID: "u1"}, ProjectSQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered, err := repo.GetProjects(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listed := map[string]bool{}
+	for _, p := range filtered {
+		listed[p.ID] = true
+	}
+
+	all, err := repo.GetProjects(rbac.RowFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range all {
+		// pid1 and p1 have a NULL description, which the record holds as "".
+		record := map[string]interface{}{"id": p.ID, "name": p.Name, "description": p.Description, "created_by": p.CreatedBy}
+		allowed := rbac.EvaluateRecord(perm, rbac.ActionView, rbac.Subject{ID: "u1"}, record).Allowed()
+		if allowed != listed[p.ID] {
+			t.Errorf("%s: EvaluateRecord allows %v, the SQL filter lists %v", p.ID, allowed, listed[p.ID])
+		}
+	}
+	if len(listed) != 2 || listed["p3"] {
+		t.Errorf("listed %v, want pid1 and p1", listed)
+	}
+}
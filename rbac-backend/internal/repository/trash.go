@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Deleting a project or task moves it to the trash: deleted_at and
+// deleted_by are set and the record is left out of gets, lists, searches and
+// updates. It stays there until it is restored, purged, or purged by
+// PurgeTrash once it has been there longer than the retention period.
+var (
+	errNotInTrash = newKindError(ErrNotFound, "record is not in the trash")
+	// errProjectInTrash refuses to restore a task into a trashed project.
+	errProjectInTrash = newKindError(ErrConstraint, "the task's project is in the trash; restore it first")
+	// errProjectHasTasks refuses to delete a project with live tasks.
+	errProjectHasTasks = newKindError(ErrConstraint, "project still has tasks")
+	// errProjectHasTrashedTasks refuses to purge a project whose tasks are
+	// still in the trash.
+	errProjectHasTrashedTasks = newKindError(ErrConstraint, "project still has tasks in the trash; purge them first")
+)
+
+// changedOne checks the result of a write of one record: it returns missing
+// when no row changed.
+func changedOne(res sql.Result, err error, missing error) error {
+	if err != nil {
+		return dbError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return missing
+	}
+	return nil
+}
+
+// PurgedRecord identifies a record PurgeTrash deleted.
+type PurgedRecord struct {
+	Kind  string // "project" or "task"
+	OrgID string
+	ID    string
+}
+
+// PurgeTrash deletes for good, in every organization, the tasks and projects
+// moved to the trash before cutoff. A project is kept while it still has
+// tasks, such as tasks trashed after it.
+func PurgeTrash(db *sql.DB, cutoff time.Time) ([]PurgedRecord, error) {
+	var purged []PurgedRecord
+	for _, step := range []struct{ kind, query string }{
+		{"task", `DELETE FROM tasks WHERE deleted_at < ? RETURNING org_id, id`},
+		{"project", `DELETE FROM projects WHERE deleted_at < ?
+			AND NOT EXISTS (SELECT 1 FROM tasks WHERE tasks.project_id = projects.id) RETURNING org_id, id`},
+	} {
+		rows, err := db.Query(step.query, cutoff.UTC())
+		if err != nil {
+			return purged, err
+		}
+		for rows.Next() {
+			p := PurgedRecord{Kind: step.kind}
+			if err := rows.Scan(&p.OrgID, &p.ID); err != nil {
+				rows.Close()
+				return purged, err
+			}
+			purged = append(purged, p)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,109 @@
+package repositories
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+)
+
+func TestTrashLifecycle(t *testing.T) {
+	db := setupTestDB(t)
+	tasks := NewTaskRepository(db).ForOrg("o1")
+	projects := NewProjectRepository(db).ForOrg("o1")
+	if err := tasks.CreateTask(models.Task{ID: "t1", ProjectID: "p1", Title: "T", CreatedBy: "u1", Status: "TODO"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := projects.DeleteProject("p1", "u1"); !errors.Is(err, ErrConstraint) {
+		t.Fatalf("deleting a project with tasks: %v, want ErrConstraint", err)
+	}
+	if err := tasks.DeleteTask("t1", "u2"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := tasks.GetTaskByID("t1"); got != nil {
+		t.Error("a trashed task is still found by GetTaskByID")
+	}
+	trashed, err := tasks.GetTrashedTask("t1")
+	if err != nil || trashed == nil || trashed.DeletedBy != "u2" || trashed.DeletedAt == nil || trashed.Version != 2 {
+		t.Fatalf("GetTrashedTask: %+v, %v", trashed, err)
+	}
+	if err := tasks.UpdateTask(*trashed); !errors.Is(err, ErrNotFound) {
+		t.Errorf("updating a trashed task: %v, want ErrNotFound", err)
+	}
+	live, _, _ := tasks.ListTasks(rbac.RowFilter{}, ListQuery{Limit: 10})
+	trash, _, _ := tasks.ListTasks(rbac.RowFilter{}, ListQuery{Limit: 10, Trash: true})
+	if len(live) != 0 || len(trash) != 1 || trash[0].ID != "t1" {
+		t.Errorf("lists: live %v, trash %v", live, trash)
+	}
+
+	if err := projects.DeleteProject("p1", "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tasks.CreateTask(models.Task{ID: "t2", ProjectID: "p1", Title: "T", CreatedBy: "u1", Status: "TODO"}); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("creating a task in a trashed project: %v, want ErrProjectNotFound", err)
+	}
+	if err := tasks.RestoreTask("t1"); !errors.Is(err, ErrConstraint) {
+		t.Errorf("restoring a task into a trashed project: %v, want ErrConstraint", err)
+	}
+	if err := projects.PurgeProject("p1"); !errors.Is(err, ErrConstraint) {
+		t.Errorf("purging a project with trashed tasks: %v, want ErrConstraint", err)
+	}
+	if err := projects.RestoreProject("p1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tasks.RestoreTask("t1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tasks.RestoreTask("t1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("restoring a live task: %v, want ErrNotFound", err)
+	}
+	if got, _ := tasks.GetTaskByID("t1"); got == nil || got.DeletedAt != nil || got.Version != 3 {
+		t.Errorf("restored task: %+v", got)
+	}
+}
+
+func TestPurgeTrashKeepsRecentAndLiveRecords(t *testing.T) {
+	db := setupTestDB(t)
+	tasks := NewTaskRepository(db).ForOrg("o1")
+	projects := NewProjectRepository(db).ForOrg("o1")
+	for _, id := range []string{"t1", "t2"} {
+		if err := tasks.CreateTask(models.Task{ID: id, ProjectID: "p1", Title: id, CreatedBy: "u1", Status: "TODO"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, id := range []string{"t1", "t2"} {
+		if err := tasks.DeleteTask(id, "u1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := projects.DeleteProject("p1", "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`UPDATE tasks SET deleted_at = ? WHERE id = 't2'`, time.Now().UTC().Add(48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// t2 was trashed after the cutoff, so p1 has to wait for it.
+	purged, err := PurgeTrash(db, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []PurgedRecord{{Kind: "task", OrgID: "o1", ID: "t1"}}; !reflect.DeepEqual(purged, want) {
+		t.Errorf("first purge: %v, want %v", purged, want)
+	}
+	purged, err = PurgeTrash(db, time.Now().Add(72*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PurgedRecord{{Kind: "task", OrgID: "o1", ID: "t2"}, {Kind: "project", OrgID: "o1", ID: "p1"}}
+	if !reflect.DeepEqual(purged, want) {
+		t.Errorf("second purge: %v, want %v", purged, want)
+	}
+	if p, _ := projects.GetProjectByID("pid1"); p == nil {
+		t.Error("a live project was purged")
+	}
+}
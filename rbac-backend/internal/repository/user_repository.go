@@ -4,33 +4,163 @@ import (
 	"database/sql"
 
 	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
 )
 
+// UserRepository reads and writes the members of one organization. A user's
+// Role is their role in that organization.
 type UserRepository struct {
-	DB *sql.DB
+	DB    *sql.DB
+	OrgID string
 }
 
 func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{DB: db}
 }
 
+// ForOrg returns a copy of the repository scoped to orgID.
+func (r *UserRepository) ForOrg(orgID string) *UserRepository {
+	return &UserRepository{DB: r.DB, OrgID: orgID}
+}
+
+// ErrAlreadyMember is returned by AddMember when the user already belongs to
+// the organization.
+var ErrAlreadyMember = newKindError(ErrConflict, "user is already a member")
+
+const memberColumns = `users.id, users.name, users.email, organization_members.role, users.is_active, users.created_at, users.updated_at`
+
+const memberJoin = `users JOIN organization_members ON organization_members.user_id = users.id`
+
+// CreateUser creates the account and makes it a member of the organization
+// with user.Role.
 func (r *UserRepository) CreateUser(user models.User) error {
-	_, err := r.DB.Exec(
+	if r.OrgID == "" {
+		return ErrNoOrganization
+	}
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
 		`INSERT INTO users (id, name, email, password_hash, role, is_active)
 		 VALUES (?, ?, ?, ?, ?, ?)`,
 		user.ID, user.Name, user.Email, user.PasswordHash, user.Role, user.IsActive,
+	); err != nil {
+		return dbError(err)
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO organization_members (org_id, user_id, role) VALUES (?, ?, ?)`,
+		r.OrgID, user.ID, user.Role,
+	); err != nil {
+		return dbError(err)
+	}
+	return tx.Commit()
+}
+
+// GetUserByID returns the member, or nil when no such user belongs to the organization.
+func (r *UserRepository) GetUserByID(id string) (*models.User, error) {
+	rows, err := r.DB.Query(
+		`SELECT `+memberColumns+` FROM `+memberJoin+`
+		 WHERE users.id = ? AND organization_members.org_id = ?`,
+		id, r.OrgID,
 	)
-	return err
+	if err != nil {
+		return nil, err
+	}
+	users, err := scanUsers(rows)
+	if err != nil || len(users) == 0 {
+		return nil, err
+	}
+	return &users[0], nil
 }
 
+// UpdateUserRole changes the member's role in the organization only.
+func (r *UserRepository) UpdateUserRole(id, role string) error {
+	_, err := r.DB.Exec(
+		`UPDATE organization_members SET role = ? WHERE org_id = ? AND user_id = ?`,
+		role, r.OrgID, id,
+	)
+	return dbError(err)
+}
+
+// RemoveMember takes the user out of the organization; the account itself
+// and its other memberships are kept. It reports false when the user was not
+// a member.
+func (r *UserRepository) RemoveMember(id string) (bool, error) {
+	res, err := r.DB.Exec(
+		`DELETE FROM organization_members WHERE org_id = ? AND user_id = ?`,
+		r.OrgID, id,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ListUsers returns the organization's members, newest account first.
 func (r *UserRepository) ListUsers() ([]models.User, error) {
-	rows, err := r.DB.Query(`
-		SELECT id, name, email, role, is_active, created_at, updated_at
-		FROM users ORDER BY created_at DESC
-	`)
+	rows, err := r.DB.Query(
+		`SELECT `+memberColumns+` FROM `+memberJoin+`
+		 WHERE organization_members.org_id = ? ORDER BY users.created_at DESC`,
+		r.OrgID,
+	)
 	if err != nil {
 		return nil, err
 	}
+	return scanUsers(rows)
+}
+
+// UserSQL maps member fields onto SQL. role is the role in the organization.
+var UserSQL = rbac.SQLMapping{
+	Columns: map[string]string{
+		"id":         "users.id",
+		"name":       "users.name",
+		"email":      "users.email",
+		"role":       "organization_members.role",
+		"is_active":  "users.is_active",
+		"created_at": "users.created_at",
+		"updated_at": "users.updated_at",
+	},
+}
+
+func init() { rbac.RegisterSQLMapping(rbac.TableUsers, UserSQL) }
+
+// UserListing is how GET /api/users lists members.
+var UserListing = Listing{
+	From:        memberJoin,
+	ID:          "users.id",
+	Org:         "organization_members.org_id",
+	SQL:         UserSQL,
+	Dates:       []string{"created_at", "updated_at"},
+	Sortable:    []string{"name", "email", "role", "created_at"},
+	Filters:     []string{"role", "is_active"},
+	Searchable:  []string{"name", "email"},
+	DefaultSort: []SortKey{{Field: "created_at", Desc: true}},
+}
+
+// ListUsersPage returns the page of members matching q, and the cursor of
+// the next page.
+func (r *UserRepository) ListUsersPage(q ListQuery) ([]models.User, string, error) {
+	ids, next, err := UserListing.page(r.DB, r.OrgID, rbac.RowFilter{}, q)
+	if err != nil || len(ids) == 0 {
+		return nil, next, err
+	}
+	where, args := inIDs("users.id", ids).And("organization_members.org_id = ?", r.OrgID)
+	rows, err := r.DB.Query(`SELECT `+memberColumns+` FROM `+memberJoin+` WHERE `+where, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	users, err := scanUsers(rows)
+	if err != nil {
+		return nil, "", err
+	}
+	return inOrder(ids, users, func(u models.User) string { return u.ID }), next, nil
+}
+
+func scanUsers(rows *sql.Rows) ([]models.User, error) {
 	defer rows.Close()
 
 	var users []models.User
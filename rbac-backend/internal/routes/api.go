@@ -0,0 +1,173 @@
+package routes
+
+import (
+	"database/sql"
+	"net/http"
+
+	"rbac-backend/internal/handlers"
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+	repositories "rbac-backend/internal/repository"
+)
+
+// API returns every route of the server. The handlers only use database when
+// serving, so API(nil) is enough for Lint, WriteMatrix and OpenAPI.
+func API(database *sql.DB) []Route {
+	projectHandler := handlers.NewProjectHandler(repositories.NewProjectRepository(database))
+	taskHandler := handlers.NewTaskHandler(repositories.NewTaskRepository(database))
+	adminHandler := handlers.NewAdminHandler(repositories.NewUserRepository(database))
+	rolesHandler := handlers.NewRolesHandler(database)
+	elevationHandler := handlers.NewElevationHandler(repositories.NewElevationRepository(database), database)
+	meHandler := handlers.NewMeHandler(database)
+	orgHandler := handlers.NewOrgHandler(database)
+	searchHandler := handlers.NewSearchHandler(database)
+
+	const (
+		projects = rbac.TableProjects
+		tasks    = rbac.TableTasks
+		users    = rbac.TableUsers
+		view     = rbac.ActionView
+		create   = rbac.ActionCreate
+		edit     = rbac.ActionEdit
+		del      = rbac.ActionDelete
+		restore  = rbac.ActionRestore
+		purge    = rbac.ActionPurge
+	)
+
+	// Example bodies whose types give the OpenAPI schemas.
+	var (
+		project     = models.Project{}
+		task        = models.Task{}
+		status      = statusResponse{}
+		message     = messageResponse{}
+		violation   = map[int]interface{}{http.StatusForbidden: constraintDetails{}}
+		invalidPerm = map[int]interface{}{http.StatusUnprocessableEntity: rbac.ValidationReport{}}
+		invalid     = map[int]interface{}{http.StatusUnprocessableEntity: handlers.ValidationDetails{}}
+		forbidden   = OneOf{constraintDetails{}, handlers.ForbiddenFieldsDetails{}}
+		write       = map[int]interface{}{http.StatusForbidden: forbidden, http.StatusUnprocessableEntity: handlers.ValidationDetails{}}
+		update      = map[int]interface{}{http.StatusForbidden: forbidden, http.StatusPreconditionFailed: handlers.StaleDetails{}, http.StatusPreconditionRequired: nil, http.StatusUnprocessableEntity: handlers.ValidationDetails{}}
+		assign      = map[int]interface{}{http.StatusForbidden: forbidden, http.StatusPreconditionFailed: handlers.StaleDetails{}, http.StatusPreconditionRequired: nil, http.StatusUnprocessableEntity: handlers.ValidationDetails{}}
+	)
+
+	rs := []Route{
+		{Method: "GET", Path: "/{$}", Public: true, Summary: "Greeting", Returns: "Greeting", Handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("Welcome to RBAC System Backend 🚀"))
+		}},
+
+		// AUTH
+		{Method: "POST", Path: "/login", Public: true, Summary: "Authenticate and return a JWT for an organization", Body: loginRequest{}, Returns: tokenResponse{}, Handler: handlers.Login(database)},
+
+		// PROJECTS
+		{Method: "GET", Path: "/projects", Table: projects, Action: view, Summary: "List projects", Query: listParams(repositories.ProjectListing), Returns: []models.Project{}, Handler: projectHandler.GetProjects},
+		{Method: "POST", Path: "/projects", Table: projects, Action: create, Summary: "Create a project", Body: handlers.ProjectRequest{}, Returns: projectResult{}, Errors: write, Handler: projectHandler.CreateProject},
+		{Method: "GET", Path: "/projects/{id}", Table: projects, Action: view, Summary: "Get a project", Returns: project, Handler: projectHandler.GetProject},
+		{Method: "PATCH", Path: "/projects/{id}", Table: projects, Action: edit, Summary: "Update a project", Body: handlers.ProjectRequest{}, Returns: updateResponse{}, Errors: update, Handler: projectHandler.UpdateProject},
+		{Method: "DELETE", Path: "/projects/{id}", Table: projects, Action: del, Summary: "Move a project to the trash", Returns: message, Handler: projectHandler.DeleteProject},
+		{Method: "GET", Path: "/projects/{project_id}/tasks", Table: tasks, Action: view, Summary: "List a project's tasks", Query: listParams(repositories.TaskListing), Returns: []models.Task{}, Handler: taskHandler.ListTasks},
+		{Method: "POST", Path: "/projects/{project_id}/tasks", Table: tasks, Action: create, Summary: "Create a task in a project", Body: handlers.TaskRequest{}, Returns: handlers.TaskResult{}, Errors: write, Handler: taskHandler.CreateTask},
+
+		// TASKS
+		{Method: "GET", Path: "/tasks", Table: tasks, Action: view, Summary: "List tasks", Query: listParams(repositories.TaskListing), Returns: []models.Task{}, Handler: taskHandler.ListTasks},
+		{Method: "POST", Path: "/tasks", Table: tasks, Action: create, Summary: "Create a task", Body: handlers.TaskRequest{}, Returns: handlers.TaskResult{}, Errors: write, Handler: taskHandler.CreateTask},
+		{Method: "GET", Path: "/tasks/{id}", Table: tasks, Action: view, Summary: "Get a task", Returns: task, Handler: taskHandler.GetTask},
+		{Method: "PATCH", Path: "/tasks/{id}", Table: tasks, Action: edit, Summary: "Update a task", Body: handlers.TaskRequest{}, Returns: updateResponse{}, Errors: update, Handler: taskHandler.UpdateTask},
+		{Method: "DELETE", Path: "/tasks/{id}", Table: tasks, Action: del, Summary: "Move a task to the trash", Returns: message, Handler: taskHandler.DeleteTask},
+		{Method: "POST", Path: "/tasks/{id}/assignees", Table: tasks, Action: edit, Summary: "Assign a task", Body: handlers.AssignRequest{}, Returns: updateResponse{}, Errors: assign, Handler: taskHandler.AssignTask},
+
+		// TRASH: deleted projects and tasks, until restored or purged
+		{Method: "GET", Path: "/projects/trash", Table: projects, Action: restore, Summary: "List deleted projects", Query: listParams(repositories.ProjectListing), Returns: []models.Project{}, Handler: projectHandler.ListTrash},
+		{Method: "POST", Path: "/projects/trash/{id}/restore", Table: projects, Action: restore, Summary: "Restore a deleted project", Returns: project, Handler: projectHandler.RestoreProject},
+		{Method: "DELETE", Path: "/projects/trash/{id}", Table: projects, Action: purge, Summary: "Delete a project for good", Returns: message, Handler: projectHandler.PurgeProject},
+		{Method: "GET", Path: "/tasks/trash", Table: tasks, Action: restore, Summary: "List deleted tasks", Query: listParams(repositories.TaskListing), Returns: []models.Task{}, Handler: taskHandler.ListTrash},
+		{Method: "POST", Path: "/tasks/trash/{id}/restore", Table: tasks, Action: restore, Summary: "Restore a deleted task", Returns: task, Handler: taskHandler.RestoreTask},
+		{Method: "DELETE", Path: "/tasks/trash/{id}", Table: tasks, Action: purge, Summary: "Delete a task for good", Returns: message, Handler: taskHandler.PurgeTask},
+
+		// DEPRECATED ALIASES: the verb-named routes that predate the resource routes
+		{Method: "POST", Path: "/projects/create", Table: projects, Action: create, Successor: "/projects", Body: handlers.ProjectRequest{}, Returns: projectResult{}, Errors: write, Handler: projectHandler.CreateProject},
+		{Method: "POST", Path: "/projects/update", Table: projects, Action: edit, Successor: "/projects/{id}", Body: handlers.ProjectRequest{}, Returns: updateResponse{}, Errors: update, Handler: projectHandler.UpdateProject},
+		{Method: "PUT", Path: "/projects/update", Table: projects, Action: edit, Successor: "/projects/{id}", Body: handlers.ProjectRequest{}, Returns: updateResponse{}, Errors: update, Handler: projectHandler.UpdateProject},
+		{Method: "POST", Path: "/projects/delete", Table: projects, Action: del, Successor: "/projects/{id}", Query: []string{"id"}, Returns: message, Handler: projectHandler.DeleteProject},
+		{Method: "DELETE", Path: "/projects/delete", Table: projects, Action: del, Successor: "/projects/{id}", Query: []string{"id"}, Returns: message, Handler: projectHandler.DeleteProject},
+		{Method: "POST", Path: "/tasks/create", Table: tasks, Action: create, Successor: "/projects/{project_id}/tasks", Body: handlers.TaskRequest{}, Returns: handlers.TaskResult{}, Errors: write, Handler: taskHandler.CreateTask},
+		{Method: "GET", Path: "/tasks/get", Table: tasks, Action: view, Successor: "/tasks/{id}", Query: []string{"id"}, Returns: task, Handler: taskHandler.GetTask},
+		{Method: "POST", Path: "/tasks/update", Table: tasks, Action: edit, Successor: "/tasks/{id}", Body: handlers.TaskRequest{}, Returns: updateResponse{}, Errors: update, Handler: taskHandler.UpdateTask},
+		{Method: "PUT", Path: "/tasks/update", Table: tasks, Action: edit, Successor: "/tasks/{id}", Body: handlers.TaskRequest{}, Returns: updateResponse{}, Errors: update, Handler: taskHandler.UpdateTask},
+		{Method: "POST", Path: "/tasks/assign", Table: tasks, Action: edit, Successor: "/tasks/{id}/assignees", Body: handlers.AssignRequest{}, Returns: updateResponse{}, Errors: assign, Handler: taskHandler.AssignTask},
+		{Method: "POST", Path: "/tasks/delete", Table: tasks, Action: del, Successor: "/tasks/{id}", Query: []string{"id"}, Returns: message, Handler: taskHandler.DeleteTask},
+		{Method: "DELETE", Path: "/tasks/delete", Table: tasks, Action: del, Successor: "/tasks/{id}", Query: []string{"id"}, Returns: message, Handler: taskHandler.DeleteTask},
+
+		// SEARCH
+		{Method: "GET", Path: "/search", Summary: "Search projects and tasks the caller can view", Query: []string{"q", "kind", "limit"}, Returns: searchResponse{}, Handler: searchHandler.Search},
+
+		// USERS
+		{Method: "GET", Path: "/api/users", Table: users, Action: view, Summary: "List the organization's members", Query: listParams(repositories.UserListing), Returns: usersResponse{}, Handler: adminHandler.ListUsers},
+		{Method: "POST", Path: "/admin/create-user", Table: users, Action: create, Summary: "Create an account in the organization", Body: createUserRequest{}, Status: http.StatusCreated, Returns: status, Errors: violation, Handler: adminHandler.CreateUser},
+		{Method: "POST", Path: "/admin/update-user-role", Table: users, Action: edit, Summary: "Change a member's role", Body: updateUserRoleRequest{}, Returns: status, Errors: violation, Handler: adminHandler.UpdateUserRole},
+		{Method: "PUT", Path: "/admin/update-user-role", Table: users, Action: edit, Summary: "Change a member's role", Body: updateUserRoleRequest{}, Returns: status, Errors: violation, Handler: adminHandler.UpdateUserRole},
+
+		// ME
+		{Method: "GET", Path: "/me", Summary: "The caller's profile and active elevations", Returns: meResponse{}, Handler: meHandler.GetMe},
+		{Method: "GET", Path: "/me/permissions", Summary: "The caller's resolved table actions and field flags", Returns: permissionsResponse{}, Handler: meHandler.GetMyPermissions},
+
+		// ORGANIZATIONS
+		{Method: "GET", Path: "/orgs", Summary: "The caller's organizations", Returns: orgsResponse{}, Handler: orgHandler.ListMyOrgs},
+		{Method: "POST", Path: "/orgs/switch", Summary: "Issue a token for another organization", Body: switchRequest{}, Returns: switchResponse{}, Handler: orgHandler.SwitchOrg},
+		{Method: "GET", Path: "/admin/orgs", Superuser: true, DefaultOrg: true, Summary: "List organizations", Returns: []models.Organization{}, Handler: orgHandler.ListOrgs},
+		{Method: "POST", Path: "/admin/orgs", Superuser: true, DefaultOrg: true, Summary: "Create an organization", Body: orgRequest{}, Status: http.StatusCreated, Returns: models.Organization{}, Handler: orgHandler.CreateOrg},
+		{Method: "GET", Path: "/orgs/invitations", Summary: "Invitations for the caller's email", Returns: invitationsResponse{}, Handler: orgHandler.ListInvitations},
+		{Method: "POST", Path: "/orgs/invitations/{id}/accept", Summary: "Join an organization by accepting its invitation", Returns: models.Membership{}, Errors: violation, Handler: orgHandler.AcceptInvitation},
+		{Method: "DELETE", Path: "/orgs/invitations/{id}", Summary: "Decline an invitation", Returns: status, Handler: orgHandler.DeclineInvitation},
+		{Method: "POST", Path: "/admin/org/members", Table: users, Action: edit, Summary: "Invite an account to the organization", Body: memberRequest{}, Status: http.StatusAccepted, Returns: status, Errors: violation, Handler: orgHandler.InviteMember},
+		{Method: "DELETE", Path: "/admin/org/members", Table: users, Action: del, Summary: "Remove a member from the organization", Query: []string{"user_id"}, Returns: status, Handler: orgHandler.RemoveMember},
+
+		// ELEVATIONS
+		{Method: "POST", Path: "/elevations/request", Summary: "Request a time-bound elevation", Body: elevationRequest{}, Status: http.StatusCreated, Returns: models.Elevation{}, Errors: invalid, Handler: elevationHandler.RequestElevation},
+		{Method: "GET", Path: "/elevations", Summary: "List elevation requests (own; all for superusers)", Query: []string{"user_id"}, Returns: elevationsResponse{}, Handler: elevationHandler.ListElevations},
+		{Method: "POST", Path: "/elevations/approve", Superuser: true, Summary: "Approve a pending request", Query: []string{"id"}, Returns: status, Errors: violation, Handler: elevationHandler.ApproveElevation},
+		{Method: "POST", Path: "/elevations/deny", Superuser: true, Summary: "Deny a pending request", Query: []string{"id"}, Returns: status, Handler: elevationHandler.DenyElevation},
+		{Method: "POST", Path: "/elevations/revoke", Summary: "Revoke a grant early (superuser, or the requester)", Query: []string{"id"}, Returns: status, Handler: elevationHandler.RevokeElevation},
+
+		// ROLES AND POLICY
+		{Method: "GET", Path: "/admin/roles", Superuser: true, Summary: "List roles", Returns: rolesResponse{}, Handler: rolesHandler.GetRoles},
+		{Method: "POST", Path: "/admin/roles", Superuser: true, DefaultOrg: true, Summary: "Create a role", Body: models.Role{}, Status: http.StatusCreated, Returns: models.Role{}, Handler: rolesHandler.CreateRole},
+		{Method: "GET", Path: "/admin/roles/{role}", Superuser: true, Summary: "A role's permissions in the organization", Returns: models.Permissions{}, Handler: rolesHandler.GetRole},
+		{Method: "PUT", Path: "/admin/roles/{role}", Superuser: true, Summary: "Replace a role's permissions (?dry_run=true only validates)", Query: []string{"dry_run", "comment"}, Body: models.Permissions{}, Returns: OneOf{roleUpdateResponse{}, rbac.ValidationReport{}}, Errors: invalidPerm, Handler: rolesHandler.UpdateRole},
+		{Method: "POST", Path: "/admin/roles/{role}", Superuser: true, Summary: "Replace a role's permissions (?dry_run=true only validates)", Query: []string{"dry_run", "comment"}, Body: models.Permissions{}, Returns: OneOf{roleUpdateResponse{}, rbac.ValidationReport{}}, Errors: invalidPerm, Handler: rolesHandler.UpdateRole},
+		{Method: "PUT", Path: "/admin/roles/{role}/settings", Superuser: true, DefaultOrg: true, Summary: "Change a role's superuser flag and description", Body: roleSettingsRequest{}, Returns: models.Role{}, Handler: rolesHandler.UpdateRoleSettings},
+		{Method: "PATCH", Path: "/admin/roles/{role}/settings", Superuser: true, DefaultOrg: true, Summary: "Change a role's superuser flag and description", Body: roleSettingsRequest{}, Returns: models.Role{}, Handler: rolesHandler.UpdateRoleSettings},
+		{Method: "GET", Path: "/admin/roles/{role}/versions", Superuser: true, Summary: "List a role's permission versions", Returns: versionsResponse{}, Handler: rolesHandler.ListVersions},
+		{Method: "GET", Path: "/admin/roles/{role}/versions/{version}", Superuser: true, Summary: "Get one permission version", Returns: models.RolePermissionVersion{}, Handler: rolesHandler.GetVersion},
+		{Method: "GET", Path: "/admin/roles/{role}/diff", Superuser: true, Summary: "Rule changes between two versions, or a version and the live config", Query: []string{"from", "to"}, Returns: diffResponse{}, Handler: rolesHandler.DiffVersions},
+		{Method: "POST", Path: "/admin/roles/{role}/rollback", Superuser: true, Summary: "Restore an earlier version as a new version", Body: rollbackRequest{}, Returns: rollbackResponse{}, Errors: invalidPerm, Handler: rolesHandler.Rollback},
+		{Method: "POST", Path: "/admin/roles/{role}/simulate", Superuser: true, Summary: "Report who would gain or lose access under proposed permissions", Body: models.Permissions{}, Returns: simulateResponse{}, Errors: invalidPerm, Handler: rolesHandler.Simulate},
+		{Method: "GET", Path: "/admin/constraints", Superuser: true, Summary: "Read separation-of-duties constraints", Returns: models.Constraints{}, Handler: rolesHandler.GetConstraints},
+		{Method: "PUT", Path: "/admin/constraints", Superuser: true, DefaultOrg: true, Summary: "Replace constraints", Body: models.Constraints{}, Returns: status, Handler: rolesHandler.UpdateConstraints},
+		{Method: "POST", Path: "/admin/constraints", Superuser: true, DefaultOrg: true, Summary: "Replace constraints", Body: models.Constraints{}, Returns: status, Handler: rolesHandler.UpdateConstraints},
+		{Method: "GET", Path: "/admin/audit", Superuser: true, Summary: "Recent audit log entries", Query: []string{"limit"}, Returns: auditResponse{}, Handler: elevationHandler.ListAudit},
+	}
+
+	// The spec describes the final slice, itself included.
+	rs = append(rs, Route{Method: "GET", Path: "/openapi.json", Public: true, Summary: "This OpenAPI document", Returns: map[string]interface{}{}})
+	rs[len(rs)-1].Handler = ServeOpenAPI(&rs)
+	return rs
+}
+
+// listParams are the query parameters of a list endpoint for l (see
+// handlers.listQuery).
+func listParams(l repositories.Listing) []string {
+	params := []string{"limit", "cursor", "sort", "q"}
+	names := map[string]string{}
+	for param, field := range l.Params {
+		names[field] = param
+	}
+	for _, f := range l.Filters {
+		if param, ok := names[f]; ok {
+			f = param
+		}
+		params = append(params, f)
+	}
+	for _, d := range l.Dates {
+		params = append(params, d+"_from", d+"_to")
+	}
+	return params
+}
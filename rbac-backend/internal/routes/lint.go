@@ -0,0 +1,134 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"rbac-backend/internal/rbac"
+)
+
+// Problem is a mistake Lint found in a route declaration.
+type Problem struct {
+	Route   string
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Route + ": " + p.Message
+}
+
+// deprecatedVerbs maps the last segment of a verb-named alias to the action
+// it performs.
+var deprecatedVerbs = map[string]string{
+	"create": rbac.ActionCreate,
+	"get":    rbac.ActionView,
+	"update": rbac.ActionEdit,
+	"assign": rbac.ActionEdit,
+	"delete": rbac.ActionDelete,
+}
+
+// Lint checks rs for duplicate patterns, unknown tables or actions,
+// conflicting access settings, actions that do not fit the method (a DELETE
+// route must check delete, a GET route view, a POST route that creates
+// something create, and so on, except in a trash) and deprecated aliases whose
+// successor is not registered.
+func Lint(rs []Route) []Problem {
+	var problems []Problem
+	add := func(rt Route, format string, args ...interface{}) {
+		problems = append(problems, Problem{Route: rt.Pattern(), Message: fmt.Sprintf(format, args...)})
+	}
+
+	seen := map[string]bool{}
+	current := map[string]bool{}
+	for _, rt := range rs {
+		if rt.Successor == "" {
+			current[rt.Path] = true
+		}
+	}
+
+	for _, rt := range rs {
+		if seen[rt.Pattern()] {
+			add(rt, "registered more than once")
+		}
+		seen[rt.Pattern()] = true
+
+		if rt.Handler == nil {
+			add(rt, "no handler")
+		}
+		if rt.Public && (rt.Superuser || rt.Table != "") {
+			add(rt, "public route cannot require superuser or a table permission")
+		}
+		if rt.Superuser && rt.Table != "" {
+			add(rt, "set either superuser or a table permission, not both")
+		}
+		if rt.Successor != "" && !current[rt.Successor] {
+			add(rt, "successor %s is not registered", rt.Successor)
+		}
+
+		if rt.Table == "" {
+			if rt.Action != "" {
+				add(rt, "action %q without a table", rt.Action)
+			}
+			continue
+		}
+		if _, ok := rbac.Registry[rt.Table]; !ok {
+			add(rt, "unknown table %q", rt.Table)
+		}
+		if !slices.Contains(rbac.TableActions, rt.Action) {
+			add(rt, "unknown action %q", rt.Action)
+			continue
+		}
+		if want := expectedActions(rt); len(want) > 0 && !slices.Contains(want, rt.Action) {
+			add(rt, "checks %s:%s, expected %s", rt.Table, rt.Action, joinOr(want))
+		}
+	}
+	return problems
+}
+
+// expectedActions are the actions that fit rt: the verb in the path for a
+// deprecated alias, restore or purge under a table's trash, otherwise the one
+// implied by the method. A POST route creates something when it answers 201 or
+// its last segment starts with "create".
+func expectedActions(rt Route) []string {
+	if rt.Successor != "" {
+		if a, ok := deprecatedVerbs[path.Base(rt.Path)]; ok {
+			return []string{a}
+		}
+	}
+	if strings.Contains(rt.Path+"/", "/trash/") {
+		// Listing and restoring the trash check restore; emptying it, purge.
+		if rt.Method == http.MethodDelete {
+			return []string{rbac.ActionPurge}
+		}
+		return []string{rbac.ActionRestore}
+	}
+	switch rt.Method {
+	case http.MethodGet, http.MethodHead:
+		return []string{rbac.ActionView}
+	case http.MethodPut, http.MethodPatch:
+		return []string{rbac.ActionEdit}
+	case http.MethodDelete:
+		return []string{rbac.ActionDelete}
+	case http.MethodPost:
+		if rt.Status == http.StatusCreated || strings.HasPrefix(path.Base(rt.Path), "create") {
+			return []string{rbac.ActionCreate}
+		}
+		return []string{rbac.ActionCreate, rbac.ActionEdit}
+	}
+	return nil
+}
+
+func joinOr(actions []string) string {
+	s := actions[0]
+	for i, a := range actions[1:] {
+		if i == len(actions)-2 {
+			s += " or " + a
+		} else {
+			s += ", " + a
+		}
+	}
+	return s
+}
@@ -0,0 +1,58 @@
+package routes
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// MatrixRow is one line of the route-permission matrix.
+type MatrixRow struct {
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Access     string `json:"access"`
+	DefaultOrg bool   `json:"default_org,omitempty"`
+	Successor  string `json:"deprecated_by,omitempty"`
+	Summary    string `json:"summary"`
+}
+
+// Matrix lists who may call each route, ordered by path and method.
+func Matrix(rs []Route) []MatrixRow {
+	rows := make([]MatrixRow, 0, len(rs))
+	for _, rt := range rs {
+		method := rt.Method
+		if method == "" {
+			method = "*"
+		}
+		rows = append(rows, MatrixRow{
+			Method:     method,
+			Path:       rt.Path,
+			Access:     rt.Access(),
+			DefaultOrg: rt.DefaultOrg,
+			Successor:  rt.Successor,
+			Summary:    rt.Summary,
+		})
+	}
+	slices.SortFunc(rows, func(a, b MatrixRow) int {
+		return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.Method, b.Method))
+	})
+	return rows
+}
+
+// WriteMatrix prints the matrix as a Markdown table.
+func WriteMatrix(w io.Writer, rs []Route) {
+	fmt.Fprintln(w, "| Method | Path | Access | Notes | Summary |")
+	fmt.Fprintln(w, "| --- | --- | --- | --- | --- |")
+	for _, row := range Matrix(rs) {
+		var notes []string
+		if row.DefaultOrg {
+			notes = append(notes, "default org only")
+		}
+		if row.Successor != "" {
+			notes = append(notes, "deprecated, use "+row.Successor)
+		}
+		fmt.Fprintf(w, "| %s | `%s` | %s | %s | %s |\n", row.Method, row.Path, row.Access, strings.Join(notes, "; "), row.Summary)
+	}
+}
@@ -0,0 +1,363 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// OneOf documents a body that has one of several shapes, e.g. a report
+// instead of the usual result when ?dry_run=true.
+type OneOf []interface{}
+
+var pathParam = regexp.MustCompile(`\{([^}.$]+)(\.\.\.)?\}`)
+
+var (
+	jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	timeType      = reflect.TypeOf(time.Time{})
+)
+
+// OpenAPI builds an OpenAPI 3 document from rs. Request and response schemas
+// come from the Body, Returns and Errors types of each route; every operation
+// carries the access it requires in x-rbac. Routes without a method are left
+// out.
+func OpenAPI(rs []Route) map[string]interface{} {
+	g := &specGen{components: map[string]interface{}{}, partial: map[reflect.Type]bool{}}
+	for _, rt := range rs {
+		// Table routes return records filtered by the caller's field rules, so
+		// their fields are not marked required.
+		if rt.Table != "" && rt.Returns != nil {
+			g.partial[elemType(reflect.TypeOf(rt.Returns))] = true
+		}
+	}
+
+	paths := map[string]map[string]interface{}{}
+	for _, rt := range rs {
+		if rt.Method == "" {
+			continue
+		}
+		p := specPath(rt.Path)
+		if paths[p] == nil {
+			paths[p] = map[string]interface{}{}
+		}
+		paths[p][strings.ToLower(rt.Method)] = g.operation(rt)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "RBAC System API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": g.components,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+}
+
+// ServeOpenAPI serves OpenAPI(rs) as JSON. The document is built on first use.
+func ServeOpenAPI(rs *[]Route) http.HandlerFunc {
+	var once sync.Once
+	var doc []byte
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { doc, _ = json.Marshal(OpenAPI(*rs)) })
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(doc)
+	}
+}
+
+// specPath turns a ServeMux path into an OpenAPI one: "/{$}" becomes "/".
+func specPath(p string) string {
+	return strings.ReplaceAll(p, "{$}", "")
+}
+
+// OperationKey is how the spec identifies the operation for a ServeMux pattern
+// such as "GET /tasks/{id}": "get /tasks/{id}".
+func OperationKey(pattern string) string {
+	method, path, _ := strings.Cut(pattern, " ")
+	return strings.ToLower(method) + " " + specPath(path)
+}
+
+type specGen struct {
+	components map[string]interface{}
+	partial    map[reflect.Type]bool
+}
+
+func (g *specGen) operation(rt Route) map[string]interface{} {
+	op := map[string]interface{}{
+		"summary": rt.Summary,
+		"x-rbac":  accessAnnotation(rt),
+	}
+	if rt.Successor != "" {
+		op["deprecated"] = true
+		op["description"] = "Deprecated: use " + rt.Successor + "."
+		if op["summary"] == "" {
+			op["summary"] = "Deprecated alias of " + rt.Successor
+		}
+	}
+	if !rt.Public {
+		op["security"] = []interface{}{map[string]interface{}{"bearerAuth": []interface{}{}}}
+	}
+
+	var params []interface{}
+	for _, m := range pathParam.FindAllStringSubmatch(rt.Path, -1) {
+		params = append(params, map[string]interface{}{
+			"name": m[1], "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, q := range rt.Query {
+		params = append(params, map[string]interface{}{
+			"name": q, "in": "query", "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if rt.Body != nil {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": g.schemaOf(rt.Body)}},
+		}
+	}
+
+	status := rt.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	responses := map[string]interface{}{
+		strconv.Itoa(status): g.response(http.StatusText(status), rt.Returns),
+		"default":            g.errorResponse("Error", nil),
+	}
+	codes := make([]int, 0, len(rt.Errors))
+	for code := range rt.Errors {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		responses[strconv.Itoa(code)] = g.errorResponse(http.StatusText(code), rt.Errors[code])
+	}
+	op["responses"] = responses
+	return op
+}
+
+func accessAnnotation(rt Route) map[string]interface{} {
+	a := map[string]interface{}{"access": rt.Access()}
+	if rt.Table != "" {
+		a["table"] = rt.Table
+		a["action"] = rt.Action
+	}
+	if rt.DefaultOrg {
+		a["default_org"] = true
+	}
+	return a
+}
+
+func (g *specGen) response(description string, body interface{}) map[string]interface{} {
+	if body == nil {
+		return map[string]interface{}{"description": description}
+	}
+	if s, ok := body.(string); ok {
+		return map[string]interface{}{
+			"description": s,
+			"content":     map[string]interface{}{"text/plain": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}},
+		}
+	}
+	return jsonResponse(description, g.schemaOf(body))
+}
+
+// errorResponse documents the apierror envelope. details, when not nil, is
+// an example of the error's details; otherwise they are left open and the
+// shared "Error" component is used.
+func (g *specGen) errorResponse(description string, details interface{}) map[string]interface{} {
+	if details == nil {
+		if _, ok := g.components["Error"]; !ok {
+			g.components["Error"] = envelopeSchema(map[string]interface{}{})
+		}
+		return jsonResponse(description, map[string]interface{}{"$ref": "#/components/schemas/Error"})
+	}
+	return jsonResponse(description, envelopeSchema(g.schemaOf(details)))
+}
+
+func envelopeSchema(details map[string]interface{}) map[string]interface{} {
+	str := map[string]interface{}{"type": "string"}
+	body := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"code": str, "message": str, "details": details, "request_id": str,
+		},
+		"required":             []string{"code", "message"},
+		"additionalProperties": false,
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           map[string]interface{}{"error": body},
+		"required":             []string{"error"},
+		"additionalProperties": false,
+	}
+}
+
+func jsonResponse(description string, schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}},
+	}
+}
+
+func (g *specGen) schemaOf(v interface{}) map[string]interface{} {
+	if alts, ok := v.(OneOf); ok {
+		var list []interface{}
+		for _, alt := range alts {
+			list = append(list, g.schemaOf(alt))
+		}
+		return map[string]interface{}{"oneOf": list}
+	}
+	return g.schema(reflect.TypeOf(v))
+}
+
+// schema maps t to a JSON schema the way encoding/json would encode it. Named
+// structs become components referenced with $ref. Slices and maps can encode
+// as null, so they are nullable.
+func (g *specGen) schema(t reflect.Type) map[string]interface{} {
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	if t.Kind() == reflect.Ptr {
+		return nullable(g.schema(t.Elem()))
+	}
+	if t.Implements(jsonMarshaler) {
+		// Custom encodings (e.g. permission rules) are described in the docs.
+		return map[string]interface{}{"type": "object", "additionalProperties": true}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem()), "nullable": true}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem()), "nullable": true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := componentName(t)
+		if _, ok := g.components[name]; !ok {
+			g.components[name] = map[string]interface{}{} // placeholder for recursive types
+			g.components[name] = g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+func (g *specGen) object(t reflect.Type) map[string]interface{} {
+	props := map[string]interface{}{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Tag.Get("json") == "" && f.Type.Kind() == reflect.Struct {
+			// encoding/json promotes the fields of an embedded struct.
+			embedded := g.object(f.Type)
+			for k, v := range embedded["properties"].(map[string]interface{}) {
+				props[k] = v
+			}
+			if req, ok := embedded["required"].([]string); ok && !g.partial[t] {
+				required = append(required, req...)
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = constrain(g.schema(f.Type), f.Tag.Get("validate"))
+		if !strings.Contains(opts, "omitempty") && !g.partial[t] {
+			required = append(required, name)
+		}
+	}
+	s := map[string]interface{}{"type": "object", "properties": props, "additionalProperties": false}
+	if len(required) > 0 {
+		sort.Strings(required)
+		s["required"] = required
+	}
+	return s
+}
+
+// constrain adds the rules of a validate tag (see package validate) that JSON
+// schema can express: enum, lengths and the uuid format.
+func constrain(s map[string]interface{}, tag string) map[string]interface{} {
+	if tag == "" {
+		return s
+	}
+	target := s
+	if items, ok := s["items"].(map[string]interface{}); ok {
+		target = items // enum and uuid apply to each element
+	}
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		n, _ := strconv.Atoi(arg)
+		switch name {
+		case "enum":
+			target["enum"] = strings.Split(arg, "|")
+		case "uuid":
+			target["format"] = "uuid"
+		case "min", "max":
+			key := map[string]string{"min": "minLength", "max": "maxLength"}[name]
+			if s["type"] == "array" {
+				key = map[string]string{"min": "minItems", "max": "maxItems"}[name]
+			}
+			s[key] = n
+		}
+	}
+	return s
+}
+
+func nullable(s map[string]interface{}) map[string]interface{} {
+	if _, ok := s["$ref"]; ok {
+		return map[string]interface{}{"allOf": []interface{}{s}, "nullable": true}
+	}
+	out := map[string]interface{}{"nullable": true}
+	for k, v := range s {
+		out[k] = v
+	}
+	return out
+}
+
+// componentName is the type name with its first letter upper-cased, so the
+// request and response types local to this package read like the models.
+func componentName(t reflect.Type) string {
+	r := []rune(t.Name())
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func elemType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,366 @@
+package routes
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"rbac-backend/internal/apierror"
+	"rbac-backend/internal/config"
+	"rbac-backend/internal/db"
+	"rbac-backend/internal/handlers"
+
+	_ "modernc.org/sqlite"
+)
+
+// TestHandlersMatchOpenAPI drives every documented operation against a
+// migrated database and checks each response against the spec: the status
+// must be documented and JSON bodies must match the schema for it, with no
+// undocumented fields. Changing a handler's output without its Route
+// description (or the reverse) fails here.
+func TestHandlersMatchOpenAPI(t *testing.T) {
+	config.AppConfig = &config.Config{JWTSecret: "test-secret"}
+	t.Chdir(filepath.Join("..", "..")) // RunMigrations looks for ./migrations
+
+	database, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "rbac.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	if err := db.RunMigrations(database); err != nil {
+		t.Fatal(err)
+	}
+	db.SeedAdmin(database)
+
+	api := API(database)
+	mux := http.NewServeMux()
+	Register(mux, database, api)
+	c := &specClient{t: t, mux: mux, spec: decodeSpec(t, OpenAPI(api)), seen: map[string]bool{}}
+
+	c.call("GET", "/", "", nil, 200)
+	c.call("GET", "/openapi.json", "", nil, 200)
+	admin := c.call("POST", "/login", "", obj{"email": "admin@example.com", "password": "admin123"}, 200)["token"].(string)
+
+	me := c.call("GET", "/me", admin, nil, 200)
+	adminID := me["user"].(obj)["id"].(string)
+	c.call("GET", "/me/permissions", admin, nil, 200)
+
+	// Projects and tasks
+	pid := c.call("POST", "/projects", admin, obj{"name": "Apollo", "description": "d", "assigned_employees": []string{adminID}}, 200)["id"].(string)
+	c.call("GET", "/projects", admin, nil, 200)
+	c.call("GET", "/projects/"+pid, admin, nil, 200)
+	read := c.header.Get("ETag")
+	c.call("PATCH", "/projects/"+pid, admin, obj{"name": "Apollo 2"}, 428)
+	c.ifMatch = read
+	c.call("PATCH", "/projects/"+pid, admin, obj{"name": "Apollo 2"}, 200)
+	c.call("PATCH", "/projects/"+pid, admin, obj{"name": "Apollo 3"}, 412)
+	c.ifMatch = "*"
+	first := c.call("POST", "/projects/"+pid+"/tasks", admin, obj{"title": "First"}, 200)["id"].(string)
+	c.call("GET", "/projects/"+pid+"/tasks", admin, nil, 200)
+	tid := c.call("POST", "/tasks", admin, obj{"project_id": pid, "title": "Second", "assignees": []string{adminID}}, 200)["id"].(string)
+	c.call("GET", "/tasks?project_id="+pid, admin, nil, 200)
+	c.call("GET", "/tasks?project_id="+pid+"&limit=1&sort=title&q=s", admin, nil, 200)
+	next := c.header.Get(handlers.NextCursorHeader)
+	c.call("GET", "/tasks?project_id="+pid+"&limit=1&sort=title&q=s&cursor="+next, admin, nil, 200)
+	c.call("GET", "/tasks?sort=-bogus", admin, nil, 400)
+	c.call("GET", "/tasks?sort=-title&cursor="+next, admin, nil, 400)
+	c.call("POST", "/tasks", admin, obj{"project_id": pid, "status": "BOGUS", "assignees": []string{"nobody"}}, 422)
+	c.call("GET", "/tasks/"+tid, admin, nil, 200)
+	c.call("PATCH", "/tasks/"+tid, admin, obj{"status": "IN_PROGRESS"}, 200)
+	c.call("POST", "/tasks/"+tid+"/assignees", admin, obj{"assignee": adminID}, 200)
+	c.call("GET", "/search?q=seco", admin, nil, 200)
+	c.call("GET", "/search?q=apollo&kind=project&limit=5", admin, nil, 200)
+	c.call("GET", "/search?kind=project", admin, nil, 400)
+
+	// Constraints, including the structured 403
+	constraints := c.call("GET", "/admin/constraints", admin, nil, 200)
+	c.call("PUT", "/admin/constraints", admin, obj{"actions": []obj{
+		{"id": "no-done", "table": "tasks", "action": "edit", "deny_if": `change.status == "DONE"`},
+	}}, 200)
+	c.call("PATCH", "/tasks/"+tid, admin, obj{"status": "DONE"}, 403)
+	c.call("PUT", "/admin/constraints", admin, obj{"actions": []obj{
+		{"id": "never-checked", "table": "projects", "action": "delete", "deny_if": "true"},
+	}}, 400)
+	c.call("POST", "/admin/constraints", admin, constraints, 200)
+	c.call("DELETE", "/projects/"+pid, admin, nil, 409) // tasks keep their project from being deleted
+	c.call("DELETE", "/tasks/"+tid, admin, nil, 200)
+	c.call("DELETE", "/tasks/"+first, admin, nil, 200)
+	c.call("GET", "/tasks/"+tid, admin, nil, 404)
+	c.call("DELETE", "/projects/"+pid, admin, nil, 200)
+
+	// Trash: deleted records come back until they are purged
+	c.call("GET", "/tasks/trash?sort=title", admin, nil, 200)
+	c.call("GET", "/projects/trash", admin, nil, 200)
+	c.call("POST", "/tasks/trash/"+tid+"/restore", admin, nil, 409) // its project is in the trash
+	c.call("DELETE", "/projects/trash/"+pid, admin, nil, 409)       // its tasks are in the trash
+	c.call("POST", "/projects/trash/"+pid+"/restore", admin, nil, 200)
+	c.call("POST", "/tasks/trash/"+tid+"/restore", admin, nil, 200)
+	c.call("GET", "/tasks/"+tid, admin, nil, 200)
+	c.call("DELETE", "/tasks/"+tid, admin, nil, 200)
+	c.call("DELETE", "/tasks/trash/"+tid, admin, nil, 200)
+	c.call("DELETE", "/tasks/trash/"+first, admin, nil, 200)
+	c.call("DELETE", "/projects/"+pid, admin, nil, 200)
+	c.call("DELETE", "/projects/trash/"+pid, admin, nil, 200)
+	c.call("POST", "/projects/trash/"+pid+"/restore", admin, nil, 404)
+
+	// Users
+	c.call("POST", "/admin/create-user", admin, obj{"name": "Vera", "email": "vera@example.com", "password": "pw"}, 201)
+	c.call("POST", "/admin/create-user", admin, obj{"name": "Vera", "email": "vera@example.com", "password": "pw"}, 409)
+	var veraID string
+	for _, u := range c.call("GET", "/api/users", admin, nil, 200)["users"].([]interface{}) {
+		if u.(obj)["email"] == "vera@example.com" {
+			veraID = u.(obj)["id"].(string)
+		}
+	}
+	c.call("POST", "/admin/update-user-role", admin, obj{"user_id": veraID, "role": "EDITOR"}, 200)
+
+	// Field modes: fields the caller cannot edit are listed, or refused
+	editor := c.call("POST", "/login", "", obj{"email": "vera@example.com", "password": "pw"}, 200)["token"].(string)
+	apollo := c.call("POST", "/projects", admin, obj{"name": "Apollo"}, 200)["id"].(string)
+	change := obj{"name": "Apollo 3", "created_by": veraID}
+	c.call("PATCH", "/projects/"+apollo, editor, change, 200)
+	c.fieldMode = "strict"
+	c.call("PATCH", "/projects/"+apollo, editor, change, 403)
+	c.fieldMode = ""
+	c.call("PUT", "/admin/update-user-role", admin, obj{"user_id": adminID, "role": "VIEWER"}, 409) // the last superuser
+	c.call("PUT", "/admin/update-user-role", admin, obj{"user_id": veraID, "role": "VIEWER"}, 200)
+	c.call("DELETE", "/projects/"+apollo, admin, nil, 200)
+
+	// Organizations
+	c.call("GET", "/orgs", admin, nil, 200)
+	orgID := c.call("POST", "/admin/orgs", admin, obj{"name": "Acme"}, 201)["id"].(string)
+	c.call("GET", "/admin/orgs", admin, nil, 200)
+	acme := c.call("POST", "/orgs/switch", admin, obj{"org_id": orgID}, 200)["token"].(string)
+	invitation := func() string {
+		c.call("POST", "/admin/org/members", acme, obj{"email": "vera@example.com", "role": "VIEWER"}, 202)
+		return c.call("GET", "/orgs/invitations", editor, nil, 200)["invitations"].([]interface{})[0].(obj)["id"].(string)
+	}
+	c.call("POST", "/orgs/invitations/"+invitation()+"/accept", editor, nil, 200)
+	c.call("POST", "/orgs/switch", editor, obj{"org_id": orgID}, 200)
+	c.call("DELETE", "/admin/org/members?user_id="+veraID, acme, nil, 200)
+	c.call("DELETE", "/orgs/invitations/"+invitation(), editor, nil, 200)
+
+	// Elevations
+	vera := c.call("POST", "/login", "", obj{"email": "vera@example.com", "password": "pw"}, 200)["token"].(string)
+	elevate := obj{"table": "projects", "action": "edit", "justification": "release", "duration_minutes": 30}
+	approved := c.call("POST", "/elevations/request", vera, elevate, 201)["id"].(string)
+	denied := c.call("POST", "/elevations/request", vera, elevate, 201)["id"].(string)
+	c.call("GET", "/elevations", admin, nil, 200)
+	c.call("POST", "/elevations/approve?id="+approved, admin, nil, 200)
+	c.call("POST", "/elevations/deny?id="+denied, admin, nil, 200)
+	c.call("POST", "/elevations/revoke?id="+approved, vera, nil, 200)
+
+	// Roles
+	perms := obj{"projects": obj{"view": true, "fields": obj{"name": obj{"view": true}}}}
+	c.call("GET", "/admin/roles", admin, nil, 200)
+	c.call("POST", "/admin/roles", admin, obj{"name": "AUDITOR", "description": "reads"}, 201)
+	c.call("GET", "/admin/roles/AUDITOR", admin, nil, 200)
+	c.call("PUT", "/admin/roles/AUDITOR", admin, perms, 200)
+	c.call("POST", "/admin/roles/AUDITOR?dry_run=true", admin, perms, 200)
+	c.call("POST", "/admin/roles/AUDITOR", admin, obj{"widgets": obj{"view": true}}, 422)
+	c.call("GET", "/admin/roles/AUDITOR/versions", admin, nil, 200)
+	c.call("GET", "/admin/roles/AUDITOR/versions/1", admin, nil, 200)
+	c.call("GET", "/admin/roles/AUDITOR/diff?from=1", admin, nil, 200)
+	c.call("POST", "/admin/roles/AUDITOR/rollback", admin, obj{"version": 1}, 200)
+	c.call("POST", "/admin/roles/VIEWER/simulate", admin, perms, 200)
+	c.call("PUT", "/admin/roles/AUDITOR/settings", admin, obj{"description": "audits"}, 200)
+	c.call("PATCH", "/admin/roles/AUDITOR/settings", admin, obj{"superuser": false}, 200)
+	c.call("GET", "/admin/audit?limit=5", admin, nil, 200)
+
+	var missed []string
+	for _, rt := range api {
+		if key := OperationKey(rt.Pattern()); rt.Successor == "" && !c.seen[key] {
+			missed = append(missed, key)
+		}
+	}
+	sort.Strings(missed)
+	if len(missed) > 0 {
+		t.Errorf("operations not exercised; extend the test:\n  %s", strings.Join(missed, "\n  "))
+	}
+}
+
+type obj = map[string]interface{}
+
+type specClient struct {
+	t    *testing.T
+	mux  *http.ServeMux
+	spec obj
+	seen map[string]bool
+
+	fieldMode string      // sent as X-Field-Mode when set
+	ifMatch   string      // sent as If-Match when set
+	header    http.Header // headers of the last response
+}
+
+func decodeSpec(t *testing.T, spec map[string]interface{}) obj {
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out obj
+	if err := json.Unmarshal(raw, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// call serves one request, checks the response against the spec and returns
+// the decoded JSON object body, if any.
+func (c *specClient) call(method, target, token string, body interface{}, want int) obj {
+	c.t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, target, &buf)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if c.fieldMode != "" {
+		req.Header.Set(handlers.FieldModeHeader, c.fieldMode)
+	}
+	if c.ifMatch != "" {
+		req.Header.Set("If-Match", c.ifMatch)
+	}
+	_, pattern := c.mux.Handler(req)
+	key := OperationKey(pattern)
+	rec := httptest.NewRecorder()
+	c.mux.ServeHTTP(rec, req)
+	c.header = rec.Header()
+
+	if rec.Code != want {
+		c.t.Fatalf("%s %s: status %d, want %d: %s", method, target, rec.Code, want, rec.Body)
+	}
+	path, _ := strings.CutPrefix(key, strings.ToLower(method)+" ")
+	op, _ := c.spec["paths"].(obj)[path].(obj)[strings.ToLower(method)].(obj)
+	if op == nil {
+		c.t.Fatalf("%s %s: operation %q is not in the spec", method, target, key)
+	}
+	c.seen[key] = true
+
+	responses := op["responses"].(obj)
+	resp, ok := responses[strconv.Itoa(rec.Code)].(obj)
+	if !ok {
+		if rec.Code < 400 {
+			c.t.Fatalf("%s: status %d is not documented", key, rec.Code)
+		}
+		resp = responses["default"].(obj)
+	}
+	if rec.Code >= 400 && rec.Header().Get(apierror.RequestIDHeader) == "" {
+		c.t.Errorf("%s %d: no %s header", key, rec.Code, apierror.RequestIDHeader)
+	}
+	isJSON := strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json")
+	if rec.Code >= 400 && !isJSON {
+		c.t.Fatalf("%s: %d error body is not JSON: %s", key, rec.Code, rec.Body)
+	}
+	if !isJSON {
+		return nil
+	}
+	media, _ := resp["content"].(obj)["application/json"].(obj)
+	if media == nil {
+		c.t.Fatalf("%s: %d returns JSON but the spec documents none", key, rec.Code)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+		c.t.Fatalf("%s: invalid JSON: %v", key, err)
+	}
+	for _, problem := range c.validate(media["schema"].(obj), decoded, "$") {
+		c.t.Errorf("%s %d: %s", key, rec.Code, problem)
+	}
+	m, _ := decoded.(obj)
+	return m
+}
+
+// validate checks v against the subset of JSON schema OpenAPI emits.
+func (c *specClient) validate(s obj, v interface{}, at string) []string {
+	if ref, ok := s["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		return c.validate(c.spec["components"].(obj)["schemas"].(obj)[name].(obj), v, at)
+	}
+	if v == nil {
+		if s["nullable"] == true || len(s) == 0 {
+			return nil
+		}
+		return []string{at + ": null is not allowed"}
+	}
+	if all, ok := s["allOf"].([]interface{}); ok {
+		var problems []string
+		for _, sub := range all {
+			problems = append(problems, c.validate(sub.(obj), v, at)...)
+		}
+		return problems
+	}
+	if one, ok := s["oneOf"].([]interface{}); ok {
+		for _, sub := range one {
+			if len(c.validate(sub.(obj), v, at)) == 0 {
+				return nil
+			}
+		}
+		return []string{at + ": matches none of the documented shapes"}
+	}
+
+	typ, _ := s["type"].(string)
+	switch typ {
+	case "object":
+		m, ok := v.(obj)
+		if !ok {
+			return []string{fmt.Sprintf("%s: %T, want object", at, v)}
+		}
+		var problems []string
+		props, _ := s["properties"].(obj)
+		for k, fv := range m {
+			switch sub := props[k]; {
+			case sub != nil:
+				problems = append(problems, c.validate(sub.(obj), fv, at+"."+k)...)
+			case s["additionalProperties"] == false:
+				problems = append(problems, at+"."+k+": undocumented field")
+			default:
+				if extra, ok := s["additionalProperties"].(obj); ok {
+					problems = append(problems, c.validate(extra, fv, at+"."+k)...)
+				}
+			}
+		}
+		required, _ := s["required"].([]interface{})
+		for _, r := range required {
+			if _, ok := m[r.(string)]; !ok {
+				problems = append(problems, at+"."+r.(string)+": required field missing")
+			}
+		}
+		return problems
+	case "array":
+		list, ok := v.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: %T, want array", at, v)}
+		}
+		var problems []string
+		for i, item := range list {
+			problems = append(problems, c.validate(s["items"].(obj), item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+		return problems
+	case "string", "boolean", "number", "integer":
+		got := map[bool]string{}
+		switch x := v.(type) {
+		case string:
+			got[true] = "string"
+		case bool:
+			got[true] = "boolean"
+		case float64:
+			got[true] = "number"
+			if typ == "integer" && x == float64(int64(x)) {
+				got[true] = "integer"
+			}
+		}
+		if got[true] != typ {
+			return []string{fmt.Sprintf("%s: %T, want %s", at, v, typ)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Package routes is the HTTP route registry. Each endpoint declares its
+// method, path and required access once; Register wires authentication and
+// RBAC from that declaration, Lint checks it for mistakes and WriteMatrix
+// reports it.
+package routes
+
+import (
+	"database/sql"
+	"net/http"
+	"slices"
+	"strings"
+
+	"rbac-backend/internal/apierror"
+	"rbac-backend/internal/middleware"
+)
+
+// Route is one endpoint. Access is, from most to least open:
+//
+//   - Public: no token needed
+//   - neither Public, Superuser nor Table set: any authenticated user
+//   - Table and Action: RBACMiddleware for that table action
+//   - Superuser: a superuser role
+//
+// DefaultOrg additionally restricts the route to the default organization.
+// Successor marks a deprecated alias and names the route replacing it.
+//
+// Query, Body, Returns, Status and Errors only describe the route for
+// OpenAPI: Body and Returns are example values whose types give the JSON
+// schemas (a string Returns is a plain-text body), Status is the success
+// status (200 when zero) and Errors gives, by status, example details of the
+// JSON error envelope.
+type Route struct {
+	Method     string
+	Path       string
+	Summary    string
+	Public     bool
+	Superuser  bool
+	Table      string
+	Action     string
+	DefaultOrg bool
+	Successor  string
+	Handler    http.HandlerFunc
+
+	Query   []string
+	Body    interface{}
+	Returns interface{}
+	Status  int
+	Errors  map[int]interface{}
+}
+
+// Pattern is the ServeMux pattern of the route. Routes without a method
+// dispatch on the method themselves.
+func (rt Route) Pattern() string {
+	if rt.Method == "" {
+		return rt.Path
+	}
+	return rt.Method + " " + rt.Path
+}
+
+// Access describes who may call the route: "public", "authenticated",
+// "superuser" or "<table>:<action>".
+func (rt Route) Access() string {
+	switch {
+	case rt.Public:
+		return "public"
+	case rt.Superuser:
+		return "superuser"
+	case rt.Table != "":
+		return rt.Table + ":" + rt.Action
+	}
+	return "authenticated"
+}
+
+// Register adds rs to mux with the middleware their access requires, behind
+// middleware.RequestID so error bodies carry the request id. A deprecated
+// alias answers its other methods with 405, since a verb-named path such as
+// /tasks/delete would otherwise match a resource pattern such as
+// GET /tasks/{id}.
+func Register(mux *http.ServeMux, database *sql.DB, rs []Route) {
+	methods := map[string][]string{}
+	for _, rt := range rs {
+		mux.Handle(rt.Pattern(), wrap(database, rt))
+		methods[rt.Path] = append(methods[rt.Path], rt.Method)
+	}
+	for _, rt := range rs {
+		if rt.Successor == "" || methods[rt.Path] == nil {
+			continue
+		}
+		allowed := methods[rt.Path]
+		for _, m := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			if !slices.Contains(allowed, m) {
+				mux.Handle(m+" "+rt.Path, middleware.RequestID(methodNotAllowed(allowed...)))
+			}
+		}
+		delete(methods, rt.Path)
+	}
+}
+
+func wrap(database *sql.DB, rt Route) http.Handler {
+	h := http.Handler(rt.Handler)
+	if rt.DefaultOrg {
+		h = middleware.RequireDefaultOrg(h)
+	}
+	switch {
+	case rt.Table != "":
+		h = middleware.RBACMiddleware(database, rt.Table, rt.Action, h)
+	case rt.Superuser:
+		h = middleware.RequireSuperuser(database, h)
+	}
+	if !rt.Public {
+		h = middleware.AuthMiddleware(database, h)
+	}
+	if rt.Successor != "" {
+		h = middleware.Deprecated(rt.Successor, h)
+	}
+	return middleware.RequestID(h)
+}
+
+// methodNotAllowed answers 405 with the given methods in the Allow header,
+// as the mux does for paths registered only with other methods.
+func methodNotAllowed(allow ...string) http.Handler {
+	if slices.Contains(allow, http.MethodGet) {
+		allow = append(allow, http.MethodHead)
+	}
+	sorted := slices.Sorted(slices.Values(allow))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", strings.Join(sorted, ", "))
+		apierror.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	})
+}
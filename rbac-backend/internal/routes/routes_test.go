@@ -0,0 +1,85 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIPassesLint(t *testing.T) {
+	for _, p := range Lint(API(nil)) {
+		t.Error(p)
+	}
+}
+
+func TestLintFindsMistakes(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) {}
+	rs := []Route{
+		{Method: "GET", Path: "/projects/{id}", Table: "projects", Action: "view", Handler: h},
+		{Method: "DELETE", Path: "/projects/{id}", Table: "projects", Action: "create", Handler: h},
+		{Method: "POST", Path: "/projects/delete", Table: "projects", Action: "create", Successor: "/projects/{id}", Handler: h},
+		{Method: "POST", Path: "/tasks/assign", Table: "tasks", Action: "edit", Successor: "/tasks/{id}/assignees", Handler: h},
+		{Method: "GET", Path: "/widgets", Table: "widgets", Action: "view", Handler: h},
+		{Method: "GET", Path: "/widgets", Table: "widgets", Action: "view", Handler: h},
+		{Method: "GET", Path: "/open", Public: true, Superuser: true, Handler: h},
+		{Method: "GET", Path: "/tasks/trash", Table: "tasks", Action: "restore", Handler: h},
+		{Method: "DELETE", Path: "/tasks/trash/{id}", Table: "tasks", Action: "delete", Handler: h},
+		{Method: "POST", Path: "/admin/create-user", Table: "users", Action: "edit", Handler: h},
+		{Method: "POST", Path: "/comments", Table: "tasks", Action: "edit", Status: http.StatusCreated, Handler: h},
+		{Method: "POST", Path: "/tasks/{id}/archive", Table: "tasks", Action: "edit", Handler: h},
+	}
+
+	want := []string{
+		"DELETE /projects/{id}: checks projects:create, expected delete",
+		"POST /projects/delete: checks projects:create, expected delete",
+		"POST /tasks/assign: successor /tasks/{id}/assignees is not registered",
+		`GET /widgets: unknown table "widgets"`,
+		"GET /widgets: registered more than once",
+		"GET /open: public route cannot require superuser or a table permission",
+		"DELETE /tasks/trash/{id}: checks tasks:delete, expected purge",
+		"POST /admin/create-user: checks users:edit, expected create",
+		"POST /comments: checks tasks:edit, expected create",
+	}
+	got := map[string]bool{}
+	for _, p := range Lint(rs) {
+		got[p.String()] = true
+	}
+	for _, w := range want {
+		if !got[w] {
+			t.Errorf("missing problem %q; got %v", w, got)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected %d problems, got %d: %v", len(want), len(got), got)
+	}
+}
+
+func TestRegisterRefusesOtherMethodsOnDeprecatedAliases(t *testing.T) {
+	h := func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(r.PathValue("id"))) }
+	mux := http.NewServeMux()
+	Register(mux, nil, []Route{
+		{Method: "GET", Path: "/tasks/{id}", Public: true, Handler: h},
+		{Method: "DELETE", Path: "/tasks/delete", Public: true, Successor: "/tasks/{id}", Handler: h},
+		{Method: "POST", Path: "/tasks/delete", Public: true, Successor: "/tasks/{id}", Handler: h},
+	})
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/tasks/delete", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "DELETE, POST" {
+		t.Fatalf("GET on alias: got %d, Allow %q", rec.Code, rec.Header().Get("Allow"))
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("DELETE", "/tasks/delete", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Deprecation") != "true" ||
+		!strings.Contains(rec.Header().Get("Link"), "</tasks/{id}>") {
+		t.Fatalf("DELETE on alias: got %d, headers %v", rec.Code, rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/tasks/t1", nil))
+	if rec.Body.String() != "t1" {
+		t.Fatalf("GET /tasks/{id}: got %q", rec.Body.String())
+	}
+}
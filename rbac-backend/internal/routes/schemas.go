@@ -0,0 +1,193 @@
+package routes
+
+import (
+	"rbac-backend/internal/handlers"
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+)
+
+// The types below only describe request and response bodies for the OpenAPI
+// spec. Handlers build most of their responses as maps; the drift test in
+// openapi_test.go keeps these descriptions in step with them.
+
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	OrgID    string `json:"org_id,omitempty"`
+}
+
+type tokenResponse struct {
+	Token string `json:"token"`
+	OrgID string `json:"org_id"`
+}
+
+type statusResponse struct {
+	Status string `json:"status"`
+}
+
+// updateResponse reports a write and, in lenient field mode, the fields it
+// ignored.
+type updateResponse struct {
+	Status        string   `json:"status"`
+	IgnoredFields []string `json:"ignored_fields,omitempty"`
+}
+
+// projectResult is a created project as the caller may see it.
+type projectResult struct {
+	models.Project
+	IgnoredFields []string `json:"ignored_fields,omitempty"`
+}
+
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+type createUserRequest struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role,omitempty"`
+}
+
+type updateUserRoleRequest struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+type usersResponse struct {
+	Users []models.User `json:"users"`
+}
+
+type searchResponse struct {
+	Results []handlers.SearchResult `json:"results"`
+}
+
+type meResponse struct {
+	User       models.User        `json:"user"`
+	OrgID      string             `json:"org_id"`
+	Elevations []models.Elevation `json:"elevations"`
+}
+
+type tableAccess struct {
+	Actions    map[string]bool                   `json:"actions"`
+	Conditions map[string]string                 `json:"conditions,omitempty"`
+	Fields     map[string]models.FieldPermission `json:"fields"`
+}
+
+type permissionsResponse struct {
+	UserID     string                 `json:"user_id"`
+	OrgID      string                 `json:"org_id"`
+	Role       string                 `json:"role"`
+	Elevations []string               `json:"elevations"`
+	Tables     map[string]tableAccess `json:"tables"`
+}
+
+type orgsResponse struct {
+	Active        string              `json:"active"`
+	Organizations []models.Membership `json:"organizations"`
+}
+
+type switchRequest struct {
+	OrgID string `json:"org_id"`
+}
+
+type switchResponse struct {
+	Token string `json:"token"`
+	OrgID string `json:"org_id"`
+	Role  string `json:"role"`
+}
+
+type orgRequest struct {
+	Name string `json:"name"`
+}
+
+type invitationsResponse struct {
+	Invitations []models.Invitation `json:"invitations"`
+}
+
+type memberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type elevationRequest struct {
+	Role            string `json:"role,omitempty"`
+	Table           string `json:"table,omitempty"`
+	Action          string `json:"action,omitempty"`
+	Justification   string `json:"justification"`
+	DurationMinutes int    `json:"duration_minutes"`
+}
+
+type elevationsResponse struct {
+	Elevations []models.Elevation `json:"elevations"`
+}
+
+type auditResponse struct {
+	Entries []models.AuditEntry `json:"entries"`
+}
+
+type rolesResponse struct {
+	Roles       []string      `json:"roles"`
+	Definitions []models.Role `json:"definitions"`
+}
+
+type roleSettingsRequest struct {
+	Superuser   *bool   `json:"superuser,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+type roleUpdateResponse struct {
+	Status   string            `json:"status"`
+	Version  int               `json:"version"`
+	Warnings []rbac.Diagnostic `json:"warnings"`
+}
+
+type versionsResponse struct {
+	Versions []models.RolePermissionVersion `json:"versions"`
+}
+
+// diffResponse.To is a version number, or "current" for the live config.
+type diffResponse struct {
+	Role    string        `json:"role"`
+	From    int           `json:"from"`
+	To      interface{}   `json:"to"`
+	Changes []rbac.Change `json:"changes"`
+}
+
+type rollbackRequest struct {
+	Version int    `json:"version"`
+	Comment string `json:"comment,omitempty"`
+}
+
+type rollbackResponse struct {
+	Status  string `json:"status"`
+	Version int    `json:"version"`
+}
+
+type recordChanges struct {
+	Gained []string `json:"gained,omitempty"`
+	Lost   []string `json:"lost,omitempty"`
+}
+
+type userImpact struct {
+	UserID  string                   `json:"user_id"`
+	Name    string                   `json:"name"`
+	Role    string                   `json:"role"`
+	Records map[string]recordChanges `json:"records"`
+}
+
+type simulateResponse struct {
+	Role          string                         `json:"role"`
+	AffectedRoles []string                       `json:"affected_roles"`
+	Changes       []rbac.Change                  `json:"changes"`
+	Access        map[string][]rbac.AccessChange `json:"access"`
+	UsersChecked  int                            `json:"users_checked"`
+	Users         []userImpact                   `json:"users"`
+	Warnings      []rbac.Diagnostic              `json:"warnings"`
+}
+
+// constraintDetails are the error details of a separation-of-duties refusal.
+type constraintDetails struct {
+	Constraint string   `json:"constraint"`
+	Roles      []string `json:"roles,omitempty"`
+}
@@ -1,54 +1,148 @@
 // internal/utils/field_filter.go
 package utils
 
-import "rbac-backend/internal/models"
+import (
+	"sort"
+
+	"rbac-backend/internal/models"
+	"rbac-backend/internal/rbac"
+)
 
 // FilterFields returns only fields the role is allowed to view.
-// If fieldPerms is nil or empty, allows all (used for ADMIN full access).
+// If fieldPerms is nil or empty, allows all (used for superuser roles).
+// Fields with an explicit view deny are always dropped, and fields with a
+// redact setting are returned redacted (see Redact).
+//
+// Rules may target nested values by path ("owner.email",
+// "assignees[*].email", "meta.*"); see rbac.FieldRuleChain. Objects and lists
+// with such rules are filtered recursively.
 func FilterFields(
 	data map[string]interface{},
 	fieldPerms map[string]models.FieldPermission,
 ) map[string]interface{} {
-	if len(fieldPerms) == 0 {
-		result := make(map[string]interface{}, len(data))
-		for k, v := range data {
-			result[k] = v
-		}
-		return result
-	}
-
-	result := make(map[string]interface{})
-	for field, value := range data {
-		perm, exists := fieldPerms[field]
-		if !exists || !perm.View {
-			continue
-		}
-		result[field] = value
-	}
-	return result
+	return filterByAction(data, fieldPerms, rbac.ActionView)
 }
 
 // FilterEditableFields returns only fields the role is allowed to create/edit.
-// If fieldPerms is nil or empty, allows all (used for ADMIN full access).
+// If fieldPerms is nil or empty, allows all (used for superuser roles).
+// Fields with an explicit edit deny are always dropped.
 func FilterEditableFields(
 	data map[string]interface{},
 	fieldPerms map[string]models.FieldPermission,
 ) map[string]interface{} {
-	if len(fieldPerms) == 0 {
-		result := make(map[string]interface{}, len(data))
-		for k, v := range data {
-			result[k] = v
-		}
-		return result
+	return filterByAction(data, fieldPerms, rbac.ActionEdit)
+}
+
+// EditableFields is FilterEditableFields that also returns the paths of the
+// fields it removed, sorted, so a write can report them instead of dropping
+// them silently. Removed list elements are reported as "path[*]".
+func EditableFields(
+	data map[string]interface{},
+	fieldPerms map[string]models.FieldPermission,
+) (map[string]interface{}, []string) {
+	removed := map[string]bool{}
+	f := fieldFilter{
+		perm:    models.ResourcePermission{Fields: fieldPerms},
+		action:  rbac.ActionEdit,
+		removed: removed,
+	}
+	kept := f.object("", data)
+	paths := make([]string, 0, len(removed))
+	for p := range removed {
+		paths = append(paths, p)
 	}
+	sort.Strings(paths)
+	return kept, paths
+}
 
-	result := make(map[string]interface{})
-	for field, value := range data {
-		perm, exists := fieldPerms[field]
-		if !exists || !perm.Edit {
-			continue
+// filterByAction keeps the fields for which rbac.Evaluate allows action.
+func filterByAction(
+	data map[string]interface{},
+	fieldPerms map[string]models.FieldPermission,
+	action string,
+) map[string]interface{} {
+	f := fieldFilter{
+		perm:   models.ResourcePermission{Fields: fieldPerms},
+		action: action,
+		redact: action == rbac.ActionView,
+	}
+	return f.object("", data)
+}
+
+type fieldFilter struct {
+	perm    models.ResourcePermission
+	action  string
+	redact  bool
+	removed map[string]bool // paths removed, when not nil
+}
+
+func (f fieldFilter) drop(path string) (interface{}, bool) {
+	if f.removed != nil {
+		f.removed[path] = true
+	}
+	return nil, false
+}
+
+func (f fieldFilter) object(prefix string, data map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if v, ok := f.value(path, value); ok {
+			result[key] = v
 		}
-		result[field] = value
 	}
 	return result
 }
+
+// value returns the part of value at path the caller may access. Objects and
+// lists with rules below path are filtered element by element and kept when
+// the path itself is allowed or anything inside it survived; everything else
+// is decided as a whole.
+func (f fieldFilter) value(path string, value interface{}) (interface{}, bool) {
+	effect := rbac.Evaluate(f.perm, f.action, path)
+	if effect == rbac.EffectDeny {
+		return f.drop(path)
+	}
+
+	if rbac.HasNestedFieldRules(f.perm.Fields, path) {
+		if strs, ok := value.([]string); ok {
+			items := make([]interface{}, len(strs))
+			for i, s := range strs {
+				items[i] = s
+			}
+			value = items
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			out := f.object(path, v)
+			if len(out) == 0 && !effect.Allowed() {
+				return f.drop(path)
+			}
+			return out, true
+		case []interface{}:
+			out := make([]interface{}, 0, len(v))
+			for _, item := range v {
+				if fv, ok := f.value(path+"[*]", item); ok {
+					out = append(out, fv)
+				}
+			}
+			if len(out) == 0 && !effect.Allowed() {
+				return f.drop(path)
+			}
+			return out, true
+		}
+	}
+
+	if !effect.Allowed() {
+		return f.drop(path)
+	}
+	if f.redact {
+		if spec := rbac.FieldRedaction(f.perm.Fields, path); spec != "" {
+			return Redact(value, spec), true
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"rbac-backend/internal/models"
+)
+
+func TestFilterFieldsNestedAndWildcardPaths(t *testing.T) {
+	fields := map[string]models.FieldPermission{
+		"*":                  {View: true},
+		"secret":             {View: false},
+		"owner.email":        {Deny: []string{"view"}},
+		"meta.*":             {View: false},
+		"meta.label":         {View: true},
+		"assignees[*].email": {View: true, Redact: "mask"},
+		"assignees[*].phone": {Deny: []string{"view"}},
+	}
+	data := map[string]interface{}{
+		"title":  "Ship it",
+		"secret": "s3cr3t",
+		"owner":  map[string]interface{}{"name": "Ann", "email": "ann@example.com"},
+		"meta":   map[string]interface{}{"label": "blue", "internal": 7},
+		"assignees": []interface{}{
+			map[string]interface{}{"email": "bo@example.com", "phone": "555"},
+		},
+	}
+
+	got := FilterFields(data, fields)
+	want := map[string]interface{}{
+		"title": "Ship it",
+		"owner": map[string]interface{}{"name": "Ann"},
+		"meta":  map[string]interface{}{"label": "blue"},
+		"assignees": []interface{}{
+			map[string]interface{}{"email": "b***@example.com"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got  %#v\nwant %#v", got, want)
+	}
+}
+
+func TestFilterFieldsNestedAllowWithoutParentRule(t *testing.T) {
+	fields := map[string]models.FieldPermission{
+		"id":          {View: true},
+		"owner.email": {View: true},
+	}
+	data := map[string]interface{}{
+		"id":    "p1",
+		"owner": map[string]interface{}{"name": "Ann", "email": "ann@example.com"},
+		"notes": "hidden",
+	}
+	got := FilterFields(data, fields)
+	want := map[string]interface{}{
+		"id":    "p1",
+		"owner": map[string]interface{}{"email": "ann@example.com"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got  %#v\nwant %#v", got, want)
+	}
+}
+
+func TestEditableFieldsReportsRemovedPaths(t *testing.T) {
+	fields := map[string]models.FieldPermission{
+		"name":        {Edit: true},
+		"created_by":  {Edit: false},
+		"meta.label":  {Edit: true},
+		"meta.secret": {Deny: []string{"edit"}},
+	}
+	data := map[string]interface{}{
+		"name":       "Apollo",
+		"created_by": "u2",
+		"meta":       map[string]interface{}{"label": "blue", "secret": "x"},
+	}
+	kept, removed := EditableFields(data, fields)
+	want := map[string]interface{}{
+		"name": "Apollo",
+		"meta": map[string]interface{}{"label": "blue"},
+	}
+	if !reflect.DeepEqual(kept, want) {
+		t.Fatalf("kept %#v\nwant %#v", kept, want)
+	}
+	if !reflect.DeepEqual(removed, []string{"created_by", "meta.secret"}) {
+		t.Fatalf("removed = %v", removed)
+	}
+
+	if _, removed := EditableFields(data, nil); len(removed) != 0 {
+		t.Fatalf("no rules: removed = %v", removed)
+	}
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"rbac-backend/internal/config"
+	"rbac-backend/internal/rbac"
+)
+
+// Redact transforms value according to a field's redact setting:
+//
+//	mask        "jane@example.com" -> "j***@example.com", "Ravi" -> "R***", numbers -> "***"
+//	hash        hex HMAC-SHA256 of the value keyed with config.RedactionKey,
+//	            stable so equal values still match
+//	truncate:N  the first N characters followed by "…" (N defaults to 4)
+//	null        nil
+//
+// Lists and objects are redacted element by element. nil stays nil. An invalid setting
+// hides the value.
+func Redact(value interface{}, spec string) interface{} {
+	mode, n, err := rbac.ParseRedaction(spec)
+	if err != nil || value == nil || mode == rbac.RedactNull {
+		return nil
+	}
+	switch v := value.(type) {
+	case []string:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = redactScalar(item, mode, n)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = Redact(item, spec)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = Redact(item, spec)
+		}
+		return out
+	}
+	return redactScalar(value, mode, n)
+}
+
+func redactScalar(value interface{}, mode string, n int) interface{} {
+	s, isString := value.(string)
+	if !isString {
+		s = fmt.Sprint(value)
+	}
+	switch mode {
+	case rbac.RedactHash:
+		var key []byte
+		if config.AppConfig != nil {
+			key = []byte(config.AppConfig.RedactionKey)
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(s))
+		return hex.EncodeToString(mac.Sum(nil))
+	case rbac.RedactTruncate:
+		runes := []rune(s)
+		if len(runes) <= n {
+			return s
+		}
+		return string(runes[:n]) + "…"
+	}
+	// mask
+	if !isString || s == "" {
+		return "***"
+	}
+	if local, domain, ok := strings.Cut(s, "@"); ok && local != "" {
+		return string([]rune(local)[:1]) + "***@" + domain
+	}
+	return string([]rune(s)[:1]) + "***"
+}
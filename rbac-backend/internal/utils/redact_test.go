@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"rbac-backend/internal/config"
+	"rbac-backend/internal/models"
+)
+
+func TestFilterFieldsRedacts(t *testing.T) {
+	fields := map[string]models.FieldPermission{
+		"id":       {View: true},
+		"email":    {View: true, Redact: "mask"},
+		"name":     {View: true, Redact: "truncate:2"},
+		"salary":   {View: true, Redact: "null"},
+		"team":     {View: true, Redact: "mask"},
+		"password": {View: false},
+	}
+	data := map[string]interface{}{
+		"id":       "E101",
+		"email":    "ravi@example.com",
+		"name":     "Ravi",
+		"salary":   90000,
+		"team":     []string{"Ann", "Bo"},
+		"password": "secret",
+	}
+
+	got := FilterFields(data, fields)
+	want := map[string]interface{}{
+		"id":     "E101",
+		"email":  "r***@example.com",
+		"name":   "Ra…",
+		"salary": nil,
+		"team":   []interface{}{"A***", "B***"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v\nwant %#v", got, want)
+	}
+
+	if editable := FilterEditableFields(map[string]interface{}{"email": "x@y.z"}, map[string]models.FieldPermission{
+		"email": {View: true, Edit: true, Redact: "mask"},
+	}); editable["email"] != "x@y.z" {
+		t.Errorf("redaction must not apply to edits, got %v", editable["email"])
+	}
+}
+
+func TestRedactHashIsStable(t *testing.T) {
+	defer func(c *config.Config) { config.AppConfig = c }(config.AppConfig)
+	config.AppConfig = &config.Config{RedactionKey: "k1"}
+
+	a, b := Redact("ravi@example.com", "hash"), Redact("ravi@example.com", "hash")
+	if a != b || a == "ravi@example.com" {
+		t.Fatalf("hash should be stable and hide the value: %v %v", a, b)
+	}
+	unkeyed := sha256.Sum256([]byte("ravi@example.com"))
+	if a == hex.EncodeToString(unkeyed[:]) {
+		t.Error("hash is a plain SHA-256, which a dictionary reverses")
+	}
+	config.AppConfig = &config.Config{RedactionKey: "k2"}
+	if Redact("ravi@example.com", "hash") == a {
+		t.Error("hash does not depend on the key")
+	}
+	if Redact(42, "bogus") != nil {
+		t.Error("an invalid mode should hide the value")
+	}
+}
@@ -0,0 +1,259 @@
+// Package validate checks request structs against declarative `validate`
+// struct tags and collects every failure as a field error:
+//
+//	Title  string   `json:"title" validate:"required,max=200"`
+//	Status string   `json:"status" validate:"enum=TODO|IN_PROGRESS|DONE"`
+//	Owners []string `json:"owners" validate:"uuid,exists=member"`
+//
+// Rules:
+//
+//   - required: the value is not empty.
+//   - min=N, max=N: the length of a string (in characters) or slice.
+//   - enum=A|B: one of the listed values.
+//   - uuid: a UUID in canonical form.
+//   - exists=KIND: Validator.Exists reports a record of KIND with that id.
+//
+// Rules other than required skip empty values. On slices, enum, uuid and
+// exists apply to each element. Fields are named by their JSON name.
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// FieldError is one failed rule.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors are the failures of one request.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Without returns e minus the errors for fields.
+func (e Errors) Without(fields ...string) Errors {
+	var out Errors
+	for _, fe := range e {
+		if !contains(fields, fe.Field) {
+			out = append(out, fe)
+		}
+	}
+	return out
+}
+
+// Validator checks structs. Exists answers exists rules; it is only needed
+// when the struct has some.
+type Validator struct {
+	Exists func(kind, id string) (bool, error)
+}
+
+// Struct checks every field of s, a struct or a pointer to one. The error is
+// only set when an exists lookup fails.
+func (v Validator) Struct(s interface{}) (Errors, error) {
+	return v.check(s, nil)
+}
+
+// Fields checks only the named fields of s, as for a partial update.
+func (v Validator) Fields(s interface{}, fields []string) (Errors, error) {
+	if fields == nil {
+		fields = []string{}
+	}
+	return v.check(s, fields)
+}
+
+func (v Validator) check(s interface{}, only []string) (Errors, error) {
+	rv := reflect.Indirect(reflect.ValueOf(s))
+	t := rv.Type()
+	var errs Errors
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("validate")
+		name := jsonName(f)
+		if tag == "" || name == "" || only != nil && !contains(only, name) {
+			continue
+		}
+		for _, rule := range strings.Split(tag, ",") {
+			fe, err := v.rule(name, rule, rv.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			if fe != nil {
+				errs = append(errs, *fe)
+				break // one error per field
+			}
+		}
+	}
+	return errs, nil
+}
+
+func (v Validator) rule(field, rule string, val reflect.Value) (*FieldError, error) {
+	name, arg, _ := strings.Cut(rule, "=")
+	fail := func(format string, a ...interface{}) (*FieldError, error) {
+		return &FieldError{Field: field, Rule: name, Message: fmt.Sprintf(format, a...)}, nil
+	}
+
+	if name == "required" {
+		if val.IsZero() || val.Kind() == reflect.Slice && val.Len() == 0 {
+			return fail("is required")
+		}
+		return nil, nil
+	}
+	if val.IsZero() {
+		return nil, nil
+	}
+
+	switch name {
+	case "min", "max":
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("validate: bad %s rule on %s", name, field)
+		}
+		length, unit := val.Len(), "items"
+		if val.Kind() == reflect.String {
+			length, unit = utf8.RuneCountInString(val.String()), "characters"
+		}
+		if name == "min" && length < n {
+			return fail("must have at least %d %s", n, unit)
+		}
+		if name == "max" && length > n {
+			return fail("must have at most %d %s", n, unit)
+		}
+		return nil, nil
+	}
+
+	for _, s := range stringsOf(val) {
+		switch name {
+		case "enum":
+			if !contains(strings.Split(arg, "|"), s) {
+				return fail("must be one of %s", strings.ReplaceAll(arg, "|", ", "))
+			}
+		case "uuid":
+			if _, err := uuid.Parse(s); err != nil || len(s) != 36 {
+				return fail("%q is not a UUID", s)
+			}
+		case "exists":
+			if v.Exists == nil {
+				return nil, fmt.Errorf("validate: no Exists for %s", field)
+			}
+			ok, err := v.Exists(arg, s)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return fail("%s %q does not exist", arg, s)
+			}
+		default:
+			return nil, fmt.Errorf("validate: unknown rule %q on %s", name, field)
+		}
+	}
+	return nil, nil
+}
+
+// Decode copies data, a decoded JSON object, into the struct dst points to.
+// Values of the wrong type and keys dst has no field for are reported as
+// field errors instead of failing the whole body.
+func Decode(data map[string]interface{}, dst interface{}) Errors {
+	rv := reflect.ValueOf(dst).Elem()
+	t := rv.Type()
+	fields := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		if name := jsonName(t.Field(i)); name != "" {
+			fields[name] = i
+		}
+	}
+
+	var errs Errors
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonName(t.Field(i))
+		value, ok := data[name]
+		if !ok || name == "" {
+			continue
+		}
+		b, _ := json.Marshal(value)
+		if err := json.Unmarshal(b, rv.Field(i).Addr().Interface()); err != nil {
+			errs = append(errs, FieldError{Field: name, Rule: "type", Message: "must be " + typeName(t.Field(i).Type)})
+		}
+	}
+	var unknown []string
+	for key := range data {
+		if _, ok := fields[key]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		errs = append(errs, FieldError{Field: key, Rule: "unknown", Message: "is not accepted"})
+	}
+	return errs
+}
+
+func jsonName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+func stringsOf(val reflect.Value) []string {
+	switch val.Kind() {
+	case reflect.String:
+		return []string{val.String()}
+	case reflect.Slice:
+		if val.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		out := make([]string, val.Len())
+		for i := range out {
+			out[i] = val.Index(i).String()
+		}
+		return out
+	}
+	return nil
+}
+
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int64, reflect.Float64:
+		return "a number"
+	case reflect.Slice:
+		return "a list of " + strings.TrimPrefix(strings.TrimPrefix(typeName(t.Elem()), "a "), "an ") + "s"
+	}
+	return "an object"
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
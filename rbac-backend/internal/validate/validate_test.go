@@ -0,0 +1,81 @@
+package validate
+
+import (
+	"reflect"
+	"testing"
+)
+
+type request struct {
+	ID      string   `json:"id,omitempty"`
+	Title   string   `json:"title" validate:"required,max=5"`
+	Status  string   `json:"status" validate:"enum=TODO|DONE"`
+	Owner   string   `json:"owner" validate:"uuid,exists=member"`
+	Members []string `json:"members" validate:"max=2,uuid"`
+}
+
+const member = "6f1c1f52-3f0e-4d0b-9a57-1f0a2b3c4d5e"
+
+var validator = Validator{Exists: func(kind, id string) (bool, error) {
+	return kind == "member" && id == member, nil
+}}
+
+func rules(errs Errors) map[string]string {
+	out := map[string]string{}
+	for _, fe := range errs {
+		out[fe.Field] = fe.Rule
+	}
+	return out
+}
+
+func TestStructCollectsEveryFieldError(t *testing.T) {
+	req := request{
+		Title:   "too long",
+		Status:  "LATER",
+		Owner:   "6f1c1f52-0000-4d0b-9a57-1f0a2b3c4d5e",
+		Members: []string{member, "bob"},
+	}
+	errs, err := validator.Struct(&req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"title": "max", "status": "enum", "owner": "exists", "members": "uuid"}
+	if got := rules(errs); !reflect.DeepEqual(got, want) {
+		t.Fatalf("rules = %v, want %v", got, want)
+	}
+
+	errs, _ = validator.Struct(request{})
+	if got := rules(errs); !reflect.DeepEqual(got, map[string]string{"title": "required"}) {
+		t.Fatalf("empty request: %v", got)
+	}
+}
+
+func TestFieldsOnlyChecksNamedFields(t *testing.T) {
+	errs, err := validator.Fields(request{Status: "LATER", Owner: member}, []string{"owner"})
+	if err != nil || len(errs) != 0 {
+		t.Fatalf("Fields = %v, %v; want no errors", errs, err)
+	}
+	errs, _ = validator.Fields(request{Status: "LATER"}, nil)
+	if len(errs) != 0 {
+		t.Fatalf("no fields: %v", errs)
+	}
+}
+
+func TestDecodeReportsTypesAndUnknownKeys(t *testing.T) {
+	var req request
+	errs := Decode(map[string]interface{}{
+		"title":      "ok",
+		"status":     7,
+		"members":    "bob",
+		"created_by": "x",
+	}, &req)
+	want := map[string]string{"status": "type", "members": "type", "created_by": "unknown"}
+	if got := rules(errs); !reflect.DeepEqual(got, want) {
+		t.Fatalf("rules = %v, want %v", got, want)
+	}
+	if req.Title != "ok" {
+		t.Fatalf("title not decoded: %+v", req)
+	}
+	if errs[1].Message != "must be a list of strings" {
+		t.Fatalf("message = %q", errs[1].Message)
+	}
+}
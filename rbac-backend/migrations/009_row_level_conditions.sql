@@ -0,0 +1,12 @@
+-- Row-level rules that used to be hardcoded in the handlers now live in config.
+-- Projects are visible to assigned employees only; viewers only see tasks they
+-- created or are assigned to.
+UPDATE role_permissions
+SET permissions = json_set(permissions, '$.projects.view',
+    json('{"if": "user.id in record.assigned_employees"}'))
+WHERE role IN ('MANAGER', 'EDITOR', 'VIEWER');
+
+UPDATE role_permissions
+SET permissions = json_set(permissions, '$.tasks.view',
+    json('{"if": "record.created_by == user.id || user.id in record.assignees"}'))
+WHERE role = 'VIEWER';
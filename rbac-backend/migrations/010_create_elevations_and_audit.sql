@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS elevation_requests (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    role TEXT,
+    table_name TEXT,
+    action TEXT,
+    justification TEXT NOT NULL,
+    duration_minutes INTEGER NOT NULL,
+    status TEXT CHECK(status IN ('PENDING','APPROVED','DENIED','REVOKED','EXPIRED')) NOT NULL DEFAULT 'PENDING',
+    approved_by TEXT,
+    requested_at DATETIME NOT NULL,
+    approved_at DATETIME,
+    expires_at DATETIME,
+    closed_at DATETIME,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_elevation_user_status ON elevation_requests(user_id, status);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id TEXT PRIMARY KEY,
+    actor_id TEXT,
+    action TEXT NOT NULL,
+    target TEXT,
+    details TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_created ON audit_log(created_at);
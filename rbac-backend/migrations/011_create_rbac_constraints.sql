@@ -0,0 +1,7 @@
+-- Separation-of-duties constraints: one JSON document (see models.Constraints).
+CREATE TABLE IF NOT EXISTS rbac_constraints (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    constraints TEXT NOT NULL
+);
+
+INSERT OR IGNORE INTO rbac_constraints (id, constraints) VALUES (1, '{"exclusive_roles": [], "actions": []}');
@@ -0,0 +1,10 @@
+-- Every change to a role's permissions is kept as a numbered version.
+CREATE TABLE IF NOT EXISTS role_permission_versions (
+    role TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    permissions TEXT NOT NULL,
+    author TEXT,
+    comment TEXT,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (role, version)
+);
@@ -0,0 +1,7 @@
+-- A role inherits every rule of its parents; see db.GetEffectivePermissions.
+CREATE TABLE IF NOT EXISTS role_inheritance (
+    role TEXT NOT NULL,
+    parent TEXT NOT NULL,
+    PRIMARY KEY (role, parent),
+    CHECK (role <> parent)
+);
@@ -0,0 +1,20 @@
+-- Role catalogue. A superuser role gets full access to every table without
+-- any permission config; see middleware.ResolveTablePermission. users.role
+-- references this table (see db.upgradeSchema).
+CREATE TABLE IF NOT EXISTS roles (
+    name TEXT PRIMARY KEY,
+    superuser INTEGER NOT NULL DEFAULT 0,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+INSERT OR IGNORE INTO roles (name, superuser, description) VALUES
+('ADMIN', 1, 'Full access to every table'),
+('MANAGER', 0, ''),
+('EDITOR', 0, ''),
+('VIEWER', 0, '');
+
+-- The users table used to be restricted to ADMIN in code. That restriction is
+-- now config: the seeded non-superuser roles get no users access.
+UPDATE role_permissions
+SET permissions = json_remove(permissions, '$.users')
+WHERE role IN ('MANAGER', 'EDITOR', 'VIEWER');
@@ -0,0 +1,40 @@
+-- Tenants. Projects, tasks, elevations, audit entries and role permissions
+-- belong to one organization each; the org_id columns on the existing tables
+-- are added by db.upgradeSchema.
+CREATE TABLE IF NOT EXISTS organizations (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT OR IGNORE INTO organizations (id, name) VALUES ('default', 'Default');
+
+-- A user's role in each organization they belong to. users.role only records
+-- the role the account was created with.
+CREATE TABLE IF NOT EXISTS organization_members (
+    org_id TEXT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL REFERENCES roles(name),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_org_members_user ON organization_members(user_id);
+
+-- Existing users join the default organization with their current role.
+INSERT OR IGNORE INTO organization_members (org_id, user_id, role)
+SELECT 'default', id, role FROM users
+WHERE NOT EXISTS (SELECT 1 FROM organization_members);
+
+-- Role permissions in effect, per organization. role_permissions, which the
+-- earlier migrations re-seed on every run, now only holds the defaults a new
+-- organization starts from.
+CREATE TABLE IF NOT EXISTS org_role_permissions (
+    org_id TEXT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    role TEXT NOT NULL,
+    permissions TEXT,
+    PRIMARY KEY (org_id, role)
+);
+
+INSERT OR IGNORE INTO org_role_permissions (org_id, role, permissions)
+SELECT 'default', role, permissions FROM role_permissions;
@@ -0,0 +1,50 @@
+-- Full-text index of project names/descriptions and task titles/descriptions
+-- for GET /search (see repositories.FTS5Searcher). Triggers keep it in sync;
+-- title and body hold a project's name and description or a task's title
+-- and description.
+CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+    kind UNINDEXED,
+    record_id UNINDEXED,
+    title,
+    body,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS projects_search_insert AFTER INSERT ON projects BEGIN
+    INSERT INTO search_index (kind, record_id, title, body)
+    VALUES ('project', NEW.id, NEW.name, COALESCE(NEW.description, ''));
+END;
+
+CREATE TRIGGER IF NOT EXISTS projects_search_update AFTER UPDATE OF name, description ON projects BEGIN
+    DELETE FROM search_index WHERE kind = 'project' AND record_id = OLD.id;
+    INSERT INTO search_index (kind, record_id, title, body)
+    VALUES ('project', NEW.id, NEW.name, COALESCE(NEW.description, ''));
+END;
+
+CREATE TRIGGER IF NOT EXISTS projects_search_delete AFTER DELETE ON projects BEGIN
+    DELETE FROM search_index WHERE kind = 'project' AND record_id = OLD.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS tasks_search_insert AFTER INSERT ON tasks BEGIN
+    INSERT INTO search_index (kind, record_id, title, body)
+    VALUES ('task', NEW.id, NEW.title, COALESCE(NEW.description, ''));
+END;
+
+CREATE TRIGGER IF NOT EXISTS tasks_search_update AFTER UPDATE OF title, description ON tasks BEGIN
+    DELETE FROM search_index WHERE kind = 'task' AND record_id = OLD.id;
+    INSERT INTO search_index (kind, record_id, title, body)
+    VALUES ('task', NEW.id, NEW.title, COALESCE(NEW.description, ''));
+END;
+
+CREATE TRIGGER IF NOT EXISTS tasks_search_delete AFTER DELETE ON tasks BEGIN
+    DELETE FROM search_index WHERE kind = 'task' AND record_id = OLD.id;
+END;
+
+-- Index the records that predate the index.
+INSERT INTO search_index (kind, record_id, title, body)
+SELECT 'project', id, name, COALESCE(description, '') FROM projects
+WHERE id NOT IN (SELECT record_id FROM search_index WHERE kind = 'project');
+
+INSERT INTO search_index (kind, record_id, title, body)
+SELECT 'task', id, title, COALESCE(description, '') FROM tasks
+WHERE id NOT IN (SELECT record_id FROM search_index WHERE kind = 'task');
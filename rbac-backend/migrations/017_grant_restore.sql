@@ -0,0 +1,23 @@
+-- Deleting a project or task now moves it to the trash. Roles that may delete
+-- may also restore, under the same condition; purging is left to superusers
+-- until granted. A config that already says whether a role may restore,
+-- including every config saved since, is left alone.
+UPDATE role_permissions
+SET permissions = json_set(permissions, '$.projects.restore', json(permissions -> '$.projects.delete'))
+WHERE json_type(permissions, '$.projects.delete') IN ('true', 'object')
+  AND json_type(permissions, '$.projects.restore') IS NULL;
+
+UPDATE role_permissions
+SET permissions = json_set(permissions, '$.tasks.restore', json(permissions -> '$.tasks.delete'))
+WHERE json_type(permissions, '$.tasks.delete') IN ('true', 'object')
+  AND json_type(permissions, '$.tasks.restore') IS NULL;
+
+UPDATE org_role_permissions
+SET permissions = json_set(permissions, '$.projects.restore', json(permissions -> '$.projects.delete'))
+WHERE json_type(permissions, '$.projects.delete') IN ('true', 'object')
+  AND json_type(permissions, '$.projects.restore') IS NULL;
+
+UPDATE org_role_permissions
+SET permissions = json_set(permissions, '$.tasks.restore', json(permissions -> '$.tasks.delete'))
+WHERE json_type(permissions, '$.tasks.delete') IN ('true', 'object')
+  AND json_type(permissions, '$.tasks.restore') IS NULL;
@@ -0,0 +1,17 @@
+-- Project responses used to show assigned_employees to anyone who could view
+-- the project, whatever the field rules said. They now follow the field rules,
+-- so roles whose project fields leave it out are given view on it. A config
+-- that already has a rule for it, or a "*" rule, is left alone.
+UPDATE role_permissions
+SET permissions = json_set(permissions, '$.projects.fields.assigned_employees', json('{"view": true, "edit": false}'))
+WHERE json_type(permissions, '$.projects.view') IN ('true', 'object')
+  AND json_type(permissions, '$.projects.fields') = 'object'
+  AND json_type(permissions, '$.projects.fields.assigned_employees') IS NULL
+  AND json_type(permissions, '$.projects.fields."*"') IS NULL;
+
+UPDATE org_role_permissions
+SET permissions = json_set(permissions, '$.projects.fields.assigned_employees', json('{"view": true, "edit": false}'))
+WHERE json_type(permissions, '$.projects.view') IN ('true', 'object')
+  AND json_type(permissions, '$.projects.fields') = 'object'
+  AND json_type(permissions, '$.projects.fields.assigned_employees') IS NULL
+  AND json_type(permissions, '$.projects.fields."*"') IS NULL;
@@ -0,0 +1,13 @@
+-- Invitations to join an organization. POST /admin/org/members records one
+-- for an email; the account with that email joins only when it accepts.
+CREATE TABLE IF NOT EXISTS organization_invitations (
+    id TEXT PRIMARY KEY,
+    org_id TEXT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL REFERENCES roles(name),
+    invited_by TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    UNIQUE (org_id, email)
+);
+
+CREATE INDEX IF NOT EXISTS idx_org_invitations_email ON organization_invitations(email);
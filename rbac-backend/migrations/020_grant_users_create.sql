@@ -0,0 +1,13 @@
+-- POST /admin/create-user now checks users create instead of edit. Nothing
+-- checked users create before, so roles that could edit users get it, under
+-- the same condition, and keep creating accounts. Roles already granted create
+-- are left alone.
+UPDATE role_permissions
+SET permissions = json_set(permissions, '$.users.create', json(permissions -> '$.users.edit'))
+WHERE json_type(permissions, '$.users.edit') IN ('true', 'object')
+  AND coalesce(json_type(permissions, '$.users.create'), 'null') NOT IN ('true', 'object');
+
+UPDATE org_role_permissions
+SET permissions = json_set(permissions, '$.users.create', json(permissions -> '$.users.edit'))
+WHERE json_type(permissions, '$.users.edit') IN ('true', 'object')
+  AND coalesce(json_type(permissions, '$.users.create'), 'null') NOT IN ('true', 'object');
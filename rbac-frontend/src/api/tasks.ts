@@ -1,20 +1,42 @@
 const API_BASE = import.meta.env.VITE_API_BASE || 'http://localhost:8080'
 
-async function request(path: string, opts: RequestInit = {}) {
+// ApiError carries the status and details of the backend's error envelope.
+export class ApiError extends Error {
+  constructor(message: string, public status: number, public details?: any) {
+    super(message)
+  }
+}
+
+async function send(path: string, opts: RequestInit = {}) {
   const token = localStorage.getItem('token')
-  const headers: Record<string,string> = { 'Content-Type': 'application/json' }
+  const headers: Record<string,string> = { 'Content-Type': 'application/json', ...(opts.headers as Record<string,string>) }
   if (token) headers['Authorization'] = `Bearer ${token}`
-  const res = await fetch(API_BASE + path, { headers, ...opts })
-  if (!res.ok) throw new Error(await res.text())
-  return res.json()
+  const res = await fetch(API_BASE + path, { ...opts, headers })
+  if (!res.ok) {
+    const d = await res.json().catch(() => ({}))
+    throw new ApiError(d.error?.message || res.statusText, res.status, d.error?.details)
+  }
+  return res
+}
+
+async function request(path: string, opts: RequestInit = {}) {
+  return (await send(path, opts)).json()
 }
 
 export async function fetchTasksByProject(projectId: string) {
   return request(`/tasks?project_id=${encodeURIComponent(projectId)}`)
 }
 
-export async function updateTask(payload: Record<string, any>) {
-  return request('/tasks/update', { method: 'POST', body: JSON.stringify(payload) })
+// updateTask changes a task read at version and returns its new version. If
+// the task changed since, it throws an ApiError with status 412 whose details
+// hold the current task.
+export async function updateTask(id: string, version: number, changes: Record<string, any>) {
+  const res = await send(`/tasks/${encodeURIComponent(id)}`, {
+    method: 'PATCH',
+    headers: { 'If-Match': `"${version}"` },
+    body: JSON.stringify(changes),
+  })
+  return Number(JSON.parse(res.headers.get('ETag') || '0'))
 }
 
 export async function createTask(payload: Record<string, any>) {
@@ -1,5 +1,5 @@
 import { useEffect, useState } from 'react'
-import { fetchTasksByProject, updateTask } from '../api/tasks'
+import { ApiError, fetchTasksByProject, updateTask } from '../api/tasks'
 
 type Task = {
   id: string
@@ -7,6 +7,7 @@ type Task = {
   description?: string
   status: string
   assignees?: string[]
+  version: number
 }
 
 const STATUSES = ['TODO', 'IN_PROGRESS', 'REVIEW', 'DONE']
@@ -19,9 +20,19 @@ export default function KanbanBoard({ projectId, usersMap = {} }: { projectId: s
     fetchTasksByProject(projectId).then(setTasks).catch(console.error)
   }, [projectId])
 
-  const move = async (id: string, status: string) => {
-    await updateTask({ id, status })
-    setTasks(prev => prev.map(t => t.id === id ? { ...t, status } : t))
+  const move = async (task: Task, status: string) => {
+    try {
+      const version = await updateTask(task.id, task.version, { status })
+      setTasks(prev => prev.map(t => t.id === task.id ? { ...t, status, version } : t))
+    } catch (err) {
+      // Someone else changed the task first: show it as it is now.
+      if (err instanceof ApiError && err.status === 412 && err.details?.current) {
+        const current = err.details.current
+        setTasks(prev => prev.map(t => t.id === task.id ? { ...t, ...current } : t))
+        return
+      }
+      console.error(err)
+    }
   }
 
   return (
@@ -38,7 +49,7 @@ export default function KanbanBoard({ projectId, usersMap = {} }: { projectId: s
               )}
               <div className="mt-2 grid grid-cols-2 gap-1">
                 {STATUSES.map(opt => (
-                  <button key={opt} disabled={opt === s} onClick={() => move(t.id, opt)} className="px-2 py-1 text-xs bg-slate-700/50 rounded disabled:opacity-50">
+                  <button key={opt} disabled={opt === s} onClick={() => move(t, opt)} className="px-2 py-1 text-xs bg-slate-700/50 rounded disabled:opacity-50">
                     {opt.split('_')[0]}
                   </button>
                 ))}
@@ -71,7 +71,7 @@ export const CreateProject = () => {
 
       if (!res.ok) {
         const d = await res.json().catch(() => ({}));
-        throw new Error(d.message || 'Failed to create project');
+        throw new Error(d.error?.message || 'Failed to create project');
       }
 
       setSuccess('Project created');
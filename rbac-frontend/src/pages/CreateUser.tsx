@@ -57,7 +57,7 @@ export const CreateUser = () => {
 
       if (!response.ok) {
         const data = await response.json();
-        throw new Error(data.message || 'Failed to create user');
+        throw new Error(data.error?.message || 'Failed to create user');
       }
 
       setSuccess('User created successfully!');
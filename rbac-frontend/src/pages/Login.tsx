@@ -33,7 +33,7 @@ function Login() {
 
       if (!response.ok) {
         const data = await response.json();
-        throw new Error(data.message || 'Login failed');
+        throw new Error(data.error?.message || 'Login failed');
       }
 
       const data = await response.json();